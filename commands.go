@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// options The values of every flag, each command only registers the flags that apply to it
+type options struct {
+	journeyPath       string
+	cmd               string
+	env               string
+	bucket            string
+	cdnDomain         string
+	region            string
+	endpoint          string
+	pathStyle         bool
+	version           string
+	forceUnlock       bool
+	force             bool
+	keep              int
+	olderThan         string
+	skipSemver        bool
+	manifestFormat    string
+	encryption        string
+	kmsKeyID          string
+	acl               string
+	backend           string
+	fromBucket        string
+	toBucket          string
+	against           string
+	channel           string
+	weight            int
+	gitSHA            string
+	gitBranch         string
+	gitTag            string
+	includeAll        bool
+	timeout           time.Duration
+	uploadTimeout     time.Duration
+	maxBandwidth      string
+	storageClass      string
+	artifact          string
+	workspace         string
+	only              string
+	out               string
+	addr              string
+	dir               string
+	from              string
+	to                string
+	concurrency       int
+	dryRun            bool
+	invalidate        bool
+	meta              metaFlags
+	notify            listFlags
+	verifyExisting    bool
+	report            string
+	skipRegistry      bool
+	skipSmokeTests    bool
+	skipHooks         bool
+	checkDistribution bool
+	yes               bool
+	profile           string
+	roleARN           string
+	externalID        string
+	roleSessionName   string
+	retries           int
+	progress          bool
+	logFormat         string
+	quiet             bool
+	verbose           bool
+	name              string
+	rootID            string
+	build             string
+	manifest          string
+	manifestSchema    bool
+	check             bool
+	format            string
+}
+
+// flagSet A flag set that ignores flags that are already registered, so commands can share groups of flags
+type flagSet struct {
+	*flag.FlagSet
+}
+
+func (fs flagSet) boolVar(p *bool, name string, value bool, usage string) {
+	if fs.Lookup(name) == nil {
+		fs.BoolVar(p, name, value, usage)
+	}
+}
+
+func (fs flagSet) intVar(p *int, name string, value int, usage string) {
+	if fs.Lookup(name) == nil {
+		fs.IntVar(p, name, value, usage)
+	}
+}
+
+func (fs flagSet) durationVar(p *time.Duration, name string, value time.Duration, usage string) {
+	if fs.Lookup(name) == nil {
+		fs.DurationVar(p, name, value, usage)
+	}
+}
+
+func (fs flagSet) stringVar(p *string, name string, value string, usage string) {
+	if fs.Lookup(name) == nil {
+		fs.StringVar(p, name, value, usage)
+	}
+}
+
+func (fs flagSet) variable(v flag.Value, name string, usage string) {
+	if fs.Lookup(name) == nil {
+		fs.Var(v, name, usage)
+	}
+}
+
+// commonFlags Flags every command that works with a journey.json takes
+func (o *options) commonFlags(fs flagSet) {
+	fs.stringVar(&o.journeyPath, "journey", "journey.json", "Location of the journey.json file, - reads it from stdin")
+	fs.stringVar(&o.env, "env", "", "Environment in journey.json to use the bucket, cdn and region of, eg: staging")
+	fs.stringVar(&o.bucket, "bucket", "", "AWS S3 bucket")
+	fs.stringVar(&o.cdnDomain, "cdn", "", "AWS Cloudfront domain")
+	fs.stringVar(&o.region, "region", "", "AWS region where bucket located, defaults to "+journey.DefaultRegion)
+	fs.stringVar(&o.endpoint, "endpoint", "", "S3 compatible endpoint to use instead of AWS, eg: http://localhost:9000, the url of the webdav or sftp server, or of a GCS or Azure emulator")
+	fs.boolVar(&o.pathStyle, "path-style", false, "Address the bucket in the path instead of the host name, needed by most S3 compatible storage")
+	fs.stringVar(&o.version, "version", "", "Version to work with, overrides the version in journey.json")
+	fs.boolVar(&o.skipSemver, "skip-semver", false, "Allow versions that are not semantic versions")
+	fs.stringVar(&o.backend, "backend", "", "Storage backend, overrides storage in journey.json: s3, gcs, azure, webdav or sftp")
+	fs.stringVar(&o.profile, "profile", "", "Named AWS profile to use from the shared config and credentials files")
+	fs.stringVar(&o.roleARN, "role-arn", "", "IAM role to assume for the deployment, eg: arn:aws:iam::123456789012:role/deploy")
+	fs.stringVar(&o.externalID, "external-id", "", "External ID required by the role")
+	fs.stringVar(&o.roleSessionName, "role-session-name", "", "Session name when assuming the role, defaults to journey-cli")
+	fs.intVar(&o.retries, "retries", journey.DefaultRetries, "How many times a failed storage request is attempted before giving up")
+	fs.durationVar(&o.timeout, "timeout", 0, "Give up when the command takes longer than this, eg: 15m")
+	o.logFlags(fs)
+}
+
+// logFlags Flags for the log output
+func (o *options) logFlags(fs flagSet) {
+	fs.stringVar(&o.logFormat, "log-format", "text", "Log format, text or json")
+	fs.boolVar(&o.quiet, "quiet", false, "Only log warnings and errors")
+	fs.boolVar(&o.verbose, "verbose", false, "Log every file as it is uploaded")
+}
+
+// writeFlags Flags for commands that write objects to the bucket
+func (o *options) writeFlags(fs flagSet) {
+	fs.stringVar(&o.encryption, "encryption", "", "Server side encryption for uploaded objects, AES256 or aws:kms")
+	fs.stringVar(&o.kmsKeyID, "kms-key-id", "", "KMS key ARN or id to encrypt objects with when encryption is aws:kms")
+	fs.stringVar(&o.acl, "acl", "", "Canned ACL for uploaded objects, private, public-read or bucket-owner-full-control")
+}
+
+// lockFlags Flags for commands that hold the lock
+func (o *options) lockFlags(fs flagSet) {
+	fs.boolVar(&o.forceUnlock, "force-unlock", false, "Remove the lock left behind by a job that is gone before running the command")
+}
+
+// uploadFlags Flags for commands that upload the local build
+func (o *options) uploadFlags(fs flagSet) {
+	o.writeFlags(fs)
+	o.lockFlags(fs)
+	fs.stringVar(&o.manifestFormat, "manifest-format", "", "Format of the asset manifest, flat, webpack, vite or cra")
+	fs.stringVar(&o.artifact, "artifact", "", "Publish from a .tar.gz, .tgz, .tar or .zip of the build instead of the build directory")
+	fs.stringVar(&o.manifest, "manifest", "", "Asset manifest, overrides the one in journey.json, - reads it from stdin")
+	fs.intVar(&o.concurrency, "concurrency", journey.DefaultConcurrency, "How many files to upload at the same time")
+	fs.boolVar(&o.dryRun, "dry-run", false, "Print what would be uploaded without uploading anything")
+	fs.stringVar(&o.storageClass, "storage-class", "", "Storage class of the uploaded version, STANDARD, STANDARD_IA, ONEZONE_IA or INTELLIGENT_TIERING")
+	fs.durationVar(&o.uploadTimeout, "upload-timeout", 0, "Give up on a file when uploading it takes longer than this, eg: 5m")
+	fs.stringVar(&o.maxBandwidth, "max-bandwidth", "", "Keep the uploads together under this rate, eg: 20MB/s")
+	fs.boolVar(&o.includeAll, "include-all", false, "Publish every file in the build directory, not only the ones in the manifest")
+	fs.stringVar(&o.report, "report", "", "Write a json report of every uploaded file to this path")
+	fs.stringVar(&o.gitSHA, "git-sha", "", "Commit the build is from, detected with git or from the CI environment when not set")
+	fs.stringVar(&o.gitBranch, "git-branch", "", "Branch the build is from, detected when not set")
+	fs.stringVar(&o.gitTag, "git-tag", "", "Tag the build is from, detected when not set")
+	fs.boolVar(&o.progress, "progress", true, "Show a progress bar when running in a terminal")
+}
+
+// yesFlags Flags for commands that ask before changing a protected bucket
+func (o *options) yesFlags(fs flagSet) {
+	fs.boolVar(&o.yes, "yes", false, "Do not ask before changing a bucket or environment marked protected")
+}
+
+// notifyFlags Flags for commands that notify chats and webhooks
+func (o *options) notifyFlags(fs flagSet) {
+	fs.variable(&o.notify, "notify", "Chat to notify, eg: slack://hooks.slack.com/services/..., can be repeated")
+}
+
+// latestFlags Flags for commands that move latest
+func (o *options) latestFlags(fs flagSet) {
+	o.writeFlags(fs)
+	o.lockFlags(fs)
+	o.notifyFlags(fs)
+	fs.boolVar(&o.invalidate, "invalidate", false, "Purge the cdn cache for latest")
+}
+
+// command A subcommand, legacy names are what -cmd used to take
+type command struct {
+	name    string
+	legacy  string
+	summary string
+	config  bool
+	flags   func(o *options, fs flagSet)
+}
+
+// commands Every subcommand, in the order help lists them
+var commands = []command{
+	{initCmd, "", "Write a new journey.json", false, func(o *options, fs flagSet) {
+		fs.stringVar(&o.journeyPath, "journey", "journey.json", "Location of the journey.json file to write")
+		fs.stringVar(&o.name, "name", "", "Name of the journey")
+		fs.stringVar(&o.version, "version", "", "First version of the journey")
+		fs.stringVar(&o.rootID, "root-id", "", "Id of the element the journey renders into")
+		fs.stringVar(&o.build, "build", "", "Build directory")
+		fs.stringVar(&o.manifest, "manifest", "", "Asset manifest")
+		fs.stringVar(&o.bucket, "bucket", "", "AWS S3 bucket")
+		fs.stringVar(&o.cdnDomain, "cdn", "", "AWS Cloudfront domain")
+		fs.boolVar(&o.skipSemver, "skip-semver", false, "Allow versions that are not semantic versions")
+		fs.boolVar(&o.force, "force", false, "Replace an existing journey.json")
+		o.logFlags(fs)
+	}},
+	{publish, "", "Upload the build as a new version", true, func(o *options, fs flagSet) {
+		o.uploadFlags(fs)
+		o.notifyFlags(fs)
+		fs.variable(o.meta, "meta", "Metadata to store with the version as key=value, can be repeated")
+		fs.boolVar(&o.verifyExisting, "verify-existing", false, "If the version is already published, verify it matches the local build instead of failing")
+		fs.boolVar(&o.skipRegistry, "skip-registry", false, "Do not register the published version with journey-registry")
+		fs.boolVar(&o.skipSmokeTests, "skip-smoke-tests", false, "Do not run the smoke tests in journey.json after publishing")
+		fs.boolVar(&o.skipHooks, "skip-hooks", false, "Do not run the prePublish and postPublish hooks in journey.json")
+		fs.boolVar(&o.force, "force", false, "Publish over an existing version")
+		fs.boolVar(&o.checkDistribution, "check-distribution", false, "Warn when the CloudFront distribution of the cdn does not serve the journey from the bucket")
+		fs.stringVar(&o.workspace, "workspace", "", "Publish every journey listed in this journeys.json at the same time, instead of -journey")
+		fs.stringVar(&o.only, "only", "", "Only publish these journeys of the workspace, eg: header,footer")
+	}},
+	{sync, "", "Upload the files of the build that changed in an existing version", true, func(o *options, fs flagSet) {
+		o.uploadFlags(fs)
+	}},
+	{diff, "", "Compare the local build with a published version", true, func(o *options, fs flagSet) {
+		fs.stringVar(&o.manifestFormat, "manifest-format", "", "Format of the asset manifest, flat, webpack, vite or cra")
+		fs.stringVar(&o.artifact, "artifact", "", "Compare a .tar.gz, .tgz, .tar or .zip of the build instead of the build directory")
+		fs.stringVar(&o.manifest, "manifest", "", "Asset manifest, overrides the one in journey.json, - reads it from stdin")
+		fs.stringVar(&o.against, "against", "", "Published version to compare the local build with, eg: 1.0.0")
+	}},
+	{verify, "", "Check a published version is complete", true, func(o *options, fs flagSet) {}},
+	{doctor, "", "Check journey.json, the AWS identity, the bucket and the cdn are set up to publish", true, func(o *options, fs flagSet) {}},
+	{compare, "", "Compare the journey urls of two published versions", true, func(o *options, fs flagSet) {
+		fs.stringVar(&o.from, "from", "", "Version to compare from, eg: 1.0.0")
+		fs.stringVar(&o.to, "to", "", "Version to compare to, defaults to the version in journey.json")
+	}},
+	{annotate, "", "Store metadata with a published version", true, func(o *options, fs flagSet) {
+		o.writeFlags(fs)
+		fs.variable(o.meta, "meta", "Metadata to store with the version as key=value, can be repeated")
+	}},
+	{setLatest, "setLatest", "Point latest at the version", true, func(o *options, fs flagSet) {
+		o.latestFlags(fs)
+		fs.boolVar(&o.skipHooks, "skip-hooks", false, "Do not run the postSetLatest hook in journey.json")
+		o.yesFlags(fs)
+	}},
+	{setChannel, "", "Point a channel like beta or canary at the version", true, func(o *options, fs flagSet) {
+		o.writeFlags(fs)
+		o.lockFlags(fs)
+		fs.stringVar(&o.channel, "channel", "", "Channel to point at the version, eg: beta")
+		fs.boolVar(&o.invalidate, "invalidate", false, "Purge the cdn cache for the channel")
+	}},
+	{canary, "", "Send part of the traffic to the version while latest gets the rest", true, func(o *options, fs flagSet) {
+		o.latestFlags(fs)
+		fs.intVar(&o.weight, "weight", 10, "Percent of the traffic the version gets")
+	}},
+	{promoteCanary, "", "Point latest at the canary version and end the rollout", true, func(o *options, fs flagSet) {
+		o.latestFlags(fs)
+	}},
+	{channels, "", "List the channels and the version each points at", true, func(o *options, fs flagSet) {}},
+	{rollback, "", "Point latest back at a previous version", true, func(o *options, fs flagSet) {
+		o.latestFlags(fs)
+		fs.stringVar(&o.to, "to", "", "Version to roll back to")
+		o.yesFlags(fs)
+	}},
+	{signUrls, "", "Sign the urls of a published version again before they expire", true, func(o *options, fs flagSet) {
+		o.writeFlags(fs)
+		o.lockFlags(fs)
+		fs.boolVar(&o.dryRun, "dry-run", false, "Print when the urls would expire without uploading anything")
+	}},
+	{download, "", "Fetch a published version into a local directory", true, func(o *options, fs flagSet) {
+		fs.stringVar(&o.out, "out", "", "Directory to download the version into, defaults to ./{name}-{version}")
+	}},
+	{serve, "", "Serve the build locally with journey urls pointing at it", true, func(o *options, fs flagSet) {
+		fs.stringVar(&o.addr, "addr", journey.DefaultServeAddr, "Address to listen on")
+		fs.stringVar(&o.dir, "dir", "", "Serve a version fetched with download from this directory instead of the build")
+		fs.stringVar(&o.manifestFormat, "manifest-format", "", "Format of the asset manifest, flat, webpack, vite or cra")
+	}},
+	{list, "", "List the published versions", true, func(o *options, fs flagSet) {}},
+	{history, "", "Print who published or moved latest and channels, and when", true, func(o *options, fs flagSet) {}},
+	{show, "", "Print the journey urls and journey.json of a version, -version latest for what latest points at", true, func(o *options, fs flagSet) {
+		fs.stringVar(&o.format, "format", "table", "Output format, table or json")
+	}},
+	{unpublish, "", "Delete a published version", true, func(o *options, fs flagSet) {
+		fs.boolVar(&o.force, "force", false, "Confirm deleting the version")
+		o.yesFlags(fs)
+	}},
+	{prune, "", "Delete old versions with a retention policy", true, func(o *options, fs flagSet) {
+		fs.intVar(&o.keep, "keep", 0, "Number of newest versions to keep")
+		fs.stringVar(&o.olderThan, "older-than", "", "Delete versions published longer ago than this, eg: 90d")
+		fs.boolVar(&o.dryRun, "dry-run", false, "Print what would be deleted without deleting anything")
+		fs.boolVar(&o.force, "force", false, "Confirm deleting the versions")
+		fs.stringVar(&o.storageClass, "storage-class", "", "Move the versions to this storage class instead of deleting them, eg: ONEZONE_IA")
+		o.yesFlags(fs)
+	}},
+	{cleanup, "", "Abort incomplete multipart uploads left behind by failed publishes", true, func(o *options, fs flagSet) {
+		o.lockFlags(fs)
+		fs.stringVar(&o.olderThan, "older-than", "", "Abort uploads started longer ago than this, defaults to 1d, eg: 12h")
+		fs.boolVar(&o.dryRun, "dry-run", false, "Print what would be aborted without aborting anything")
+	}},
+	{archive, "", "Move old versions to Glacier, keeping them listed", true, func(o *options, fs flagSet) {
+		o.lockFlags(fs)
+		fs.intVar(&o.keep, "keep", 0, "Archive all but the newest versions")
+		fs.stringVar(&o.olderThan, "older-than", "", "Archive versions published longer ago than this, eg: 180d")
+		fs.stringVar(&o.storageClass, "storage-class", "", "Archive storage class, GLACIER (default) or DEEP_ARCHIVE")
+		fs.boolVar(&o.dryRun, "dry-run", false, "Print what would be archived without archiving anything")
+	}},
+	{restore, "", "Bring an archived version back, run it again once the objects are restored", true, func(o *options, fs flagSet) {
+		o.lockFlags(fs)
+		fs.boolVar(&o.dryRun, "dry-run", false, "Ask for the objects back without moving them once they are")
+	}},
+	{promote, "", "Copy a published version from another bucket", true, func(o *options, fs flagSet) {
+		o.writeFlags(fs)
+		fs.stringVar(&o.fromBucket, "from-bucket", "", "Bucket to copy the version from")
+		fs.stringVar(&o.toBucket, "to-bucket", "", "Bucket to copy the version to, overrides the bucket")
+	}},
+	{completion, "", "Print the bash completion script, eg: source <(journey-cli completion)", false, func(o *options, fs flagSet) {}},
+	{versionCmd, "", "Print the version of journey-cli and what it was built from", false, func(o *options, fs flagSet) {}},
+	{selfUpdateCmd, "", "Replace journey-cli with the latest release", false, func(o *options, fs flagSet) {
+		fs.boolVar(&o.check, "check", false, "Only print whether a newer release is available")
+		o.logFlags(fs)
+	}},
+	{schema, "", "Print the JSON Schema of journey.json", false, func(o *options, fs flagSet) {
+		fs.boolVar(&o.manifestSchema, "manifest", false, "Print the JSON Schema of a flat asset manifest instead")
+	}},
+}
+
+// findCommand Find the command by its name or legacy name
+func findCommand(name string) (*command, bool) {
+	for i, c := range commands {
+		if c.name == name || (len(c.legacy) > 0 && c.legacy == name) {
+			return &commands[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// newFlagSet Create the flag set of the command
+func (c *command) newFlagSet(o *options, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "Usage: journey-cli %v [flags]\n\n%v\n\nFlags:\n", c.name, c.summary)
+		fs.PrintDefaults()
+	}
+
+	c.flags(o, flagSet{fs})
+	if c.config {
+		o.commonFlags(flagSet{fs})
+	}
+
+	return fs
+}
+
+// legacyCommand The command picked by -cmd, read before parsing so only its flags are registered
+func legacyCommand(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case name == "cmd" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(name, "cmd="):
+			return strings.TrimPrefix(name, "cmd=")
+		}
+	}
+
+	return publish
+}
+
+// legacyFlagSet The flat flag set with -cmd picking the command, kept so existing scripts keep working. Only the
+// flags of the picked command are registered so flags of other commands are rejected instead of silently ignored
+func legacyFlagSet(o *options, c *command, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("journey-cli", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() { usage(out) }
+
+	fs.StringVar(&o.cmd, "cmd", publish, "Command to invoke, eg: publish")
+	o.commonFlags(flagSet{fs})
+	c.flags(o, flagSet{fs})
+
+	return fs
+}
+
+// usage Print the commands
+func usage(out io.Writer) {
+	fmt.Fprintf(out, "Usage: journey-cli <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-14v %v\n", c.name, c.summary)
+	}
+	fmt.Fprintf(out, "\nRun journey-cli <command> -h for the flags of a command.\n")
+}
+
+// parseArgs Parse the command line, either a subcommand followed by its flags or the legacy -cmd flag
+func parseArgs(args []string, out io.Writer) (*options, *command, error) {
+	o := &options{meta: metaFlags{}}
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if args[0] == "help" {
+			usage(out)
+			return nil, nil, flag.ErrHelp
+		}
+
+		c, ok := findCommand(args[0])
+		if !ok {
+			usage(out)
+			return nil, nil, configError(fmt.Errorf("Do not recognize command: %v", args[0]))
+		}
+
+		if err := c.newFlagSet(o, out).Parse(args[1:]); err != nil {
+			return nil, nil, err
+		}
+		o.cmd = c.name
+
+		return o, c, nil
+	}
+
+	name := legacyCommand(args)
+	c, ok := findCommand(name)
+	if !ok {
+		return nil, nil, configError(fmt.Errorf("Do not recognize command: %v", name))
+	}
+
+	if err := legacyFlagSet(o, c, out).Parse(args); err != nil {
+		return nil, nil, err
+	}
+	o.cmd = c.name
+
+	return o, c, nil
+}
+
+// bashCompletion Print a bash completion script for the commands and their flags
+func bashCompletion(out io.Writer) {
+	var names []string
+	var b bytes.Buffer
+
+	for _, c := range commands {
+		names = append(names, c.name)
+
+		var flags []string
+		c.newFlagSet(&options{meta: metaFlags{}}, ioutil.Discard).VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name)
+		})
+		sort.Strings(flags)
+		fmt.Fprintf(&b, "        %v) opts=\"%v\" ;;\n", c.name, strings.Join(flags, " "))
+	}
+
+	fmt.Fprintf(out, `_journey_cli() {
+    local cur opts
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "%v help" -- "$cur"))
+        return
+    fi
+    case "${COMP_WORDS[1]}" in
+%v    esac
+    COMPREPLY=($(compgen -W "$opts" -- "$cur"))
+}
+complete -o default -F _journey_cli journey-cli
+`, strings.Join(names, " "), b.String())
+}
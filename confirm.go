@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// protectedAction What the command is about to do that needs confirming on a protected bucket, empty when it changes nothing live
+func protectedAction(o *options, j *journey.Journey) string {
+	switch o.cmd {
+	case setLatest:
+		return fmt.Sprintf("point latest of %v at %v", j.Name, j.Version)
+	case rollback:
+		if len(o.to) <= 0 {
+			return fmt.Sprintf("roll latest of %v back to the previous version", j.Name)
+		}
+		return fmt.Sprintf("roll latest of %v back to %v", j.Name, o.to)
+	case unpublish:
+		if o.force {
+			return fmt.Sprintf("delete %v/%v", j.Name, j.Version)
+		}
+	case prune:
+		if !o.dryRun && (o.force || len(o.storageClass) > 0) {
+			return fmt.Sprintf("prune the old versions of %v", j.Name)
+		}
+	}
+
+	return ""
+}
+
+// confirm Ask before changing a bucket or environment marked protected in journey.json, unless -yes is passed.
+// Without a terminal to ask on, the command needs -yes
+func confirm(o *options, j *journey.Journey, in *os.File, out io.Writer) error {
+	action := protectedAction(o, j)
+	if !j.Protected || len(action) <= 0 || o.yes {
+		return nil
+	}
+
+	where := fmt.Sprintf("Bucket %v", j.Bucket)
+	if len(j.Environment) > 0 {
+		where = fmt.Sprintf("The %v environment", j.Environment)
+	}
+	if !isTerminal(in) {
+		return configError(fmt.Errorf("%v is protected, pass -yes to %v", where, action))
+	}
+
+	fmt.Fprintf(out, "%v is protected. Are you sure you want to %v? [y/N] ", where, action)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("Did not %v, it was not confirmed", action)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// runDoctor Check journey.json, the AWS identity, the bucket and the cdn, and print what to fix. Checks carry on past
+// a journey.json that is not valid so everything wrong shows up at once
+func runDoctor(ctx context.Context, o *options, j *journey.Journey, out io.Writer) error {
+	config := journey.Finding{Check: "config", OK: true, Detail: fmt.Sprintf("%v is valid", o.journeyPath)}
+	if err := configure(o, o.journeyPath, j); err != nil {
+		config = journey.Finding{Check: "config", Detail: err.Error(), Fix: "Fix journey.json, journey-cli schema prints the fields it takes"}
+	}
+	if len(j.Region) <= 0 {
+		j.Region = journey.DefaultRegion
+	}
+
+	sess, err := j.NewSession(j.Region)
+	if err != nil {
+		return err
+	}
+
+	var store journey.Storage
+	if len(j.Bucket) > 0 {
+		if store, err = journey.NewStorage(j.Storage, j.Bucket, sess, j.StorageOptions()); err != nil {
+			return configError(err)
+		}
+	}
+
+	findings := append([]journey.Finding{config}, j.Doctor(ctx, sess, store)...)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+	failed := 0
+	for _, f := range findings {
+		status := "ok"
+		if !f.OK {
+			status = "FAIL"
+			failed++
+		}
+		// AWS errors span lines, which would break the table
+		fmt.Fprintf(w, "%v\t%v\t%v\n", f.Check, status, strings.Join(strings.Fields(f.Detail), " "))
+	}
+	w.Flush()
+
+	if failed <= 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	for _, f := range findings {
+		if !f.OK {
+			fmt.Fprintf(out, "%v: %v\n", f.Check, f.Fix)
+		}
+	}
+
+	return fmt.Errorf("%v of %v checks failed", failed, len(findings))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// Exit codes CI can branch on
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitConfig      = 2
+	exitVersionUsed = 3
+	exitUpload      = 4
+	exitLatest      = 5
+	exitLocked      = 6
+	exitVerify      = 7
+	exitInterrupted = 130
+)
+
+// exitError An error with the exit code the process ends with
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// withCode Attach the exit code to the error, nil stays nil
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &exitError{code: code, err: err}
+}
+
+// configError Bad flags, journey.json or asset manifest
+func configError(err error) error {
+	return withCode(exitConfig, err)
+}
+
+// exitCode Map the error to the exit code, errors the journey package knows about win over the category they were given
+func exitCode(err error) int {
+	code := exitFailure
+	if e, ok := err.(*exitError); ok {
+		code, err = e.code, e.err
+	}
+
+	switch e := err.(type) {
+	case nil:
+		return exitOK
+	case *journey.VersionExistsError:
+		return exitVersionUsed
+	case *journey.LockedError:
+		return exitLocked
+	case *journey.UploadError:
+		if e.Interrupted {
+			return exitInterrupted
+		}
+		return exitUpload
+	}
+
+	return code
+}
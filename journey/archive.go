@@ -0,0 +1,236 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Archive storage classes, objects in them have to be restored before they can be read
+const (
+	StorageClassGlacier     = "GLACIER"
+	StorageClassDeepArchive = "DEEP_ARCHIVE"
+)
+
+// ArchivedFile Marks an archived version, kept next to its journey.json
+const ArchivedFile = "archived.json"
+
+// restoreDays How long the temporary copy of a restored object is kept while it is moved back
+const restoreDays = 7
+
+// Archived What archived.json records about an archived version
+type Archived struct {
+	StorageClass string    `json:"storageClass"`
+	Archived     time.Time `json:"archived"`
+}
+
+// validateArchiveClass Validate the storage class is one versions can be archived to
+func validateArchiveClass(class string) error {
+	switch class {
+	case StorageClassGlacier, StorageClassDeepArchive:
+		return nil
+	default:
+		return fmt.Errorf("Versions can not be archived to %v, use %v or %v", class, StorageClassGlacier, StorageClassDeepArchive)
+	}
+}
+
+// isArchiveClass Check if objects in the storage class need restoring before they can be read
+func isArchiveClass(class string) bool {
+	return class == StorageClassGlacier || class == StorageClassDeepArchive
+}
+
+// keptOnArchive Check if the file of a version stays readable when it is archived, so it is still listed, compared and described
+func (j *Journey) keptOnArchive(file string) bool {
+	switch file {
+	case JourneyFile, ManifestFile, j.UrlsFile(), MetadataFile, ArchivedFile:
+		return true
+	default:
+		return false
+	}
+}
+
+// isArchived Check if the version is archived
+func (j *Journey) isArchived(ctx context.Context, store Storage, version string) (bool, error) {
+	key := j.GetVersionKey(version, ArchivedFile)
+	if _, err := store.Head(ctx, key); err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+
+	return true, nil
+}
+
+// Archive Move the assets of a published version to an archive storage class and mark it archived,
+// its journey.json and journey urls stay where they are so it is still listed
+func (j *Journey) Archive(ctx context.Context, store Storage, version string, class string) error {
+	if err := validateArchiveClass(class); err != nil {
+		return err
+	}
+
+	served, err := j.servedVersions(ctx, store)
+	if err != nil {
+		return err
+	}
+	if channel, ok := served[version]; ok {
+		return j.servedError(version, channel, "archiving")
+	}
+
+	prefix := j.GetVersionKey(version, "")
+	objects, err := listVersion(ctx, store, prefix)
+	if err != nil {
+		return fmt.Errorf("Unable to list %v: %v", prefix, err)
+	}
+	if _, ok := objects[JourneyFile]; !ok {
+		return fmt.Errorf("Version %v/%v is not published", j.Name, version)
+	}
+
+	var files []string
+	for file, o := range objects {
+		if !j.keptOnArchive(file) && o.StorageClass != class {
+			files = append(files, file)
+		}
+	}
+
+	if j.DryRun {
+		Log.Infof("Dry run, %v objects of %v/%v would be archived to %v", len(files), j.Name, version, class)
+		return nil
+	}
+
+	// mark it first, so a version that is half archived can not be pointed at
+	data, err := json.Marshal(&Archived{StorageClass: class, Archived: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("Unable to parse %v into json", ArchivedFile)
+	}
+	key := prefix + ArchivedFile
+	if err := store.Upload(ctx, key, bytes.NewReader(data), j.uploadOptions(key, "application/json")); err != nil {
+		return fmt.Errorf("Unable to upload %v: %v", key, err)
+	}
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		Log.Debugf("Archiving %v to %v", prefix+file, class)
+		if err := store.SetStorageClass(ctx, prefix+file, class); err != nil {
+			return fmt.Errorf("Unable to archive %v: %v", prefix+file, err)
+		}
+	}
+
+	Log.Event(fmt.Sprintf("Archived %v/%v to %v", j.Name, version, class), Fields{
+		"name":         j.Name,
+		"version":      version,
+		"storageClass": class,
+		"files":        len(files),
+	})
+
+	return nil
+}
+
+// ArchiveVersions Archive the versions outside the retention window, like prune picks them
+func (j *Journey) ArchiveVersions(ctx context.Context, store Storage, keep int, olderThan time.Duration, class string) ([]string, error) {
+	versions, err := j.ListVersions(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	served, err := j.servedVersions(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive []string
+	for _, v := range outsideRetention(versions, served, keep, olderThan) {
+		if !v.Archived {
+			archive = append(archive, v.Version)
+		}
+	}
+	if len(archive) <= 0 {
+		Log.Infof("No versions of %v are left to archive", j.Name)
+		return nil, nil
+	}
+	Log.Infof("Archiving these versions of %v: %v", j.Name, strings.Join(archive, ", "))
+
+	for _, version := range archive {
+		if err := j.Archive(ctx, store, version, class); err != nil {
+			return archive, err
+		}
+	}
+
+	return archive, nil
+}
+
+// Restore Bring an archived version back. Archived objects take hours to restore, so the first run asks for them
+// and a run once they are back moves them to their storage class and unmarks the version
+func (j *Journey) Restore(ctx context.Context, store Storage, version string) (bool, error) {
+	if archived, err := j.isArchived(ctx, store, version); err != nil {
+		return false, err
+	} else if !archived {
+		return false, fmt.Errorf("Version %v/%v is not archived", j.Name, version)
+	}
+
+	prefix := j.GetVersionKey(version, "")
+	objects, err := listVersion(ctx, store, prefix)
+	if err != nil {
+		return false, fmt.Errorf("Unable to list %v: %v", prefix, err)
+	}
+
+	var restored []string
+	pending := 0
+	for file, o := range objects {
+		if !isArchiveClass(o.StorageClass) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		ok, err := store.Restore(ctx, prefix+file, restoreDays)
+		if err != nil {
+			return false, fmt.Errorf("Unable to restore %v: %v", prefix+file, err)
+		}
+		if !ok {
+			pending++
+			continue
+		}
+		restored = append(restored, file)
+	}
+
+	if pending > 0 {
+		Log.Infof("Restoring %v objects of %v/%v, run restore again once they are back, which takes hours from %v and up to 12 hours from %v",
+			pending, j.Name, version, StorageClassGlacier, StorageClassDeepArchive)
+		return false, nil
+	}
+	if j.DryRun {
+		Log.Infof("Dry run, %v objects of %v/%v are restored and would be moved back", len(restored), j.Name, version)
+		return false, nil
+	}
+
+	for _, file := range restored {
+		class := j.getStorageClass(prefix + file)
+		if len(class) <= 0 {
+			class = StorageClassStandard
+		}
+
+		Log.Debugf("Moving %v back to %v", prefix+file, class)
+		if err := store.SetStorageClass(ctx, prefix+file, class); err != nil {
+			return false, fmt.Errorf("Unable to move %v back to %v: %v", prefix+file, class, err)
+		}
+	}
+
+	if err := store.Delete(ctx, prefix+ArchivedFile); err != nil {
+		return false, fmt.Errorf("Unable to delete %v: %v", prefix+ArchivedFile, err)
+	}
+
+	Log.Event(fmt.Sprintf("Restored %v/%v", j.Name, version), Fields{
+		"name":    j.Name,
+		"version": version,
+		"files":   len(restored),
+	})
+
+	return true, nil
+}
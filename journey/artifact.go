@@ -0,0 +1,214 @@
+package journey
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// UseArtifact Unpack a build artifact, a .tar.gz, .tgz, .tar or .zip holding the build directory, and publish from it
+// in place of the build directory. The manifest is read from the artifact when it lives in the build directory.
+// The returned func removes the unpacked files
+func (j *Journey) UseArtifact(artifact string) (func(), error) {
+	dir, err := ioutil.TempDir("", "journey-artifact-")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create a directory to unpack %v into: %v", artifact, err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			Log.Warnf("Unable to remove %v: %v", dir, err)
+		}
+	}
+
+	name := strings.ToLower(artifact)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		err = untarFile(artifact, dir, true)
+	case strings.HasSuffix(name, ".tar"):
+		err = untarFile(artifact, dir, false)
+	case strings.HasSuffix(name, ".zip"):
+		err = unzipFile(artifact, dir)
+	default:
+		err = fmt.Errorf("Artifact %v is not a .tar.gz, .tgz, .tar or .zip", artifact)
+	}
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	root, err := artifactRoot(dir)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	// the manifest is usually part of the build, read it from the artifact too
+	if rel, err := filepath.Rel(j.Build, j.Manifest); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		j.Manifest = filepath.Join(root, rel)
+	}
+	j.Build = root
+	Log.Infof("Unpacked %v", artifact)
+
+	return cleanup, nil
+}
+
+// artifactRoot The build directory inside the unpacked artifact, artifacts holding a single directory are unwrapped
+func artifactRoot(dir string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %v: %v", dir, err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+
+	return dir, nil
+}
+
+// artifactPath The path an entry of the artifact is unpacked to, cleaned from the root so it can not climb out of dir
+func artifactPath(dir string, name string) string {
+	return filepath.Join(dir, filepath.FromSlash(path.Clean("/"+toSlash(name))))
+}
+
+// writeArtifactFile Write the content of an entry to target
+func writeArtifactFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeArtifactLink Create a symlink from an entry, so the symlink policy applies to it like in a build directory,
+// links pointing outside the artifact are refused
+func writeArtifactLink(dir string, target string, link string) error {
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(link))
+	if filepath.IsAbs(filepath.FromSlash(link)) || (resolved != dir && !strings.HasPrefix(resolved, dir+string(filepath.Separator))) {
+		return fmt.Errorf("it links to %v outside the artifact", link)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	return os.Symlink(link, target)
+}
+
+// untarFile Unpack a tar, gzipped or not, into dir
+func untarFile(artifact string, dir string, gzipped bool) error {
+	f, err := os.Open(artifact)
+	if err != nil {
+		return fmt.Errorf("Unable to open %v: %v", artifact, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("Unable to read %v: %v", artifact, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to read %v: %v", artifact, err)
+		}
+
+		target := artifactPath(dir, h.Name)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg, tar.TypeRegA:
+			err = writeArtifactFile(target, tr, h.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = writeArtifactLink(dir, target, h.Linkname)
+		case tar.TypeLink:
+			err = copyArtifactFile(artifactPath(dir, h.Linkname), target)
+		default:
+			Log.Debugf("Skipping %v in %v, it is not a file or directory", h.Name, artifact)
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to unpack %v from %v: %v", h.Name, artifact, err)
+		}
+	}
+}
+
+// copyArtifactFile Copy a file that was already unpacked, for hard links
+func copyArtifactFile(source string, target string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return writeArtifactFile(target, f, info.Mode())
+}
+
+// unzipFile Unpack a zip into dir
+func unzipFile(artifact string, dir string) error {
+	zr, err := zip.OpenReader(artifact)
+	if err != nil {
+		return fmt.Errorf("Unable to open %v: %v", artifact, err)
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		if err := unzipEntry(dir, artifactPath(dir, zf.Name), zf); err != nil {
+			return fmt.Errorf("Unable to unpack %v from %v: %v", zf.Name, artifact, err)
+		}
+	}
+
+	return nil
+}
+
+// unzipEntry Unpack one entry of a zip to target
+func unzipEntry(dir string, target string, zf *zip.File) error {
+	mode := zf.Mode()
+	if mode.IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&os.ModeSymlink != 0 {
+		link, err := ioutil.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		return writeArtifactLink(dir, target, string(link))
+	}
+
+	return writeArtifactFile(target, rc, mode)
+}
@@ -0,0 +1,277 @@
+package journey
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Symlink policies for assets that are symlinks in the build directory
+const (
+	SymlinkFollow = "follow"
+	SymlinkSkip   = "skip"
+	SymlinkError  = "error"
+)
+
+// Empty file policies for zero byte assets
+const (
+	EmptyFileWarn  = "warn"
+	EmptyFileError = "error"
+)
+
+// defaultWarnAssetSize Assets larger than this are called out in the logs unless configured otherwise
+const defaultWarnAssetSize = 25 << 20
+
+// junkFiles Files created by operating systems and editors that never belong on the CDN
+var junkFiles = map[string]bool{
+	".DS_Store":   true,
+	"Thumbs.db":   true,
+	"desktop.ini": true,
+}
+
+// isJunkFile Check if the file at path is an os/editor artifact or a dotfile, only files found by walking the build are
+// checked, a file the manifest lists is always published
+func isJunkFile(path string) bool {
+	name := filepath.Base(path)
+
+	if junkFiles[name] || strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	switch {
+	case strings.HasSuffix(name, "~"), strings.HasSuffix(name, ".swp"), strings.HasSuffix(name, ".swo"):
+		return true
+	}
+
+	return false
+}
+
+// skipJunk Check if walking the build should pass over the file, or the whole directory when it is a dot directory like .git
+func (j *Journey) skipJunk(root string, path string, info os.FileInfo) (bool, error) {
+	if j.IncludeHidden || path == root || !isJunkFile(path) {
+		return false, nil
+	}
+
+	if info.IsDir() {
+		return true, filepath.SkipDir
+	}
+	Log.Debugf("%v is a dotfile or junk file and will not be uploaded", path)
+
+	return true, nil
+}
+
+// validateSymlinkPolicy Validate the symlink policy is one we know how to handle
+func validateSymlinkPolicy(policy string) error {
+	switch policy {
+	case "", SymlinkFollow, SymlinkSkip, SymlinkError:
+		return nil
+	default:
+		return fmt.Errorf("Symlink policy %v is not supported, use %v, %v or %v", policy, SymlinkFollow, SymlinkSkip, SymlinkError)
+	}
+}
+
+// checkSymlink Apply the symlink policy to the asset at path, returns false if the asset should be skipped
+func (j *Journey) checkSymlink(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, fmt.Errorf("Unable to read asset %v: %v", path, err)
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return true, nil
+	}
+
+	switch j.Symlinks {
+	case SymlinkSkip:
+		Log.Infof("Asset %v is a symlink and will not be uploaded", path)
+		return false, nil
+	case SymlinkError:
+		return false, fmt.Errorf("Asset %v is a symlink, which is not allowed by the symlink policy", path)
+	}
+
+	// EvalSymlinks fails on broken links as well as links that loop back on themselves
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false, fmt.Errorf("Unable to follow symlink %v: %v", path, err)
+	}
+
+	info, err = os.Stat(target)
+	if err != nil {
+		return false, fmt.Errorf("Symlink %v points to %v which can not be read: %v", path, target, err)
+	}
+
+	if info.IsDir() {
+		return false, fmt.Errorf("Symlink %v points to the directory %v, not a file", path, target)
+	}
+
+	return true, nil
+}
+
+// validateEmptyFilePolicy Validate the empty file policy is one we know how to handle
+func validateEmptyFilePolicy(policy string) error {
+	switch policy {
+	case "", EmptyFileWarn, EmptyFileError:
+		return nil
+	default:
+		return fmt.Errorf("Empty file policy %v is not supported, use %v or %v", policy, EmptyFileWarn, EmptyFileError)
+	}
+}
+
+// validateAssetSizes Validate the asset size limits can be parsed and make sense together
+func (j *Journey) validateAssetSizes() error {
+	warn, err := ParseSize(j.WarnAssetSize)
+	if err != nil {
+		return err
+	}
+
+	max, err := ParseSize(j.MaxAssetSize)
+	if err != nil {
+		return err
+	}
+
+	if max > 0 && warn > max {
+		return fmt.Errorf("warnAssetSize %v is larger than maxAssetSize %v", j.WarnAssetSize, j.MaxAssetSize)
+	}
+
+	return nil
+}
+
+// checkSize Check the asset against the empty file policy and the asset size limits
+func (j *Journey) checkSize(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read asset %v: %v", path, err)
+	}
+
+	// sizes are validated when the journey is loaded
+	warn, _ := ParseSize(j.WarnAssetSize)
+	max, _ := ParseSize(j.MaxAssetSize)
+	if warn <= 0 {
+		warn = defaultWarnAssetSize
+	}
+
+	size := info.Size()
+	if size == 0 {
+		if j.EmptyFiles == EmptyFileError {
+			return fmt.Errorf("Asset %v is empty, the build may have written a truncated file", path)
+		}
+		Log.Warnf("Asset %v is empty, the build may have written a truncated file", path)
+	}
+
+	if max > 0 && size > max {
+		return fmt.Errorf("Asset %v is %v which is over the max asset size of %v", path, FormatSize(size), FormatSize(max))
+	}
+
+	if size > warn {
+		Log.Warnf("Asset %v is %v, make sure it is meant to be published", path, FormatSize(size))
+	}
+
+	return nil
+}
+
+// checkManifestEntries Check every manifest entry that would be uploaded points at its own file in the build, failing with
+// every entry whose file is missing, was written as an absolute path outside the build, or is listed under another name too.
+// Vite lists the css a chunk pulls in under every chunk that imports it, so its manifests can name a file more than once
+func (j *Journey) checkManifestEntries(assets map[string]string) error {
+	var missing, outside, duplicates []string
+	names := make(map[string][]string, len(assets))
+
+	for k, v := range assets {
+		if matchAny(j.Exclude, v) {
+			continue
+		}
+		names[v] = append(names[v], k)
+
+		if _, err := os.Lstat(j.GetAssetPath(v)); !os.IsNotExist(err) {
+			continue
+		}
+		// absolute paths lose their leading slash when they are parsed, so see if it was a file on this machine
+		if _, err := os.Stat(filepath.FromSlash("/" + v)); err == nil {
+			outside = append(outside, fmt.Sprintf("  %v: /%v is outside the build directory %v", k, v, j.Build))
+		} else {
+			missing = append(missing, fmt.Sprintf("  %v: %v is not in the build directory %v", k, v, j.Build))
+		}
+	}
+
+	if j.ManifestFormat != ManifestVite {
+		for v, keys := range names {
+			if len(keys) > 1 {
+				sort.Strings(keys)
+				duplicates = append(duplicates, fmt.Sprintf("  %v are all %v", strings.Join(keys, ", "), v))
+			}
+		}
+	}
+
+	var problems []string
+	for _, p := range []struct {
+		title   string
+		entries []string
+	}{
+		{"Missing files", missing},
+		{"Absolute paths", outside},
+		{"Entries for the same file", duplicates},
+	} {
+		if len(p.entries) > 0 {
+			sort.Strings(p.entries)
+			problems = append(problems, fmt.Sprintf("%v:\n%v", p.title, strings.Join(p.entries, "\n")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("The asset manifest %v does not match the build:\n%v", j.Manifest, strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// PlanAssets Check every asset in the manifest against the journey policies and return the assets to upload
+func (j *Journey) PlanAssets(assets map[string]string) (map[string]string, error) {
+	if err := j.checkManifestEntries(assets); err != nil {
+		return nil, err
+	}
+
+	planned := make(map[string]string, len(assets))
+	targets := make(map[string]string, len(assets))
+
+	for k, v := range assets {
+		path := j.GetAssetPath(v)
+
+		if matchAny(j.Exclude, v) {
+			Log.Debugf("Asset %v matches an exclude pattern and will not be uploaded", v)
+			continue
+		}
+
+		if err := j.checkKey(v); err != nil {
+			return nil, err
+		}
+
+		ok, err := j.checkSymlink(path)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := j.checkSize(path); err != nil {
+			return nil, err
+		}
+
+		// warn when two manifest entries end up being the same file, usually a symlink to shared content
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			if other, seen := targets[target]; seen && other != v {
+				Log.Warnf("Assets %v and %v resolve to the same file %v", other, v, target)
+			}
+			targets[target] = v
+		}
+
+		planned[k] = v
+	}
+
+	if err := j.checkKeyCollisions(planned); err != nil {
+		return nil, err
+	}
+
+	return planned, nil
+}
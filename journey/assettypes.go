@@ -0,0 +1,76 @@
+package journey
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Asset Struct for tracking public data of an asset that is not css or js, like a font, image or wasm module
+type Asset struct {
+	URL  string `json:"url"`
+	Type string `json:"type"`
+}
+
+// Categories assets are listed under in journey-urls.json
+const (
+	AssetFont  = "font"
+	AssetImage = "image"
+	AssetWasm  = "wasm"
+	AssetJSON  = "json"
+)
+
+// defaultAssetTypes The category of each extension listed in journey-urls.json besides css and js
+var defaultAssetTypes = map[string]string{
+	".woff":  AssetFont,
+	".woff2": AssetFont,
+	".ttf":   AssetFont,
+	".otf":   AssetFont,
+	".eot":   AssetFont,
+	".png":   AssetImage,
+	".jpg":   AssetImage,
+	".jpeg":  AssetImage,
+	".gif":   AssetImage,
+	".svg":   AssetImage,
+	".webp":  AssetImage,
+	".avif":  AssetImage,
+	".ico":   AssetImage,
+	".wasm":  AssetWasm,
+	".json":  AssetJSON,
+}
+
+// validateAssetTypes Validate the extensions in the asset types mapping
+func validateAssetTypes(types map[string]string) error {
+	for ext := range types {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+			return fmt.Errorf("Asset type %v is not an extension, eg: .wasm", ext)
+		}
+		switch strings.ToLower(ext) {
+		case ".css", ".js", ".map":
+			return fmt.Errorf("Asset type %v can not be changed, css, js and source maps are always handled the same way", ext)
+		}
+	}
+
+	return nil
+}
+
+// isListed Check if journey-urls.json lists the asset, css, js and the assets with a category
+func (j *Journey) isListed(asset string) bool {
+	switch filepath.Ext(asset) {
+	case ".css", ".js":
+		return true
+	}
+
+	return len(j.getAssetType(asset)) > 0
+}
+
+// getAssetType Get the category an asset is listed under in journey-urls.json, empty when it is not listed.
+// The assetTypes in journey.json win over the defaults and an empty category stops an extension being listed
+func (j *Journey) getAssetType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if t, ok := j.AssetTypes[ext]; ok {
+		return t
+	}
+
+	return defaultAssetTypes[ext]
+}
@@ -0,0 +1,540 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureVersion The version of the Blob service REST API requests are made with
+const azureVersion = "2019-12-12"
+
+// azureTimeout How long a single request to Blob storage may take, blocks of big files included
+const azureTimeout = 10 * time.Minute
+
+// azureBlockSize Bodies bigger than this are uploaded in blocks of this size, unless a part size is set
+const azureBlockSize = 8 * 1024 * 1024
+
+// azureCopyPoll How often a copy that is still pending is checked on
+const azureCopyPoll = time.Second
+
+// Environment variables the Azure account, and its key or a SAS token, are read from, like the az cli does
+const (
+	azureAccountEnv = "AZURE_STORAGE_ACCOUNT"
+	azureKeyEnv     = "AZURE_STORAGE_KEY"
+	azureSASEnv     = "AZURE_STORAGE_SAS_TOKEN"
+)
+
+// azureTiers The access tier of Blob storage each S3 storage class is stored in
+var azureTiers = map[string]string{
+	StorageClassStandard:           "Hot",
+	StorageClassStandardIA:         "Cool",
+	StorageClassOneZoneIA:          "Cool",
+	StorageClassIntelligentTiering: "Hot",
+	StorageClassGlacier:            "Archive",
+	StorageClassDeepArchive:        "Archive",
+}
+
+// azureS3StorageClasses The S3 storage class an access tier is reported as
+var azureS3StorageClasses = map[string]string{
+	"Hot":     StorageClassStandard,
+	"Cool":    StorageClassStandardIA,
+	"Cold":    StorageClassStandardIA,
+	"Archive": StorageClassGlacier,
+}
+
+// AzureStorage Storage in an Azure Blob storage container, spoken to over the REST API. Requests are signed with the
+// account key, or carry a SAS token when there is no key. ACLs are set on the container, so they are not applied
+type AzureStorage struct {
+	Bucket    string
+	base      *url.URL
+	client    *http.Client
+	account   string
+	key       []byte
+	sas       url.Values
+	blockSize int
+}
+
+// azureBlob A blob in a listing
+type azureBlob struct {
+	Name       string `xml:"Name"`
+	Properties struct {
+		LastModified  string `xml:"Last-Modified"`
+		ETag          string `xml:"Etag"`
+		ContentLength int64  `xml:"Content-Length"`
+		ContentType   string `xml:"Content-Type"`
+		ContentMD5    string `xml:"Content-MD5"`
+		AccessTier    string `xml:"AccessTier"`
+	} `xml:"Properties"`
+	Metadata struct {
+		Items []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"Metadata"`
+}
+
+// azureList A page of a listing
+type azureList struct {
+	Blobs    []*azureBlob `xml:"Blobs>Blob"`
+	Prefixes []string     `xml:"Blobs>BlobPrefix>Name"`
+	Next     string       `xml:"NextMarker"`
+}
+
+// NewAzureStorage Create storage for the container named by the bucket, in the account and with the key or SAS token
+// of the environment. The endpoint is the blob service of the account unless one is set, eg: for Azurite
+func NewAzureStorage(bucket string, opts StorageOptions) (*AzureStorage, error) {
+	account := os.Getenv(azureAccountEnv)
+	if len(account) <= 0 {
+		return nil, fmt.Errorf("Storage backend %v needs the storage account in %v", BackendAzure, azureAccountEnv)
+	}
+
+	endpoint := opts.Endpoint
+	if len(endpoint) <= 0 {
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("Endpoint %v is not an http or https url", endpoint)
+	}
+
+	a := &AzureStorage{Bucket: bucket, base: base, client: &http.Client{Timeout: azureTimeout}, account: account, blockSize: azureBlockSize}
+	if opts.PartSize > 0 {
+		a.blockSize = int(opts.PartSize)
+	}
+
+	if key := os.Getenv(azureKeyEnv); len(key) > 0 {
+		if a.key, err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("%v is not a base64 account key: %v", azureKeyEnv, err)
+		}
+		return a, nil
+	}
+	if sas := os.Getenv(azureSASEnv); len(sas) > 0 {
+		if a.sas, err = url.ParseQuery(strings.TrimPrefix(sas, "?")); err != nil {
+			return nil, fmt.Errorf("%v is not a SAS token: %v", azureSASEnv, err)
+		}
+		return a, nil
+	}
+
+	return nil, fmt.Errorf("Storage backend %v needs the account key in %v or a SAS token in %v", BackendAzure, azureKeyEnv, azureSASEnv)
+}
+
+// url The url of the blob in the container, or of the container itself when the key is empty
+func (a *AzureStorage) url(container string, key string, query url.Values) *url.URL {
+	u := *a.base
+	u.Path = a.base.Path + "/" + container
+	if len(key) > 0 {
+		u.Path += "/" + key
+	}
+	u.RawQuery = query.Encode()
+
+	return &u
+}
+
+// withSAS The url with the SAS token added to its query, when requests carry one
+func (a *AzureStorage) withSAS(u *url.URL) *url.URL {
+	if a.sas == nil {
+		return u
+	}
+
+	signed := *u
+	query := signed.Query()
+	for k, v := range a.sas {
+		query[k] = v
+	}
+	signed.RawQuery = query.Encode()
+
+	return &signed
+}
+
+// azureStringToSign The string a request is signed with for Shared Key authorization
+func azureStringToSign(account string, req *http.Request) string {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var headers []string
+	for k := range req.Header {
+		if name := strings.ToLower(k); strings.HasPrefix(name, "x-ms-") {
+			headers = append(headers, name+":"+strings.TrimSpace(strings.Join(req.Header[k], ","))+"\n")
+		}
+	}
+	sort.Strings(headers)
+
+	resource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	var names []string
+	for k := range query {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		values := query[k]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(values, ",")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		req.Header.Get("Date"),
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(headers, "") + resource,
+	}, "\n")
+}
+
+// request Send a request signed with the account key, or with the SAS token added to its query
+func (a *AzureStorage) request(ctx context.Context, method string, u *url.URL, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, a.withSAS(u).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("x-ms-version", azureVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+
+	if a.key != nil {
+		mac := hmac.New(sha256.New, a.key)
+		mac.Write([]byte(azureStringToSign(a.account, req)))
+		req.Header.Set("Authorization", "SharedKey "+a.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+
+	return a.client.Do(req.WithContext(ctx))
+}
+
+// do Send a request and close the response, failing on anything but a success, a missing blob is ErrNotFound
+func (a *AzureStorage) do(ctx context.Context, method string, u *url.URL, body io.Reader, header http.Header) (http.Header, error) {
+	res, err := a.request(ctx, method, u, body, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, newStatusError(res.Request, res)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+
+	return res.Header, nil
+}
+
+// blobHeaders The headers a blob is stored with
+func blobHeaders(opts UploadOptions) http.Header {
+	header := http.Header{}
+	if len(opts.ContentType) > 0 {
+		header.Set("x-ms-blob-content-type", opts.ContentType)
+	}
+	if len(opts.CacheControl) > 0 {
+		header.Set("x-ms-blob-cache-control", opts.CacheControl)
+	}
+	if len(opts.ContentEncoding) > 0 {
+		header.Set("x-ms-blob-content-encoding", opts.ContentEncoding)
+	}
+	if tier, ok := azureTiers[opts.StorageClass]; ok {
+		header.Set("x-ms-access-tier", tier)
+	}
+	// set as is so the names keep their case
+	for k, v := range opts.Metadata {
+		header["x-ms-meta-"+k] = []string{v}
+	}
+
+	return header
+}
+
+// Upload Put the body in a single request when it fits in a block, or else in blocks committed with a block list.
+// The md5 of the content is stored with the blob either way, so its ETag is the md5 like S3 has
+func (a *AzureStorage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	u := a.url(a.Bucket, key, nil)
+	header := blobHeaders(opts)
+
+	block := make([]byte, a.blockSize)
+	n, err := io.ReadFull(body, block)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		sum := md5.Sum(block[:n])
+		header.Set("x-ms-blob-type", "BlockBlob")
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		_, err = a.do(ctx, http.MethodPut, u, bytes.NewReader(block[:n]), header)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	h := md5.New()
+	for n > 0 {
+		if err := a.putBlock(ctx, key, len(ids), block[:n], h, &ids); err != nil {
+			return err
+		}
+
+		n, err = io.ReadFull(body, block)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+
+	var list bytes.Buffer
+	list.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for _, id := range ids {
+		list.WriteString("<Latest>" + id + "</Latest>")
+	}
+	list.WriteString("</BlockList>")
+
+	header.Set("x-ms-blob-content-md5", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	_, err = a.do(ctx, http.MethodPut, a.url(a.Bucket, key, url.Values{"comp": {"blocklist"}}), &list, header)
+
+	return err
+}
+
+// putBlock Stage a block of the blob, adding its id to ids and its content to the md5 of the blob
+func (a *AzureStorage) putBlock(ctx context.Context, key string, index int, block []byte, h hash.Hash, ids *[]string) error {
+	// ids of the blocks of a blob all have the same length
+	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%08d", index)))
+	if _, err := a.do(ctx, http.MethodPut, a.url(a.Bucket, key, url.Values{"comp": {"block"}, "blockid": {id}}), bytes.NewReader(block), nil); err != nil {
+		return fmt.Errorf("Unable to upload block %v of %v: %v", index, key, err)
+	}
+	h.Write(block)
+	*ids = append(*ids, id)
+
+	return nil
+}
+
+// azureETag The hex md5 of the blob when it has one like S3 has, or else its ETag without quotes
+func azureETag(contentMD5 string, etag string) string {
+	if sum, err := base64.StdEncoding.DecodeString(contentMD5); err == nil && len(sum) > 0 {
+		return hex.EncodeToString(sum)
+	}
+
+	return strings.Trim(etag, `"`)
+}
+
+// Head Get the properties and metadata of the blob
+func (a *AzureStorage) Head(ctx context.Context, key string) (*Object, error) {
+	header, err := a.do(ctx, http.MethodHead, a.url(a.Bucket, key, nil), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Object{
+		Key:          key,
+		ETag:         azureETag(header.Get("Content-MD5"), header.Get("ETag")),
+		ContentType:  header.Get("Content-Type"),
+		StorageClass: azureS3StorageClasses[header.Get("x-ms-access-tier")],
+		Metadata:     make(map[string]string),
+	}
+	o.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if modified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		o.LastModified = modified
+	}
+	for k := range header {
+		if name := strings.ToLower(k); strings.HasPrefix(name, "x-ms-meta-") {
+			o.Metadata[name[len("x-ms-meta-"):]] = header.Get(k)
+		}
+	}
+
+	return o, nil
+}
+
+// Get Get the content of the blob, the caller closes it
+func (a *AzureStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := a.request(ctx, http.MethodGet, a.url(a.Bucket, key, nil), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, newStatusError(res.Request, res)
+	}
+
+	return res.Body, nil
+}
+
+// Copy Server side copy a blob to another key
+func (a *AzureStorage) Copy(ctx context.Context, from string, to string) error {
+	return a.CopyFrom(ctx, a.Bucket, from, to)
+}
+
+// CopyFrom Server side copy a blob from another container of the account, keeping its properties and metadata.
+// Copies within an account are usually done by the time the request returns, the ones that are not are waited on
+func (a *AzureStorage) CopyFrom(ctx context.Context, container string, from string, to string) error {
+	header := http.Header{}
+	header.Set("x-ms-copy-source", a.withSAS(a.url(container, from, nil)).String())
+
+	res, err := a.do(ctx, http.MethodPut, a.url(a.Bucket, to, nil), nil, header)
+	for err == nil {
+		switch status := res.Get("x-ms-copy-status"); status {
+		case "", "success":
+			return nil
+		case "pending":
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(azureCopyPoll):
+			}
+			res, err = a.do(ctx, http.MethodHead, a.url(a.Bucket, to, nil), nil, nil)
+		default:
+			return fmt.Errorf("Copying %v/%v to %v %v: %v", container, from, to, status, res.Get("x-ms-copy-status-description"))
+		}
+	}
+
+	return err
+}
+
+// Delete Delete the blobs and their snapshots, keys that do not exist are ignored like S3 does
+func (a *AzureStorage) Delete(ctx context.Context, keys ...string) error {
+	header := http.Header{}
+	header.Set("x-ms-delete-snapshots", "include")
+
+	for _, key := range keys {
+		if _, err := a.do(ctx, http.MethodDelete, a.url(a.Bucket, key, nil), nil, header); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// list Page through the blobs and prefixes under the prefix, one level deep when there is a delimiter
+func (a *AzureStorage) list(ctx context.Context, prefix string, delimiter string) ([]*Object, []string, error) {
+	var objects []*Object
+	var prefixes []string
+
+	query := url.Values{"restype": {"container"}, "comp": {"list"}, "include": {"metadata"}, "prefix": {prefix}}
+	if len(delimiter) > 0 {
+		query.Set("delimiter", delimiter)
+	}
+	for {
+		res, err := a.request(ctx, http.MethodGet, a.url(a.Bucket, "", query), nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var page azureList
+		if res.StatusCode != http.StatusOK {
+			err = newStatusError(res.Request, res)
+		} else if derr := xml.NewDecoder(res.Body).Decode(&page); derr != nil {
+			err = fmt.Errorf("Unable to parse the listing of %v: %v", prefix, derr)
+		}
+		res.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, b := range page.Blobs {
+			o := &Object{
+				Key:          b.Name,
+				Size:         b.Properties.ContentLength,
+				ETag:         azureETag(b.Properties.ContentMD5, b.Properties.ETag),
+				ContentType:  b.Properties.ContentType,
+				StorageClass: azureS3StorageClasses[b.Properties.AccessTier],
+				Metadata:     make(map[string]string, len(b.Metadata.Items)),
+			}
+			if modified, err := http.ParseTime(b.Properties.LastModified); err == nil {
+				o.LastModified = modified
+			}
+			for _, m := range b.Metadata.Items {
+				o.Metadata[m.XMLName.Local] = m.Value
+			}
+			objects = append(objects, o)
+		}
+		prefixes = append(prefixes, page.Prefixes...)
+
+		if len(page.Next) <= 0 {
+			return objects, prefixes, nil
+		}
+		query.Set("marker", page.Next)
+	}
+}
+
+// List List every blob under the prefix, Blob storage sorts them by key like S3 does
+func (a *AzureStorage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	objects, _, err := a.list(ctx, prefix, "")
+	return objects, err
+}
+
+// ListPrefixes List the prefixes one level below the prefix
+func (a *AzureStorage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	_, prefixes, err := a.list(ctx, prefix, "/")
+	return prefixes, err
+}
+
+// ListIncompleteUploads Blocks that were never committed can not be listed by prefix, Blob storage drops them after a week
+func (a *AzureStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortUpload Blocks that were never committed can not be deleted, Blob storage drops them after a week
+func (a *AzureStorage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	return ErrNotFound
+}
+
+// SetStorageClass Move the blob to the access tier of the S3 storage class
+func (a *AzureStorage) SetStorageClass(ctx context.Context, key string, class string) error {
+	tier, ok := azureTiers[class]
+	if !ok {
+		return fmt.Errorf("Storage class %v has no Blob storage access tier", class)
+	}
+
+	header := http.Header{}
+	header.Set("x-ms-access-tier", tier)
+	_, err := a.do(ctx, http.MethodPut, a.url(a.Bucket, key, url.Values{"comp": {"tier"}}), nil, header)
+
+	return err
+}
+
+// Restore Rehydrate a blob in the archive tier back to hot, it can be read once it is done. Unlike S3 the blob stays hot
+// after, so the days are not used
+func (a *AzureStorage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	header, err := a.do(ctx, http.MethodHead, a.url(a.Bucket, key, nil), nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if header.Get("x-ms-access-tier") != "Archive" {
+		return true, nil
+	}
+	if strings.HasPrefix(header.Get("x-ms-archive-status"), "rehydrate-pending") {
+		return false, nil
+	}
+
+	rehydrate := http.Header{}
+	rehydrate.Set("x-ms-access-tier", "Hot")
+	rehydrate.Set("x-ms-rehydrate-priority", "Standard")
+	_, err = a.do(ctx, http.MethodPut, a.url(a.Bucket, key, url.Values{"comp": {"tier"}}), nil, rehydrate)
+
+	return false, err
+}
@@ -0,0 +1,343 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAzureBlob A blob the fake keeps, with the headers it was stored with
+type fakeAzureBlob struct {
+	content []byte
+	header  http.Header
+}
+
+// fakeAzure Enough of the Blob service for the storage to be tested against, one blob per page of a listing.
+// Every request has to carry the SAS token
+type fakeAzure struct {
+	mu     sync.Mutex
+	blobs  map[string]*fakeAzureBlob
+	blocks map[string][]byte
+}
+
+func newFakeAzure() *fakeAzure {
+	return &fakeAzure{blobs: make(map[string]*fakeAzureBlob), blocks: make(map[string][]byte)}
+}
+
+// put Store the blob with the x-ms-blob headers and metadata of the request
+func (f *fakeAzure) put(name string, content []byte, r *http.Request) {
+	sum := md5.Sum(content)
+	header := http.Header{}
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	header.Set("ETag", `"0x8D`+hex.EncodeToString(sum[:4])+`"`)
+	header.Set("x-ms-access-tier", "Hot")
+	for k, v := range r.Header {
+		switch name := strings.ToLower(k); {
+		case name == "x-ms-blob-content-type":
+			header.Set("Content-Type", v[0])
+		case name == "x-ms-blob-cache-control":
+			header.Set("Cache-Control", v[0])
+		case name == "x-ms-access-tier", strings.HasPrefix(name, "x-ms-meta-"):
+			header.Set(name, v[0])
+		}
+	}
+	f.blobs[name] = &fakeAzureBlob{content: content, header: header}
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	if query.Get("sig") != "secret" {
+		http.Error(w, "no SAS token", http.StatusForbidden)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/account/")
+	body, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodGet && query.Get("comp") == "list":
+		f.list(w, r)
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		f.blocks[name+"/"+query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		xml.Unmarshal(body, &list)
+		var content []byte
+		for _, id := range list.Latest {
+			content = append(content, f.blocks[name+"/"+id]...)
+		}
+		f.put(name, content, r)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "tier":
+		b, ok := f.blobs[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b.header.Set("x-ms-access-tier", r.Header.Get("x-ms-access-tier"))
+	case r.Method == http.MethodPut && len(r.Header.Get("x-ms-copy-source")) > 0:
+		source, _ := url.Parse(r.Header.Get("x-ms-copy-source"))
+		b, ok := f.blobs[strings.TrimPrefix(source.Path, "/account/")]
+		if !ok || source.Query().Get("sig") != "secret" {
+			http.NotFound(w, r)
+			return
+		}
+		header := http.Header{}
+		for k, v := range b.header {
+			header[k] = v
+		}
+		f.blobs[name] = &fakeAzureBlob{content: b.content, header: header}
+		w.Header().Set("x-ms-copy-status", "success")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			http.Error(w, "no blob type", http.StatusBadRequest)
+			return
+		}
+		f.put(name, body, r)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		b, ok := f.blobs[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for k, v := range b.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(b.content)))
+		if r.Method == http.MethodGet {
+			w.Write(b.content)
+		}
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
+
+// list Page through the blobs of the container one by one, the marker is the name of the next one
+func (f *fakeAzure) list(w http.ResponseWriter, r *http.Request) {
+	container := strings.TrimPrefix(r.URL.Path, "/account/") + "/"
+	prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+
+	var names []string
+	seen := make(map[string]bool)
+	for k := range f.blobs {
+		name := strings.TrimPrefix(k, container)
+		if !strings.HasPrefix(k, container) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if i := strings.Index(name[len(prefix):], delimiter); len(delimiter) > 0 && i >= 0 {
+			name = name[:len(prefix)+i+1]
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start := sort.SearchStrings(names, r.URL.Query().Get("marker"))
+	var page bytes.Buffer
+	page.WriteString("<EnumerationResults><Blobs>")
+	if start < len(names) {
+		if b, ok := f.blobs[container+names[start]]; ok {
+			fmt.Fprintf(&page, "<Blob><Name>%v</Name><Properties><Content-Length>%v</Content-Length><Content-MD5>%v</Content-MD5><Content-Type>%v</Content-Type><AccessTier>%v</AccessTier></Properties><Metadata>",
+				names[start], len(b.content), b.header.Get("Content-MD5"), b.header.Get("Content-Type"), b.header.Get("x-ms-access-tier"))
+			for k := range b.header {
+				if name := strings.ToLower(k); strings.HasPrefix(name, "x-ms-meta-") {
+					fmt.Fprintf(&page, "<%v>%v</%v>", name[len("x-ms-meta-"):], b.header.Get(k), name[len("x-ms-meta-"):])
+				}
+			}
+			page.WriteString("</Metadata></Blob>")
+		} else {
+			fmt.Fprintf(&page, "<BlobPrefix><Name>%v</Name></BlobPrefix>", names[start])
+		}
+	}
+	page.WriteString("</Blobs><NextMarker>")
+	if start+1 < len(names) {
+		page.WriteString(names[start+1])
+	}
+	page.WriteString("</NextMarker></EnumerationResults>")
+	w.Write(page.Bytes())
+}
+
+func TestAzureStorage(t *testing.T) {
+	fake := newFakeAzure()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL + "/account")
+	store := &AzureStorage{Bucket: "portal", base: base, client: server.Client(), account: "account", sas: url.Values{"sig": {"secret"}}, blockSize: 16}
+	ctx := context.Background()
+
+	content := []byte("console.log('checkout');")
+	opts := UploadOptions{ContentType: "application/javascript", CacheControl: "max-age=60", Metadata: map[string]string{ChecksumMetadata: "sum"}}
+	for _, key := range []string{"checkout/1.0.0/app.js", "checkout/1.0.0/js/vendor.js", "checkout/1.1.0/app.js", "cart/1.0.0/app.js"} {
+		if err := store.Upload(ctx, key, bytes.NewReader(content), opts); err != nil {
+			t.Fatalf("Upload(%v) failed: %v", key, err)
+		}
+	}
+	if err := store.Upload(ctx, "checkout/1.0.0/small.js", bytes.NewReader(content[:10]), opts); err != nil {
+		t.Fatalf("Upload() of a single block failed: %v", err)
+	}
+
+	sum := md5.Sum(content)
+	o, err := store.Head(ctx, "checkout/1.0.0/app.js")
+	if err != nil {
+		t.Fatalf("Head() failed: %v", err)
+	}
+	if o.Size != int64(len(content)) || o.ETag != hex.EncodeToString(sum[:]) || o.ContentType != "application/javascript" ||
+		o.StorageClass != StorageClassStandard || metadataValue(o.Metadata, ChecksumMetadata) != "sum" {
+		t.Errorf("Head() = %+v, want %v bytes with ETag %x, its content type, class and metadata", o, len(content), sum)
+	}
+	if cacheControl := fake.blobs["portal/checkout/1.0.0/app.js"].header.Get("Cache-Control"); cacheControl != "max-age=60" {
+		t.Errorf("Cache-Control = %v, want max-age=60", cacheControl)
+	}
+	if _, err := store.Head(ctx, "checkout/2.0.0/app.js"); err != ErrNotFound {
+		t.Errorf("Head() of a missing key = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Copy(ctx, "checkout/1.0.0/app.js", "checkout/latest/app.js"); err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	r, err := store.Get(ctx, "checkout/latest/app.js")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Get() = %q, %v, want %q", got, err, content)
+	}
+
+	if err := store.SetStorageClass(ctx, "checkout/1.0.0/app.js", StorageClassGlacier); err != nil {
+		t.Fatalf("SetStorageClass() failed: %v", err)
+	}
+	if o, err := store.Head(ctx, "checkout/1.0.0/app.js"); err != nil || o.StorageClass != StorageClassGlacier {
+		t.Errorf("Head() after SetStorageClass() = %+v, %v, want %v", o, err, StorageClassGlacier)
+	}
+	// the fake rehydrates at once, so the first restore starts it and the second finds the blob hot
+	for _, want := range []bool{false, true} {
+		if restored, err := store.Restore(ctx, "checkout/1.0.0/app.js", 1); err != nil || restored != want {
+			t.Errorf("Restore() = %v, %v, want %v", restored, err, want)
+		}
+	}
+
+	list, err := store.List(ctx, "checkout/1.0.0/")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var keys []string
+	for _, o := range list {
+		keys = append(keys, o.Key)
+	}
+	if want := []string{"checkout/1.0.0/app.js", "checkout/1.0.0/js/vendor.js", "checkout/1.0.0/small.js"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+	if len(list) > 0 && (list[0].ETag != hex.EncodeToString(sum[:]) || metadataValue(list[0].Metadata, ChecksumMetadata) != "sum") {
+		t.Errorf("List() = %+v, want the md5 and metadata of the blob", list[0])
+	}
+
+	prefixes, err := store.ListPrefixes(ctx, "checkout/")
+	if err != nil {
+		t.Fatalf("ListPrefixes() failed: %v", err)
+	}
+	if want := []string{"checkout/1.0.0/", "checkout/1.1.0/", "checkout/latest/"}; !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("ListPrefixes() = %v, want %v", prefixes, want)
+	}
+
+	if err := store.Delete(ctx, "checkout/1.1.0/app.js", "checkout/1.1.0/missing.js"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.Head(ctx, "checkout/1.1.0/app.js"); err != ErrNotFound {
+		t.Errorf("Head() of a deleted key = %v, want ErrNotFound", err)
+	}
+
+	store.sas = url.Values{"sig": {"wrong"}}
+	_, err = store.Head(ctx, "checkout/1.0.0/app.js")
+	if serr, ok := err.(*statusError); !ok || serr.StatusCode != http.StatusForbidden {
+		t.Errorf("Head() with the wrong SAS token = %v, want a 403", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "wrong") {
+		t.Errorf("Head() = %v, the error shows the SAS token", err)
+	}
+}
+
+func TestNewAzureStorageEndpoint(t *testing.T) {
+	for k, v := range map[string]string{azureAccountEnv: "account", azureKeyEnv: "", azureSASEnv: "sig=secret"} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{"blob service of the account", "", "https://account.blob.core.windows.net", false},
+		{"azurite", "http://127.0.0.1:10000/devstoreaccount1/", "http://127.0.0.1:10000/devstoreaccount1", false},
+		{"not http", "ftp://127.0.0.1/account", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := NewAzureStorage("portal", StorageOptions{Endpoint: test.endpoint})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewAzureStorage() = %v, want an error %v", err, test.wantErr)
+			}
+			if err == nil && store.base.String() != test.want {
+				t.Errorf("NewAzureStorage() talks to %v, want %v", store.base, test.want)
+			}
+		})
+	}
+
+	store, err := NewAzureStorage("portal", StorageOptions{PartSize: 16 << 20})
+	if err != nil {
+		t.Fatalf("NewAzureStorage() with a part size failed: %v", err)
+	}
+	if store.blockSize != 16<<20 {
+		t.Errorf("blockSize = %v, want %v", store.blockSize, 16<<20)
+	}
+}
+
+func TestAzureStringToSign(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut, "https://account.blob.core.windows.net/portal/checkout/1.0.0/app%20one.js?comp=block&blockid=YQ%3D%3D", strings.NewReader("hello"))
+	req.Header.Set("x-ms-version", azureVersion)
+	req.Header.Set("x-ms-date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	req.Header["x-ms-meta-sha256"] = []string{"sum"}
+	req.Header.Set("Content-Type", "application/javascript")
+
+	want := "PUT\n\n\n5\n\napplication/javascript\n\n\n\n\n\n\n" +
+		"x-ms-date:Mon, 02 Jan 2006 15:04:05 GMT\nx-ms-meta-sha256:sum\nx-ms-version:2019-12-12\n" +
+		"/account/portal/checkout/1.0.0/app%20one.js\nblockid:YQ==\ncomp:block"
+	if got := azureStringToSign("account", req); got != want {
+		t.Errorf("azureStringToSign() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter Spreads reads across every upload so together they stay under a number of bytes per second
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate int64
+	next time.Time
+}
+
+// newRateLimiter Create a limiter for bytes per second
+func newRateLimiter(rate int64) *rateLimiter {
+	return &rateLimiter{rate: rate}
+}
+
+// wait Account for n bytes that were read, sleeping until the rate allows them
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// readerAtSeeker A body s3manager can upload in parallel parts without buffering them, like a file
+type readerAtSeeker interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+// throttledBody A body that is read no faster than the limiter allows, keeping ReadAt so large files still stream in parts
+type throttledBody struct {
+	body    readerAtSeeker
+	limiter *rateLimiter
+	ctx     context.Context
+}
+
+func (t *throttledBody) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	if werr := t.limiter.wait(t.ctx, n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+func (t *throttledBody) ReadAt(p []byte, off int64) (int, error) {
+	n, err := t.body.ReadAt(p, off)
+	if werr := t.limiter.wait(t.ctx, n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+func (t *throttledBody) Seek(offset int64, whence int) (int64, error) {
+	return t.body.Seek(offset, whence)
+}
+
+// throttle Limit how fast the body is read when the upload has a limiter
+func throttle(ctx context.Context, body readerAtSeeker, limiter *rateLimiter) io.Reader {
+	if limiter == nil {
+		return body
+	}
+
+	return &throttledBody{body: body, limiter: limiter, ctx: ctx}
+}
+
+// ParseBandwidth Parse a rate like 20MB/s or 512KB into bytes per second
+func ParseBandwidth(bandwidth string) (int64, error) {
+	rate, err := ParseSize(strings.TrimSuffix(strings.TrimSpace(bandwidth), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("Bandwidth %v is not valid, use a value like 20MB/s", bandwidth)
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,171 @@
+package journey
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// SizeBudget The most the files matching a glob, or the entrypoints, may weigh. Each file is checked on its own
+// unless total is set, gzip checks the size the browser downloads and warn only warns instead of failing the publish
+type SizeBudget struct {
+	Files   string `json:"files"`
+	Entry   bool   `json:"entry"`
+	MaxSize string `json:"maxSize"`
+	Gzip    bool   `json:"gzip"`
+	Total   bool   `json:"total"`
+	Warn    bool   `json:"warn"`
+}
+
+// validate Validate the budget matches some files and has a size
+func (b *SizeBudget) validate() error {
+	if len(b.Files) <= 0 && !b.Entry {
+		return fmt.Errorf("Size budgets need files, eg: **/*.js, or entry")
+	}
+	if err := validateGlobs("Size budget", []string{b.Files}); err != nil {
+		return err
+	}
+
+	max, err := ParseSize(b.MaxSize)
+	if err != nil {
+		return fmt.Errorf("Size budget of %v: %v", b.name(), err)
+	}
+	if max <= 0 {
+		return fmt.Errorf("Size budget of %v needs a maxSize, eg: 300KB", b.name())
+	}
+
+	return nil
+}
+
+// name What the budget applies to, for messages
+func (b *SizeBudget) name() string {
+	name := b.Files
+	if b.Entry {
+		name = strings.TrimSpace("entry " + name)
+	}
+	if b.Gzip {
+		name += " gzip"
+	}
+
+	return name
+}
+
+// validateSizeBudgets Validate every size budget
+func validateSizeBudgets(budgets []SizeBudget) error {
+	for _, b := range budgets {
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// budgetSizes Sizes of the files of the plan, read once however many budgets match them
+type budgetSizes struct {
+	j    *Journey
+	page []byte
+	raw  map[string]int64
+	gzip map[string]int64
+}
+
+// size The size of the asset in the build, or of the rendered page, gzipped when asked
+func (s *budgetSizes) size(asset string, gzipped bool) (int64, error) {
+	sizes := s.raw
+	if gzipped {
+		sizes = s.gzip
+	}
+	if size, ok := sizes[asset]; ok {
+		return size, nil
+	}
+
+	content := s.page
+	if asset != HTMLFile || s.page == nil {
+		c, err := ioutil.ReadFile(s.j.GetAssetPath(asset))
+		if err != nil {
+			return 0, fmt.Errorf("Unable to read %v to check its size: %v", asset, err)
+		}
+		content = c
+	}
+	if gzipped {
+		gz, err := gzipBytes(content)
+		if err != nil {
+			return 0, fmt.Errorf("Unable to gzip %v to check its size: %v", asset, err)
+		}
+		content = gz
+	}
+	sizes[asset] = int64(len(content))
+
+	return sizes[asset], nil
+}
+
+// CheckSizeBudgets Check the files of the plan against the size budgets, warning about the budgets that only warn
+// and failing with every file over the others
+func (j *Journey) CheckSizeBudgets(p *Plan) error {
+	if len(j.SizeBudgets) <= 0 {
+		return nil
+	}
+
+	entries, err := j.entrypointOrder(p.assets)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, v := range p.assets {
+		if !seen[v] {
+			seen[v] = true
+			files = append(files, v)
+		}
+	}
+	if p.page != nil && !seen[HTMLFile] {
+		files = append(files, HTMLFile)
+	}
+	sort.Strings(files)
+
+	sizes := &budgetSizes{j: j, page: p.page, raw: make(map[string]int64), gzip: make(map[string]int64)}
+	var over []string
+	for _, b := range j.SizeBudgets {
+		max, _ := ParseSize(b.MaxSize)
+
+		var problems []string
+		var total int64
+		for _, f := range files {
+			if _, entry := entries[f]; b.Entry && !entry {
+				continue
+			}
+			if len(b.Files) > 0 && !matchAny([]string{b.Files}, f) {
+				continue
+			}
+
+			size, err := sizes.size(f, b.Gzip)
+			if err != nil {
+				return err
+			}
+			total += size
+			if !b.Total && size > max {
+				problems = append(problems, fmt.Sprintf("%v is %v, over the %v budget of %v", f, FormatSize(size), b.name(), FormatSize(max)))
+			}
+		}
+		if b.Total && total > max {
+			problems = append(problems, fmt.Sprintf("%v is %v in total, over its budget of %v", b.name(), FormatSize(total), FormatSize(max)))
+		}
+
+		if !b.Warn {
+			over = append(over, problems...)
+			continue
+		}
+		for _, problem := range problems {
+			Log.Warnf("%v", problem)
+		}
+	}
+
+	if len(over) > 0 {
+		return fmt.Errorf("%v/%v is over its size budgets:\n%v", j.Name, j.Version, strings.Join(over, "\n"))
+	}
+	Log.Debugf("%v/%v is within its %v size budgets", j.Name, j.Version, len(j.SizeBudgets))
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CanaryFile The object next to the latest journey urls that splits traffic between latest and a canary version
+const CanaryFile = "canary.json"
+
+// CanaryVersion A version taking part in a canary rollout and the percent of traffic it gets
+type CanaryVersion struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Weight  int    `json:"weight"`
+}
+
+// Canary The versions a runtime loader picks between, by weight, during a gradual rollout
+type Canary struct {
+	Stable CanaryVersion `json:"stable"`
+	Canary CanaryVersion `json:"canary"`
+}
+
+// validateCanaryWeight Validate the canary gets some, but not all, of the traffic
+func validateCanaryWeight(weight int) error {
+	if weight < 1 || weight > 99 {
+		return fmt.Errorf("Canary weight %v is not valid, use a percent between 1 and 99", weight)
+	}
+
+	return nil
+}
+
+// currentLatest Get the version latest points at, empty when latest was never set
+func (j *Journey) currentLatest(ctx context.Context, store Storage) (string, error) {
+	return j.channelVersion(ctx, store, Latest)
+}
+
+// StartCanary Send weight percent of the traffic to the version and the rest to latest until the canary is promoted
+func (j *Journey) StartCanary(ctx context.Context, weight int, store Storage) error {
+	if err := validateCanaryWeight(weight); err != nil {
+		return err
+	}
+
+	source := j.GetVersionKey(j.Version, j.UrlsFile())
+	if _, err := store.Head(ctx, source); err != nil {
+		return fmt.Errorf("Unable to start a canary of %v/%v, %v can not be found: %v", j.Name, j.Version, source, err)
+	}
+
+	stable, err := j.currentLatest(ctx, store)
+	if err != nil {
+		return err
+	}
+	if len(stable) <= 0 {
+		return fmt.Errorf("Latest of %v is not set, a canary needs a stable version to share traffic with", j.Name)
+	}
+	if stable == j.Version {
+		return fmt.Errorf("Version %v/%v is already latest", j.Name, j.Version)
+	}
+
+	canary := &Canary{
+		Stable: CanaryVersion{Version: stable, URL: j.CDNDomain + j.GetVersionKey(stable, j.UrlsFile()), Weight: 100 - weight},
+		Canary: CanaryVersion{Version: j.Version, URL: j.CDNDomain + j.GetVersionKey(j.Version, j.UrlsFile()), Weight: weight},
+	}
+
+	data, err := json.MarshalIndent(canary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to parse the canary into json")
+	}
+
+	key := j.GetLatestKey(CanaryFile)
+	if err := store.Upload(ctx, key, bytes.NewReader(data), j.uploadOptions(key, "application/json")); err != nil {
+		return fmt.Errorf("Unable to upload %v: %v", key, err)
+	}
+	Log.Infof("Version %v/%v gets %v%% of the traffic, %v gets the rest", j.Name, j.Version, weight, stable)
+
+	return j.recordHistory(ctx, store, HistoryEntry{Action: ActionCanary, Version: j.Version, Weight: weight})
+}
+
+// GetCanary Get the canary rollout of the journey, nil when there is none
+func (j *Journey) GetCanary(ctx context.Context, store Storage) (*Canary, error) {
+	key := j.GetLatestKey(CanaryFile)
+
+	body, err := store.Get(ctx, key)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+	defer body.Close()
+
+	var canary Canary
+	if err := json.NewDecoder(body).Decode(&canary); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", key, err)
+	}
+
+	return &canary, nil
+}
+
+// PromoteCanary Finish the rollout, pointing latest at the canary version, which is returned
+func (j *Journey) PromoteCanary(ctx context.Context, store Storage) (string, error) {
+	canary, err := j.GetCanary(ctx, store)
+	if err != nil {
+		return "", err
+	}
+	if canary == nil {
+		return "", fmt.Errorf("There is no canary of %v to promote", j.Name)
+	}
+
+	version := canary.Canary.Version
+	if err := j.pointChannel(ctx, store, Latest, version); err != nil {
+		return "", err
+	}
+
+	return version, j.recordHistory(ctx, store, HistoryEntry{Action: ActionPromoteCanary, Version: version})
+}
+
+// endCanary Remove the canary rollout, moving latest by any means ends it
+func (j *Journey) endCanary(ctx context.Context, store Storage) error {
+	if err := store.Delete(ctx, j.GetLatestKey(CanaryFile)); err != nil {
+		return fmt.Errorf("Unable to delete %v: %v", j.GetLatestKey(CanaryFile), err)
+	}
+
+	return nil
+}
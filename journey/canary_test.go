@@ -0,0 +1,113 @@
+package journey
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStartCanary(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    string
+		weight     int
+		setLatest  bool
+		wantErr    bool
+		wantStable int
+	}{
+		{"canary of the next version", "1.1.0", 10, true, false, 90},
+		{"weight too low", "1.1.0", 0, true, true, 0},
+		{"weight too high", "1.1.0", 100, true, true, 0},
+		{"no latest", "1.1.0", 10, false, true, 0},
+		{"canary of latest", "1.0.0", 10, true, true, 0},
+		{"not published", "2.0.0", 10, true, true, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+			publishVersions(t, store, "1.0.0", "1.1.0")
+
+			stable, cleanup := newTestJourney(t, "1.0.0")
+			defer cleanup()
+			if test.setLatest {
+				if err := stable.SetLatest(ctx, store); err != nil {
+					t.Fatalf("SetLatest() failed: %v", err)
+				}
+			}
+
+			j, cleanup := newTestJourney(t, test.version)
+			defer cleanup()
+			if err := j.StartCanary(ctx, test.weight, store); (err != nil) != test.wantErr {
+				t.Fatalf("StartCanary() = %v, want an error %v", err, test.wantErr)
+			}
+
+			canary, err := j.GetCanary(ctx, store)
+			if err != nil {
+				t.Fatalf("GetCanary() failed: %v", err)
+			}
+			if test.wantErr {
+				if canary != nil {
+					t.Errorf("GetCanary() = %+v, want no canary", canary)
+				}
+				return
+			}
+			if canary == nil || canary.Stable.Version != "1.0.0" || canary.Stable.Weight != test.wantStable || canary.Canary.Version != test.version || canary.Canary.Weight != test.weight {
+				t.Fatalf("GetCanary() = %+v, want %v at %v%% and 1.0.0 at %v%%", canary, test.version, test.weight, test.wantStable)
+			}
+			if want := "https://cdn.example.com/" + j.GetVersionKey(test.version, JourneyUrlsFile); canary.Canary.URL != want {
+				t.Errorf("Canary.URL = %v, want %v", canary.Canary.URL, want)
+			}
+		})
+	}
+}
+
+func TestPromoteCanary(t *testing.T) {
+	tests := []struct {
+		name       string
+		canary     bool
+		wantErr    bool
+		wantLatest string
+	}{
+		{"canary running", true, false, "1.1.0"},
+		{"no canary", false, true, "1.0.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+			publishVersions(t, store, "1.0.0", "1.1.0")
+
+			stable, cleanup := newTestJourney(t, "1.0.0")
+			defer cleanup()
+			if err := stable.SetLatest(ctx, store); err != nil {
+				t.Fatalf("SetLatest() failed: %v", err)
+			}
+
+			j, cleanup := newTestJourney(t, "1.1.0")
+			defer cleanup()
+			if test.canary {
+				if err := j.StartCanary(ctx, 25, store); err != nil {
+					t.Fatalf("StartCanary() failed: %v", err)
+				}
+			}
+
+			version, err := j.PromoteCanary(ctx, store)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("PromoteCanary() = %v, want an error %v", err, test.wantErr)
+			}
+			if !test.wantErr && version != test.wantLatest {
+				t.Errorf("PromoteCanary() = %v, want %v", version, test.wantLatest)
+			}
+			if got := latestVersion(t, j, store); got != test.wantLatest {
+				t.Errorf("latest = %v, want %v", got, test.wantLatest)
+			}
+
+			// moving latest ends the rollout
+			if canary, err := j.GetCanary(ctx, store); err != nil || canary != nil {
+				t.Errorf("GetCanary() after PromoteCanary() = %+v, %v, want no canary", canary, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layouts of the keys of a version, under the version or content addressed
+const (
+	LayoutVersion = "version"
+	LayoutCAS     = "cas"
+)
+
+// CASPrefix Where every asset is published by the sha256 of its content in the cas layout, eg: {name}/cas/{sha256}
+const CASPrefix = "cas"
+
+// casGracePeriod Unreferenced content is only deleted once it is this old, so a publish still uploading is left alone
+const casGracePeriod = 24 * time.Hour
+
+// validateLayout Validate the layout is supported
+func validateLayout(layout string) error {
+	switch layout {
+	case "", LayoutVersion, LayoutCAS:
+		return nil
+	default:
+		return fmt.Errorf("Layout %v is not supported, use %v or %v", layout, LayoutVersion, LayoutCAS)
+	}
+}
+
+// contentPrefixes The prefixes of objects named by their content, which versions share
+func (j *Journey) contentPrefixes() []string {
+	return []string{j.GetJourneyKey(SharedPrefix + "/"), j.GetJourneyKey(CASPrefix + "/")}
+}
+
+// referencedContent The content addressed keys any journey urls in the bucket point at, of versions, latest and channels,
+// along with their compressed variants
+func (j *Journey) referencedContent(ctx context.Context, store Storage) (map[string]bool, error) {
+	prefixes, err := store.ListPrefixes(ctx, j.GetJourneyKey(""))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list %v: %v", j.Name, err)
+	}
+
+	referenced := make(map[string]bool)
+	for _, prefix := range prefixes {
+		name := strings.TrimSuffix(strings.TrimPrefix(prefix, j.GetJourneyKey("")), "/")
+		if name == SharedPrefix || name == CASPrefix {
+			continue
+		}
+
+		key, err := j.findUrlsFile(ctx, store, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) <= 0 {
+			continue
+		}
+
+		urls, err := getJourneyUrls(ctx, store, key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := j.sharedUrlKeys(urls)
+		if err != nil {
+			return nil, fmt.Errorf("%v/%v: %v", j.Name, name, err)
+		}
+		for _, key := range keys {
+			referenced[key] = true
+			for _, suffix := range encodingSuffixes {
+				referenced[key+suffix] = true
+			}
+		}
+	}
+
+	return referenced, nil
+}
+
+// findUrlsFile Find the key of the journey urls of a version or channel, which may have been published under the default
+// name before paths.urlsFile was changed. Empty for a publish that has not finished, whose content the grace period keeps,
+// while a published version without journey urls fails the collection so none of its content is deleted
+func (j *Journey) findUrlsFile(ctx context.Context, store Storage, name string) (string, error) {
+	files := []string{j.UrlsFile()}
+	if j.UrlsFile() != JourneyUrlsFile {
+		files = append(files, JourneyUrlsFile)
+	}
+
+	for _, file := range files {
+		key := j.GetVersionKey(name, file)
+		if _, err := store.Head(ctx, key); err == nil {
+			return key, nil
+		} else if err != ErrNotFound {
+			return "", fmt.Errorf("Unable to get %v: %v", key, err)
+		}
+	}
+
+	key := j.GetVersionKey(name, JourneyFile)
+	if _, err := store.Head(ctx, key); err == ErrNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+
+	return "", fmt.Errorf("Version %v/%v has no %v, not deleting content it may still use", j.Name, name, strings.Join(files, " or "))
+}
+
+// CollectContent Delete the content addressed objects no journey urls point at anymore, like after versions are pruned
+func (j *Journey) CollectContent(ctx context.Context, store Storage) error {
+	referenced, err := j.referencedContent(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	var unused []string
+	for _, prefix := range j.contentPrefixes() {
+		objects, err := store.List(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("Unable to list %v: %v", prefix, err)
+		}
+		for _, o := range objects {
+			if !referenced[o.Key] && time.Since(o.LastModified) > casGracePeriod {
+				unused = append(unused, o.Key)
+			}
+		}
+	}
+
+	if len(unused) <= 0 {
+		return nil
+	}
+
+	if err := store.Delete(ctx, unused...); err != nil {
+		return fmt.Errorf("Unable to delete the objects no version of %v uses: %v", j.Name, err)
+	}
+	Log.Infof("Deleted %v objects no version of %v uses", len(unused), j.Name)
+
+	return nil
+}
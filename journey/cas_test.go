@@ -0,0 +1,140 @@
+package journey
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// publishCAS Publish the version in the cas layout with every file in the build, a LICENSE journey-urls.json does not
+// list and gzip and brotli variants included, the js of each version differs so it is content of its own
+func publishCAS(t *testing.T, store Storage, version string) *Journey {
+	j, cleanup := newTestJourney(t, version)
+	defer cleanup()
+	j.Layout = LayoutCAS
+	j.IncludeAll = true
+	j.Compress = []string{EncodingGzip, EncodingBrotli}
+	j.CompressVariants = true
+
+	files := map[string]string{"app.js": "console.log('" + version + "');", "LICENSE": "MIT"}
+	for name, content := range files {
+		if err := ioutil.WriteFile(j.GetAssetPath(name), []byte(content), 0644); err != nil {
+			t.Fatalf("Unable to write %v: %v", name, err)
+		}
+	}
+
+	if _, err := j.Publish(context.Background(), testAssets(), store); err != nil {
+		t.Fatalf("Publish(%v) failed: %v", version, err)
+	}
+
+	return j
+}
+
+func TestPublishCAS(t *testing.T) {
+	store := NewMemoryStorage("portal")
+	j := publishCAS(t, store, "1.0.0")
+
+	want := []string{JourneyFile, j.UrlsFile(), ManifestFile, "LICENSE"}
+	for _, file := range want {
+		if _, err := store.Head(context.Background(), j.GetAssetKey(file)); err != nil {
+			t.Errorf("Head(%v) = %v, want it under the version", file, err)
+		}
+	}
+	if keys := storedKeys(t, store, j.GetJourneyKey(CASPrefix+"/")); len(keys) != 6 {
+		t.Errorf("cas has %v, want app.js and app.css with their gzip and brotli variants", keys)
+	}
+}
+
+func TestCollectContent(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage("portal")
+	publishCAS(t, store, "1.0.0")
+	j := publishCAS(t, store, "1.1.0")
+
+	// age everything past the grace period, which only protects publishes that are still uploading, 1.0.0 the most
+	for key, o := range store.objects(store.Bucket) {
+		o.LastModified = time.Now().Add(-2 * casGracePeriod)
+		if strings.HasPrefix(key, j.GetVersionKey("1.0.0", "")) {
+			o.LastModified = o.LastModified.Add(-time.Hour)
+		}
+	}
+
+	if _, err := j.Prune(ctx, store, 1, 0, "", true); err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+
+	if keys := storedKeys(t, store, j.GetVersionKey("1.0.0", "")); len(keys) > 0 {
+		t.Errorf("1.0.0 has %v left, want it pruned", keys)
+	}
+	if _, err := store.Head(ctx, j.GetAssetKey("LICENSE")); err != nil {
+		t.Errorf("Head(LICENSE) = %v, want the file journey-urls.json does not list kept", err)
+	}
+
+	urls, err := getJourneyUrls(ctx, store, j.GetAssetKey(j.UrlsFile()))
+	if err != nil {
+		t.Fatalf("getJourneyUrls() failed: %v", err)
+	}
+	keys, err := j.sharedUrlKeys(urls)
+	if err != nil {
+		t.Fatalf("sharedUrlKeys() failed: %v", err)
+	}
+	for _, key := range keys {
+		for _, suffix := range []string{"", ".gz", ".br"} {
+			if _, err := store.Head(ctx, key+suffix); err != nil {
+				t.Errorf("Head(%v) = %v, want the content 1.1.0 uses kept", key+suffix, err)
+			}
+		}
+	}
+
+	// app.css is the same in both versions, only the js of 1.0.0 and its variants are unused
+	if got := storedKeys(t, store, j.GetJourneyKey(CASPrefix+"/")); len(got) != 6 {
+		t.Errorf("cas has %v, want the 6 objects 1.1.0 uses", got)
+	}
+}
+
+func TestPlanPublishCASRules(t *testing.T) {
+	j, cleanup := newTestJourney(t, "1.0.0")
+	defer cleanup()
+	j.Layout = LayoutCAS
+	j.Compress = []string{EncodingGzip}
+	j.CompressVariants = true
+	j.CacheControl = map[string]string{"app.css": "max-age=60", ".js": "max-age=31536000, immutable", "*": "no-cache"}
+	j.StorageClasses = map[string]string{".css": StorageClassOneZoneIA}
+
+	plan, err := j.PlanPublish(testAssets())
+	if err != nil {
+		t.Fatalf("PlanPublish() failed: %v", err)
+	}
+
+	tests := []struct {
+		asset            string
+		wantCacheControl string
+		wantClass        string
+	}{
+		{"app.js", "max-age=31536000, immutable", ""},
+		{"app.css", "max-age=60", StorageClassOneZoneIA},
+	}
+
+	for _, test := range tests {
+		key, err := j.assetKey(test.asset)
+		if err != nil {
+			t.Fatalf("assetKey(%v) failed: %v", test.asset, err)
+		}
+		found := 0
+		for _, u := range plan.Uploads {
+			// the gzip variant is matched by the file it is the content of too
+			if u.Key != key && u.Key != key+".gz" {
+				continue
+			}
+			found++
+			if u.CacheControl != test.wantCacheControl || u.StorageClass != test.wantClass {
+				t.Errorf("%v has Cache-Control %q and class %q, want %q and %q", u.Key, u.CacheControl, u.StorageClass, test.wantCacheControl, test.wantClass)
+			}
+		}
+		if found != 2 {
+			t.Errorf("Plan has %v uploads of %v, want it and its gzip variant", found, test.asset)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+)
+
+// Cdns whose cache -invalidate can purge
+const (
+	CDNCloudFront = "cloudfront"
+	CDNFastly     = "fastly"
+	CDNCloudflare = "cloudflare"
+)
+
+// purgeTimeout How long purging the cache of the fastly and cloudflare apis may take
+const purgeTimeout = 30 * time.Second
+
+// Purge The cdn in front of the bucket whose cache -invalidate purges, CloudFront with distributionID unless set
+type Purge struct {
+	Provider  string `json:"provider"`
+	ServiceID string `json:"serviceID"`
+	ZoneID    string `json:"zoneID"`
+	Token     string `json:"token" journey:"private"`
+}
+
+// validate Validate the cdn has what its api needs
+func (p *Purge) validate() error {
+	switch p.Provider {
+	case "", CDNCloudFront:
+	case CDNFastly:
+		if len(p.ServiceID) <= 0 || len(p.Token) <= 0 {
+			return fmt.Errorf("Purging %v needs a serviceID and token", CDNFastly)
+		}
+	case CDNCloudflare:
+		if len(p.ZoneID) <= 0 || len(p.Token) <= 0 {
+			return fmt.Errorf("Purging %v needs a zoneID and token", CDNCloudflare)
+		}
+	default:
+		return fmt.Errorf("Purging %v is not supported, use %v, %v or %v", p.Provider, CDNCloudFront, CDNFastly, CDNCloudflare)
+	}
+
+	return nil
+}
+
+// CDN Purges the cached files of a channel, latest included, from the cdn in front of the bucket
+type CDN interface {
+	Purge(ctx context.Context, channel string) error
+}
+
+// NewCDN The cdn set by purge in journey.json
+func (j *Journey) NewCDN(sess *session.Session) CDN {
+	switch j.Purge.Provider {
+	case CDNFastly:
+		return &fastlyCDN{j: j}
+	case CDNCloudflare:
+		return &cloudflareCDN{j: j}
+	default:
+		return &cloudFrontCDN{j: j, svc: cloudfront.New(sess)}
+	}
+}
+
+// channelFiles The files kept in the path of a channel
+func (j *Journey) channelFiles(channel string) []string {
+	files := []string{j.UrlsFile()}
+	if j.ImportMap {
+		files = append(files, ImportMapFile)
+	}
+	if j.hasOutputFormat(OutputSingleSpa) {
+		files = append(files, SingleSpaImportMapFile, SingleSpaFile)
+	}
+	if channel == Latest {
+		files = append(files, LatestFile, CanaryFile)
+	} else {
+		files = append(files, ChannelFile)
+	}
+
+	return files
+}
+
+// purgeRequest Send a request to the api of the cdn and check it succeeded
+func purgeRequest(ctx context.Context, req *http.Request, what string) error {
+	client := &http.Client{Timeout: purgeTimeout}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Unable to purge %v: %v", what, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Unable to purge %v: %v", what, res.Status)
+	}
+
+	return nil
+}
+
+// cloudFrontCDN Invalidates the path of the channel on the distribution set by distributionID
+type cloudFrontCDN struct {
+	j   *Journey
+	svc *cloudfront.CloudFront
+}
+
+// Purge Create an invalidation of every file in the path of the channel
+func (c *cloudFrontCDN) Purge(ctx context.Context, channel string) error {
+	if len(c.j.DistributionID) <= 0 {
+		return fmt.Errorf("A distributionID is required in journey.json to invalidate %v", channel)
+	}
+
+	path := "/" + c.j.GetChannelKey(channel, "*")
+	out, err := c.svc.CreateInvalidationWithContext(ctx, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(c.j.DistributionID),
+		InvalidationBatch: &cloudfront.InvalidationBatch{
+			CallerReference: aws.String(c.j.Name + "-" + c.j.Version + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)),
+			Paths: &cloudfront.Paths{
+				Items:    []*string{aws.String(path)},
+				Quantity: aws.Int64(1),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to invalidate %v on distribution %v: %v", path, c.j.DistributionID, err)
+	}
+	Log.Infof("Created invalidation %v for %v", aws.StringValue(out.Invalidation.Id), path)
+
+	return nil
+}
+
+// fastlyCDN Purges the surrogate key of the channel, {name}/{channel}, which the service has to set on the responses
+type fastlyCDN struct {
+	j *Journey
+}
+
+// Purge Purge every object tagged with the surrogate key of the channel
+func (f *fastlyCDN) Purge(ctx context.Context, channel string) error {
+	key := strings.TrimSuffix(f.j.GetChannelKey(channel, ""), "/")
+	// the key goes in a header since its slash can not be part of the path of the single key purge
+	req, err := http.NewRequest(http.MethodPost, "https://api.fastly.com/service/"+f.j.Purge.ServiceID+"/purge", nil)
+	if err != nil {
+		return fmt.Errorf("Fastly service %v is not valid: %v", f.j.Purge.ServiceID, err)
+	}
+	req.Header.Set("Fastly-Key", f.j.Purge.Token)
+	req.Header.Set("Surrogate-Key", key)
+	req.Header.Set("Accept", "application/json")
+
+	if err := purgeRequest(ctx, req, "surrogate key "+key+" on Fastly service "+f.j.Purge.ServiceID); err != nil {
+		return err
+	}
+	Log.Infof("Purged surrogate key %v on Fastly service %v", key, f.j.Purge.ServiceID)
+
+	return nil
+}
+
+// cloudflareCDN Purges the urls of the files in the path of the channel from the zone
+type cloudflareCDN struct {
+	j *Journey
+}
+
+// Purge Purge the url of every file in the path of the channel
+func (c *cloudflareCDN) Purge(ctx context.Context, channel string) error {
+	var files []string
+	for _, f := range c.j.channelFiles(channel) {
+		files = append(files, c.j.CDNDomain+escapeKey(c.j.GetChannelKey(channel, f)))
+	}
+
+	body, err := json.Marshal(map[string][]string{"files": files})
+	if err != nil {
+		return fmt.Errorf("Unable to parse the urls to purge into json")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.cloudflare.com/client/v4/zones/"+c.j.Purge.ZoneID+"/purge_cache", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Cloudflare zone %v is not valid: %v", c.j.Purge.ZoneID, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.j.Purge.Token)
+
+	if err := purgeRequest(ctx, req, channel+" on Cloudflare zone "+c.j.Purge.ZoneID); err != nil {
+		return err
+	}
+	Log.Infof("Purged %v urls of %v on Cloudflare zone %v", len(files), channel, c.j.Purge.ZoneID)
+
+	return nil
+}
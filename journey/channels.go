@@ -0,0 +1,171 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ChannelInfo A channel of the journey and the version it points at
+type ChannelInfo struct {
+	Channel string
+	Version string
+	Updated time.Time
+}
+
+// channelName Channels are lower case names like beta or canary so they can not be mistaken for versions
+var channelName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// GetChannelKey Get the key of a file in the path of a channel of the journey
+func (j *Journey) GetChannelKey(channel string, file string) string {
+	return j.GetJourneyKey(channel + "/" + file)
+}
+
+// validateChannel Validate the channel name can not be mistaken for a version
+func validateChannel(channel string) error {
+	if !channelName.MatchString(channel) {
+		return fmt.Errorf("Channel %q is not valid, use lower case letters, numbers and dashes, eg: beta", channel)
+	}
+	if _, err := ParseSemver(channel); err == nil {
+		return fmt.Errorf("Channel %v is a version, pick a name like beta or canary", channel)
+	}
+
+	return nil
+}
+
+// SetChannel Copy the journey urls of the version to the path of the channel, latest is the stable channel
+func (j *Journey) SetChannel(ctx context.Context, channel string, store Storage) error {
+	if channel == Latest {
+		return j.SetLatest(ctx, store)
+	}
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+
+	// a version published with -skip-semver could have the same name
+	if _, err := store.Head(ctx, j.GetVersionKey(channel, JourneyFile)); err == nil {
+		return fmt.Errorf("Channel %v is the name of a published version of %v", channel, j.Name)
+	}
+
+	source := j.GetVersionKey(j.Version, j.UrlsFile())
+	if _, err := store.Head(ctx, source); err != nil {
+		return fmt.Errorf("Unable to point %v at %v/%v, %v can not be found: %v", channel, j.Name, j.Version, source, err)
+	}
+
+	if err := j.pointChannel(ctx, store, channel, j.Version); err != nil {
+		return err
+	}
+
+	return j.recordHistory(ctx, store, HistoryEntry{Action: ActionSetChannel, Channel: channel, Version: j.Version})
+}
+
+// pointChannel Copy the journey urls of the version to the path of the channel
+func (j *Journey) pointChannel(ctx context.Context, store Storage, channel string, version string) error {
+	if archived, err := j.isArchived(ctx, store, version); err != nil {
+		return err
+	} else if archived {
+		return fmt.Errorf("Version %v/%v is archived, restore it before pointing %v at it", j.Name, version, channel)
+	}
+
+	previous, err := j.channelVersion(ctx, store, channel)
+	if err != nil {
+		return err
+	}
+
+	source := j.GetVersionKey(version, j.UrlsFile())
+
+	if err := store.Copy(ctx, source, j.GetChannelKey(channel, j.UrlsFile())); err != nil {
+		return fmt.Errorf("Unable to copy %v to %v: %v", source, channel, err)
+	}
+	if err := j.pointImportMap(ctx, store, channel, version); err != nil {
+		return err
+	}
+	if err := j.writePointer(ctx, store, channel, version, previous); err != nil {
+		return err
+	}
+	Log.Infof("Version %v/%v is now %v", j.Name, version, channel)
+
+	if channel == Latest {
+		return j.endCanary(ctx, store)
+	}
+
+	return nil
+}
+
+// channelVersion Get the version the channel points at, empty when it was never set
+func (j *Journey) channelVersion(ctx context.Context, store Storage, channel string) (string, error) {
+	pointer, err := j.getPointer(ctx, store, channel)
+	if err != nil {
+		return "", err
+	}
+	if pointer != nil {
+		return pointer.Version, nil
+	}
+
+	// set before the version was recorded
+	channels, err := j.ListChannels(ctx, store)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range channels {
+		if c.Channel == channel {
+			return c.Version, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ListChannels List every channel of the journey, latest included, and the version its latest.json or channel.json
+// records. A channel set before those were written holds a copy of a version's journey urls, so the matching content
+// tells us which version it is, which is ambiguous when versions have the same journey urls like in the cas layout
+func (j *Journey) ListChannels(ctx context.Context, store Storage) ([]*ChannelInfo, error) {
+	prefixes, err := store.ListPrefixes(ctx, j.GetJourneyKey(""))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the channels of %v: %v", j.Name, err)
+	}
+
+	versions := make(map[string]string)
+	var channels []*ChannelInfo
+	var unrecorded []*ChannelInfo
+	for _, prefix := range prefixes {
+		name := strings.TrimSuffix(strings.TrimPrefix(prefix, j.GetJourneyKey("")), "/")
+
+		urls, err := store.Head(ctx, j.GetVersionKey(name, j.UrlsFile()))
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get %v: %v", j.GetVersionKey(name, j.UrlsFile()), err)
+		}
+
+		// versions have a journey.json, channels only have journey urls
+		if _, err := store.Head(ctx, j.GetVersionKey(name, JourneyFile)); err == nil {
+			versions[j.objectContent(urls)] = name
+			continue
+		} else if err != ErrNotFound {
+			return nil, fmt.Errorf("Unable to get %v/%v: %v", j.Name, name, err)
+		}
+
+		c := &ChannelInfo{Channel: name, Updated: urls.LastModified}
+		pointer, err := j.getPointer(ctx, store, name)
+		if err != nil {
+			return nil, err
+		}
+		if pointer != nil {
+			c.Version = pointer.Version
+		} else {
+			c.Version = j.objectContent(urls)
+			unrecorded = append(unrecorded, c)
+		}
+		channels = append(channels, c)
+	}
+
+	for _, c := range unrecorded {
+		c.Version = versions[c.Version]
+	}
+
+	return channels, nil
+}
@@ -0,0 +1,160 @@
+package journey
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSetChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		version string
+		wantErr bool
+	}{
+		{"beta", "beta", "1.1.0", false},
+		{"latest", Latest, "1.0.0", false},
+		{"upper case", "Beta", "1.1.0", true},
+		{"version as channel", "2.0.0", "1.1.0", true},
+		{"published version as channel", "v1", "1.1.0", true},
+		{"not published", "beta", "2.0.0", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+			publishVersions(t, store, "1.0.0", "1.1.0")
+
+			// a version published with -skip-semver, which a channel can not shadow
+			v1, cleanup := newTestJourney(t, "v1")
+			defer cleanup()
+			v1.SkipSemver = true
+			if _, err := v1.Publish(ctx, testAssets(), store); err != nil {
+				t.Fatalf("Publish(v1) failed: %v", err)
+			}
+
+			j, cleanup := newTestJourney(t, test.version)
+			defer cleanup()
+			err := j.SetChannel(ctx, test.channel, store)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("SetChannel() = %v, want an error %v", err, test.wantErr)
+			}
+
+			channels, err := j.ListChannels(ctx, store)
+			if err != nil {
+				t.Fatalf("ListChannels() failed: %v", err)
+			}
+			got := make(map[string]string)
+			for _, c := range channels {
+				got[c.Channel] = c.Version
+			}
+			want := map[string]string{}
+			if !test.wantErr {
+				want[test.channel] = test.version
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ListChannels() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestListChannels(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage("portal")
+	publishVersions(t, store, "1.0.0", "1.1.0", "1.2.0")
+
+	pointed := []struct {
+		channel string
+		version string
+	}{
+		{Latest, "1.0.0"},
+		{"beta", "1.1.0"},
+		{"next", "1.2.0"},
+		{"beta", "1.2.0"},
+	}
+	for _, p := range pointed {
+		j, cleanup := newTestJourney(t, p.version)
+		err := j.SetChannel(ctx, p.channel, store)
+		cleanup()
+		if err != nil {
+			t.Fatalf("SetChannel(%v, %v) failed: %v", p.channel, p.version, err)
+		}
+	}
+
+	j, cleanup := newTestJourney(t, "1.2.0")
+	defer cleanup()
+	channels, err := j.ListChannels(ctx, store)
+	if err != nil {
+		t.Fatalf("ListChannels() failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, c := range channels {
+		got[c.Channel] = c.Version
+	}
+	if want := map[string]string{Latest: "1.0.0", "beta": "1.2.0", "next": "1.2.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListChannels() = %v, want %v", got, want)
+	}
+}
+
+func TestListChannelsIdenticalVersions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage("portal")
+
+	// the same build in the cas layout gives every version the same journey urls
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		j, cleanup := newTestJourney(t, version)
+		j.Layout = LayoutCAS
+		_, err := j.Publish(ctx, testAssets(), store)
+		cleanup()
+		if err != nil {
+			t.Fatalf("Publish(%v) failed: %v", version, err)
+		}
+	}
+
+	pointed := []struct {
+		channel string
+		version string
+	}{
+		{Latest, "1.1.0"},
+		{"beta", "1.0.0"},
+	}
+	for _, p := range pointed {
+		j, cleanup := newTestJourney(t, p.version)
+		err := j.SetChannel(ctx, p.channel, store)
+		cleanup()
+		if err != nil {
+			t.Fatalf("SetChannel(%v, %v) failed: %v", p.channel, p.version, err)
+		}
+	}
+
+	j, cleanup := newTestJourney(t, "1.0.0")
+	defer cleanup()
+	channels, err := j.ListChannels(ctx, store)
+	if err != nil {
+		t.Fatalf("ListChannels() failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, c := range channels {
+		got[c.Channel] = c.Version
+	}
+	if want := map[string]string{Latest: "1.1.0", "beta": "1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListChannels() = %v, want %v", got, want)
+	}
+
+	versions, err := j.ListVersions(ctx, store)
+	if err != nil {
+		t.Fatalf("ListVersions() failed: %v", err)
+	}
+	for _, v := range versions {
+		if v.Latest != (v.Version == "1.1.0") {
+			t.Errorf("ListVersions() has %v with Latest %v, want only 1.1.0 latest", v.Version, v.Latest)
+		}
+	}
+	if latest, err := j.IsLatest(ctx, store, "1.0.0"); err != nil || latest {
+		t.Errorf("IsLatest(1.0.0) = %v, %v, want false", latest, err)
+	}
+}
@@ -0,0 +1,163 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// s3ETag Compute the ETag S3 gives the size bytes read from r when the uploader sends them in parts of partSize,
+// the md5 of the content for a single PutObject or the md5 of every part's md5 followed by the number of parts
+func s3ETag(r io.Reader, size int64, partSize int64) (string, error) {
+	if partSize <= 0 {
+		partSize = s3manager.DefaultUploadPartSize
+	}
+	// the uploader grows the parts of very large files so they fit in the most parts S3 allows
+	if size/partSize >= int64(s3manager.MaxUploadParts) {
+		partSize = size/int64(s3manager.MaxUploadParts) + 1
+	}
+
+	// small files are a single PutObject and the ETag is the md5 of the content
+	if size <= partSize {
+		h := md5.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	var sums []byte
+	parts := 0
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, r, partSize)
+		if n > 0 {
+			sums = append(sums, h.Sum(nil)...)
+			parts++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%v-%d", hex.EncodeToString(sum[:]), parts), nil
+}
+
+// fileETag Compute the ETag S3 will give the file at path when uploaded in parts of partSize, zero for the default
+func fileETag(path string, partSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	return s3ETag(f, info.Size(), partSize)
+}
+
+// bytesETag Compute the ETag S3 will give data uploaded in parts of partSize, zero for the default
+func bytesETag(data []byte, partSize int64) string {
+	// reading from memory does not fail
+	etag, _ := s3ETag(bytes.NewReader(data), int64(len(data)), partSize)
+	return etag
+}
+
+// etag Compute the ETag S3 will give the upload
+func (u *Upload) etag() (string, error) {
+	if u.Body != nil {
+		return bytesETag(u.Body, u.PartSize), nil
+	}
+
+	return fileETag(u.Path, u.PartSize)
+}
+
+// fileIntegrity Compute the subresource integrity of the file at path, browsers check it against the decoded content
+func fileIntegrity(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha512.New384()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// ChecksumMetadata The object metadata holding the hex sha256 of the content
+const ChecksumMetadata = "sha256"
+
+// checksums Compute the ETag S3 will give the upload and the sha256 of its content
+func (u *Upload) checksums() (string, string, error) {
+	etag, err := u.etag()
+	if err != nil {
+		return "", "", err
+	}
+	if u.Body != nil {
+		sum := sha256.Sum256(u.Body)
+		return etag, hex.EncodeToString(sum[:]), nil
+	}
+
+	f, err := os.Open(u.Path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", "", err
+	}
+
+	return etag, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// metadataValue Get the metadata value for the key, S3 returns the keys in canonical header case
+func metadataValue(metadata map[string]string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// verifyUpload Check the stored object has the ETag and sha256 computed before uploading it
+func verifyUpload(ctx context.Context, store Storage, u *Upload, etag string, sum string) error {
+	o, err := store.Head(ctx, u.Key)
+	if err != nil {
+		return fmt.Errorf("Unable to verify the upload: %v", err)
+	}
+
+	if u.VerifyETag && o.ETag != etag {
+		return fmt.Errorf("Uploaded object has the ETag %v, expected %v", o.ETag, etag)
+	}
+	if stored := metadataValue(o.Metadata, ChecksumMetadata); stored != sum {
+		return fmt.Errorf("Uploaded object has the sha256 %v, expected %v", stored, sum)
+	}
+
+	Log.Debugf("Key: %v, verified with sha256 %v", u.Key, sum)
+	return nil
+}
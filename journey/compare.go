@@ -0,0 +1,140 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Comparison The differences between two published versions
+type Comparison struct {
+	From    string
+	To      string
+	Objects *Diff
+	Sizes   map[string][2]int64
+	Urls    *Diff
+}
+
+// String Print the comparison as a report of objects and journey urls that changed
+func (c *Comparison) String() string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "Comparing %v to %v\n", c.From, c.To)
+	fmt.Fprintln(&b, "Objects:")
+	for _, k := range c.Objects.Added {
+		fmt.Fprintf(&b, "+ %v (%v)\n", k, FormatSize(c.Sizes[k][1]))
+	}
+	for _, k := range c.Objects.Removed {
+		fmt.Fprintf(&b, "- %v (%v)\n", k, FormatSize(c.Sizes[k][0]))
+	}
+	for _, k := range c.Objects.Changed {
+		fmt.Fprintf(&b, "~ %v (%v -> %v)\n", k, FormatSize(c.Sizes[k][0]), FormatSize(c.Sizes[k][1]))
+	}
+
+	fmt.Fprintln(&b, "Journey urls:")
+	if urls := c.Urls.String(); len(urls) > 0 {
+		fmt.Fprintln(&b, urls)
+	}
+
+	return b.String()
+}
+
+// getJourneyUrls Download and parse the journey urls stored at key
+func getJourneyUrls(ctx context.Context, store Storage, key string) (*Urls, error) {
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+	defer body.Close()
+
+	var urls Urls
+	if err := json.NewDecoder(body).Decode(&urls); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", key, err)
+	}
+
+	return &urls, nil
+}
+
+// urlEntries Key the journey url entries by their path inside the version so two versions can be compared
+func urlEntries(urls *Urls, prefix string) map[string]string {
+	entries := make(map[string]string)
+
+	trim := func(url string) string {
+		if i := strings.Index(url, prefix); i >= 0 {
+			return url[i+len(prefix):]
+		}
+		return url
+	}
+
+	for _, css := range urls.CSS {
+		entries["css "+trim(css.URL)] = ""
+	}
+	for _, js := range urls.JS {
+		entries["js "+trim(js.URL)] = js.RootID
+	}
+	for _, a := range urls.Assets {
+		entries[a.Type+" "+trim(a.URL)] = ""
+	}
+
+	return entries
+}
+
+// Compare Diff the objects and journey urls of two published versions of the journey
+func (j *Journey) Compare(ctx context.Context, from string, to string, store Storage) (*Comparison, error) {
+	if len(from) <= 0 || len(to) <= 0 {
+		return nil, fmt.Errorf("Two versions are needed to compare, got %q and %q", from, to)
+	}
+
+	c := Comparison{From: from, To: to, Sizes: make(map[string][2]int64)}
+
+	fromPrefix := j.GetVersionKey(from, "")
+	toPrefix := j.GetVersionKey(to, "")
+
+	fromObjects, err := listVersion(ctx, store, fromPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the objects of %v: %v", fromPrefix, err)
+	}
+	toObjects, err := listVersion(ctx, store, toPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the objects of %v: %v", toPrefix, err)
+	}
+
+	if len(fromObjects) == 0 || len(toObjects) == 0 {
+		return nil, fmt.Errorf("Both %v and %v must be published to compare them", fromPrefix, toPrefix)
+	}
+
+	fromSums, err := j.objectChecksums(ctx, store, fromPrefix, fromObjects)
+	if err != nil {
+		return nil, err
+	}
+	toSums, err := j.objectChecksums(ctx, store, toPrefix, toObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Objects = diffObjects(fromSums, toSums)
+	for k, o := range fromObjects {
+		sizes := c.Sizes[k]
+		sizes[0] = o.Size
+		c.Sizes[k] = sizes
+	}
+	for k, o := range toObjects {
+		sizes := c.Sizes[k]
+		sizes[1] = o.Size
+		c.Sizes[k] = sizes
+	}
+
+	fromUrls, err := getJourneyUrls(ctx, store, fromPrefix+j.UrlsFile())
+	if err != nil {
+		return nil, err
+	}
+	toUrls, err := getJourneyUrls(ctx, store, toPrefix+j.UrlsFile())
+	if err != nil {
+		return nil, err
+	}
+	c.Urls = diffObjects(urlEntries(fromUrls, fromPrefix), urlEntries(toUrls, toPrefix))
+
+	return &c, nil
+}
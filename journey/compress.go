@@ -0,0 +1,135 @@
+package journey
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Encodings assets can be pre-compressed with
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+// encodingSuffixes The extension of the variant uploaded next to the original for each encoding
+var encodingSuffixes = map[string]string{
+	EncodingGzip:   ".gz",
+	EncodingBrotli: ".br",
+}
+
+// compressibleTypes Extensions of text assets that are worth compressing
+var compressibleTypes = map[string]bool{
+	".js":   true,
+	".css":  true,
+	".svg":  true,
+	".json": true,
+	".html": true,
+}
+
+// validateCompress Validate the configured encodings can be produced, an asset compressed in place has a single encoding
+func validateCompress(encodings []string, variants bool) error {
+	seen := make(map[string]bool, len(encodings))
+	for _, e := range encodings {
+		switch e {
+		case EncodingGzip, EncodingBrotli:
+		default:
+			return fmt.Errorf("Compression with %v is not supported, use %v or %v", e, EncodingGzip, EncodingBrotli)
+		}
+		if seen[e] {
+			return fmt.Errorf("Compression with %v is listed more than once", e)
+		}
+		seen[e] = true
+	}
+
+	if len(encodings) > 1 && !variants {
+		return fmt.Errorf("Assets compressed in place are stored with one Content-Encoding, list one of %v or set compressVariants", encodings)
+	}
+
+	return nil
+}
+
+// compressBytes Compress the content with the encoding
+func compressBytes(content []byte, encoding string) ([]byte, error) {
+	if encoding == EncodingBrotli {
+		return brotliBytes(content)
+	}
+
+	return gzipBytes(content)
+}
+
+// gzipBytes Gzip the content at the best compression
+func gzipBytes(content []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// brotliBytes Brotli the content at the best compression
+func brotliBytes(content []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := brotli.NewWriterLevel(&b, brotli.BestCompression)
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// compressUploads Compress the text assets in the plan, either in place with a Content-Encoding
+// or as .gz and .br variants uploaded next to the originals
+func (j *Journey) compressUploads(uploads []*Upload) ([]*Upload, error) {
+	if len(j.Compress) <= 0 {
+		return uploads, nil
+	}
+
+	var variants []*Upload
+	for _, u := range uploads {
+		// keys in the cas layout have no extension
+		if len(u.Path) <= 0 || !compressibleTypes[filepath.Ext(u.Path)] {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(u.Path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to compress %v: %v", u.Path, err)
+		}
+
+		for _, encoding := range j.Compress {
+			compressed, err := compressBytes(content, encoding)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to compress %v with %v: %v", u.Path, encoding, err)
+			}
+
+			if j.CompressVariants {
+				v := j.newUpload(u.Key+encodingSuffixes[encoding], u.Path, compressed, u.ContentType)
+				v.ContentEncoding = encoding
+				variants = append(variants, v)
+				continue
+			}
+
+			u.Body = compressed
+			u.Size = int64(len(compressed))
+			u.ContentEncoding = encoding
+		}
+	}
+
+	return append(uploads, variants...), nil
+}
@@ -0,0 +1,117 @@
+package journey
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// decompress Read back content compressed with the encoding
+func decompress(t *testing.T, content []byte, encoding string) []byte {
+	var b []byte
+	var err error
+	switch encoding {
+	case EncodingGzip:
+		r, gerr := gzip.NewReader(bytes.NewReader(content))
+		if gerr != nil {
+			t.Fatalf("Unable to read the gzip: %v", gerr)
+		}
+		b, err = ioutil.ReadAll(r)
+	case EncodingBrotli:
+		b, err = ioutil.ReadAll(brotli.NewReader(bytes.NewReader(content)))
+	default:
+		return content
+	}
+	if err != nil {
+		t.Fatalf("Unable to decompress %v: %v", encoding, err)
+	}
+
+	return b
+}
+
+func TestValidateCompress(t *testing.T) {
+	tests := []struct {
+		name      string
+		encodings []string
+		variants  bool
+		wantErr   bool
+	}{
+		{"none", nil, false, false},
+		{"gzip", []string{EncodingGzip}, false, false},
+		{"brotli", []string{EncodingBrotli}, false, false},
+		{"both as variants", []string{EncodingGzip, EncodingBrotli}, true, false},
+		{"both in place", []string{EncodingGzip, EncodingBrotli}, false, true},
+		{"listed twice", []string{EncodingGzip, EncodingGzip}, true, true},
+		{"unknown", []string{"zstd"}, true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateCompress(test.encodings, test.variants)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateCompress(%v, %v) = %v, want an error %v", test.encodings, test.variants, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompressUploads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journey-compress")
+	if err != nil {
+		t.Fatalf("Unable to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("console.log('checkout');\n"), 100)
+	path := filepath.Join(dir, "app.js")
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Unable to write %v: %v", path, err)
+	}
+
+	tests := []struct {
+		name      string
+		encodings []string
+		variants  bool
+		// encoding of each uploaded key
+		want map[string]string
+	}{
+		{"gzip in place", []string{EncodingGzip}, false, map[string]string{"app.js": EncodingGzip}},
+		{"brotli in place", []string{EncodingBrotli}, false, map[string]string{"app.js": EncodingBrotli}},
+		{"variants", []string{EncodingGzip, EncodingBrotli}, true, map[string]string{"app.js": "", "app.js.gz": EncodingGzip, "app.js.br": EncodingBrotli}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			j := &Journey{Compress: test.encodings, CompressVariants: test.variants}
+			uploads, err := j.compressUploads([]*Upload{j.newUpload("app.js", path, nil, "application/javascript")})
+			if err != nil {
+				t.Fatalf("compressUploads() failed: %v", err)
+			}
+			if len(uploads) != len(test.want) {
+				t.Fatalf("compressUploads() = %v uploads, want %v", len(uploads), len(test.want))
+			}
+
+			for _, u := range uploads {
+				encoding, ok := test.want[u.Key]
+				if !ok {
+					t.Errorf("compressUploads() uploads %v", u.Key)
+					continue
+				}
+				if u.ContentEncoding != encoding {
+					t.Errorf("%v ContentEncoding = %q, want %q", u.Key, u.ContentEncoding, encoding)
+				}
+				if len(encoding) <= 0 {
+					continue
+				}
+				if got := decompress(t, u.Body, encoding); !bytes.Equal(got, content) {
+					t.Errorf("%v decompresses to %d bytes, want %d", u.Key, len(got), len(content))
+				}
+			}
+		})
+	}
+}
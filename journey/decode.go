@@ -0,0 +1,273 @@
+package journey
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Formats config can be written in, picked by the extension of the file
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// ConfigFormat The format of the config at path by its extension, anything unknown is json
+func ConfigFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// DecodeConfig Parse json config into v, errors point at the line and column in name and unknown fields are rejected
+func DecodeConfig(name string, content []byte, v interface{}) error {
+	return decodeConfig(name, content, v, describeJSONError)
+}
+
+// DecodeConfigFormat Parse config in the format into v like DecodeConfig and return the json it stands for,
+// yaml and toml are converted to json first so they are read, validated and published like journey.json
+func DecodeConfigFormat(name string, format string, content []byte, v interface{}) ([]byte, error) {
+	var raw interface{}
+	switch format {
+	case FormatJSON:
+		return content, DecodeConfig(name, content, v)
+	case FormatYAML:
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("Unable to parse %v: %v", name, err)
+		}
+		raw = yamlToJSON(raw)
+	case FormatTOML:
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(content), &table); err != nil {
+			return nil, fmt.Errorf("Unable to parse %v: %v", name, err)
+		}
+		raw = table
+	default:
+		return nil, fmt.Errorf("Config format %v is not supported, use %v, %v or %v", format, FormatJSON, FormatYAML, FormatTOML)
+	}
+
+	converted, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to convert %v into json: %v", name, err)
+	}
+
+	return converted, decodeConfig(name, converted, v, describeConvertedError)
+}
+
+// yamlToJSON Turn the maps yaml decodes into, which can have any key, into maps json can encode
+func yamlToJSON(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = yamlToJSON(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlToJSON(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// decodeConfig Parse json config into v, rejecting unknown fields, with describe explaining what went wrong
+func decodeConfig(name string, content []byte, v interface{}, describe func(string, []byte, error) error) error {
+	if err := json.Unmarshal(content, v); err != nil {
+		return describe(name, content, err)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return describe(name, content, err)
+	}
+
+	var unknown []string
+	unknownFields("", raw, reflect.TypeOf(v), &unknown)
+	if len(unknown) <= 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(unknown))
+	for _, u := range unknown {
+		lines = append(lines, "  "+u)
+	}
+
+	return fmt.Errorf("%v has fields that are not supported, see journey-cli schema for the ones that are:\n%v", name, strings.Join(lines, "\n"))
+}
+
+// describeJSONError Turn an error from encoding/json into one with the line and column it happened at
+func describeJSONError(name string, content []byte, err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		line, col := jsonPosition(content, e.Offset)
+		return fmt.Errorf("%v:%v:%v: %v", name, line, col, e)
+	case *json.UnmarshalTypeError:
+		line, col := jsonPosition(content, e.Offset)
+		field := e.Field
+		if len(field) <= 0 {
+			return fmt.Errorf("%v:%v:%v: expected a %v, found a %v", name, line, col, jsonTypeName(e.Type), e.Value)
+		}
+		return fmt.Errorf("%v:%v:%v: %v must be a %v, found a %v", name, line, col, strconv.Quote(field), jsonTypeName(e.Type), e.Value)
+	default:
+		return fmt.Errorf("Unable to parse %v: %v", name, err)
+	}
+}
+
+// describeConvertedError Turn an error from encoding/json into one naming the field, the lines of json converted
+// from yaml or toml are not the lines of the file
+func describeConvertedError(name string, content []byte, err error) error {
+	if e, ok := err.(*json.UnmarshalTypeError); ok && len(e.Field) > 0 {
+		return fmt.Errorf("%v: %v must be a %v, found a %v", name, strconv.Quote(e.Field), jsonTypeName(e.Type), e.Value)
+	}
+
+	return fmt.Errorf("Unable to parse %v: %v", name, err)
+}
+
+// jsonPosition The line and column of the byte offset, both counting from 1
+func jsonPosition(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, col
+}
+
+// jsonTypeName The name of the json type a go type is read from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	default:
+		return "object"
+	}
+}
+
+// jsonFields The fields of a struct keyed by the name they have in json
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) <= 0 {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+
+	return fields
+}
+
+// unknownFields Collect the keys in the json that do not match a field of the type, with the field that was probably meant
+func unknownFields(path string, raw interface{}, t reflect.Type, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for k, item := range v {
+				unknownFields(path+"."+k, item, t.Elem(), unknown)
+			}
+		case reflect.Struct:
+			fields := jsonFields(t)
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				if f, ok := matchField(k, fields); ok {
+					unknownFields(path+"."+k, v[k], f.Type, unknown)
+					continue
+				}
+				// lets editors find the schema
+				if len(path) <= 0 && k == "$schema" {
+					continue
+				}
+
+				msg := strconv.Quote(strings.TrimPrefix(path+"."+k, "."))
+				if s := suggestField(k, fields); len(s) > 0 {
+					msg += fmt.Sprintf(", did you mean %v?", strconv.Quote(s))
+				}
+				*unknown = append(*unknown, msg)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range v {
+				unknownFields(fmt.Sprintf("%v[%v]", path, i), item, t.Elem(), unknown)
+			}
+		}
+	}
+}
+
+// matchField Find the field a key is read into, encoding/json ignores case when there is no exact match
+func matchField(key string, fields map[string]reflect.StructField) (reflect.StructField, bool) {
+	if f, ok := fields[key]; ok {
+		return f, true
+	}
+	for name, f := range fields {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// suggestField The json name of the field a key probably meant, matched ignoring case against the json and go names
+func suggestField(key string, fields map[string]reflect.StructField) string {
+	k := strings.ToLower(key)
+	var names []string
+	for name, f := range fields {
+		if strings.ToLower(f.Name) == k {
+			return name
+		}
+		if strings.HasPrefix(k, strings.ToLower(name)) || strings.HasPrefix(strings.ToLower(name), k) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,118 @@
+package journey
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"journey.json", FormatJSON},
+		{"config/journey.yaml", FormatYAML},
+		{"journey.YML", FormatYAML},
+		{"journey.toml", FormatTOML},
+		{"journey", FormatJSON},
+	}
+
+	for _, test := range tests {
+		if got := ConfigFormat(test.path); got != test.want {
+			t.Errorf("ConfigFormat(%v) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestDecodeConfigFormat(t *testing.T) {
+	want := Journey{
+		Name:         "checkout",
+		Version:      "1.2.0",
+		Bucket:       "acme-frontends",
+		Concurrency:  4,
+		CacheControl: map[string]string{"*": "max-age=60"},
+		Environments: map[string]Environment{"prod": {Bucket: "acme-prod", Region: "eu-west-1"}},
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		content string
+	}{
+		{"json", FormatJSON, `{"name": "checkout", "version": "1.2.0", "bucket": "acme-frontends", "Concurrency": 4, "cacheControl": {"*": "max-age=60"}, "environments": {"prod": {"bucket": "acme-prod", "region": "eu-west-1"}}}`},
+		{"yaml", FormatYAML, `
+name: checkout
+version: 1.2.0
+bucket: acme-frontends
+Concurrency: 4
+cacheControl:
+  "*": max-age=60
+environments:
+  prod:
+    bucket: acme-prod
+    region: eu-west-1
+`},
+		{"toml", FormatTOML, `
+name = "checkout"
+version = "1.2.0"
+bucket = "acme-frontends"
+Concurrency = 4
+
+[cacheControl]
+"*" = "max-age=60"
+
+[environments.prod]
+bucket = "acme-prod"
+region = "eu-west-1"
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got Journey
+			content, err := DecodeConfigFormat("journey."+test.format, test.format, []byte(test.content), &got)
+			if err != nil {
+				t.Fatalf("DecodeConfigFormat() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DecodeConfigFormat() = %+v, want %+v", got, want)
+			}
+			if !json.Valid(content) {
+				t.Errorf("DecodeConfigFormat() returned content that is not json:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigFormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		want    string
+	}{
+		{"json syntax", FormatJSON, "{\n  \"name\": checkout\n}", "journey.json:2:12:"},
+		{"json unknown field", FormatJSON, `{"bukcet": "acme"}`, `"bukcet"`},
+		{"yaml syntax", FormatYAML, "name: [checkout", "Unable to parse journey.yaml"},
+		{"yaml unknown field", FormatYAML, "cachecontrl: {}", `"cachecontrl"`},
+		{"yaml wrong type", FormatYAML, "concurrency: many", `"concurrency" must be a whole number, found a string`},
+		{"toml syntax", FormatTOML, "name = ", "Unable to parse journey.toml"},
+		{"toml wrong type", FormatTOML, "exclude = \"*.txt\"", `"exclude" must be a list, found a string`},
+		{"unknown format", "ini", "name=checkout", "Config format ini is not supported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var j Journey
+			_, err := DecodeConfigFormat("journey."+test.format, test.format, []byte(test.content), &j)
+			if err == nil {
+				t.Fatalf("DecodeConfigFormat() did not fail")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("DecodeConfigFormat() = %v, want it to mention %v", err, test.want)
+			}
+		})
+	}
+}
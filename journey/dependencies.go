@@ -0,0 +1,100 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+)
+
+// Dependency Another journey in the bucket the loader loads first, like a design system or vendor bundle,
+// and the range of its versions this journey works with, or latest
+type Dependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// validateDependencies Validate every dependency names another journey once and has a version range
+func validateDependencies(name string, dependencies []Dependency) error {
+	seen := make(map[string]bool)
+	for _, d := range dependencies {
+		switch {
+		case len(d.Name) <= 0:
+			return fmt.Errorf("Dependencies need a name")
+		case d.Name == name:
+			return fmt.Errorf("Journey %v can not depend on itself", name)
+		case seen[d.Name]:
+			return fmt.Errorf("Dependency %v is listed more than once", d.Name)
+		case len(d.Version) <= 0:
+			return fmt.Errorf("Dependency %v needs a version range, eg: ^1.0.0 or latest", d.Name)
+		}
+		seen[d.Name] = true
+
+		if d.Version == Latest {
+			continue
+		}
+		if _, err := ParseVersionRange(d.Version); err != nil {
+			return fmt.Errorf("Dependency %v: %v", d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// dependencyJourney The journey of a dependency, in the same bucket under the same prefix
+func (j *Journey) dependencyJourney(name string) *Journey {
+	return &Journey{Name: name, Prefix: j.Prefix}
+}
+
+// resolveDependency Find the newest version of the dependency in its range that is published and not archived
+func (j *Journey) resolveDependency(ctx context.Context, store Storage, d Dependency) (string, error) {
+	versions, err := j.dependencyJourney(d.Name).ListVersions(ctx, store)
+	if err != nil {
+		return "", err
+	}
+
+	var r *VersionRange
+	if d.Version != Latest {
+		// the range was validated when the journey was loaded
+		r, _ = ParseVersionRange(d.Version)
+	}
+
+	var newest *Semver
+	var resolved string
+	for _, v := range versions {
+		if v.Archived {
+			continue
+		}
+		if r == nil {
+			if v.Latest {
+				return v.Version, nil
+			}
+			continue
+		}
+
+		s, err := ParseSemver(v.Version)
+		if err != nil || !r.Match(s) {
+			continue
+		}
+		if newest == nil || s.Compare(newest) > 0 {
+			newest, resolved = s, v.Version
+		}
+	}
+
+	if len(resolved) <= 0 {
+		return "", fmt.Errorf("Dependency %v %v is not published to %v, publish it first", d.Name, d.Version, j.Bucket)
+	}
+
+	return resolved, nil
+}
+
+// CheckDependencies Check a version of every dependency is published to the bucket, so the loader can find them
+func (j *Journey) CheckDependencies(ctx context.Context, store Storage) error {
+	for _, d := range j.Dependencies {
+		version, err := j.resolveDependency(ctx, store, d)
+		if err != nil {
+			return err
+		}
+		Log.Infof("Dependency %v %v resolves to %v/%v", d.Name, d.Version, d.Name, version)
+	}
+
+	return nil
+}
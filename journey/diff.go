@@ -0,0 +1,216 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff The keys that differ between two sets of objects, relative to their version
+type Diff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty Check if there are no differences
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String Print the differences one key per line, prefixed with +, - or ~
+func (d *Diff) String() string {
+	var lines []string
+	for _, k := range d.Added {
+		lines = append(lines, "+ "+k)
+	}
+	for _, k := range d.Removed {
+		lines = append(lines, "- "+k)
+	}
+	for _, k := range d.Changed {
+		lines = append(lines, "~ "+k)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// diffObjects Compare two maps of key to ETag, from is the old side and to is the new side
+func diffObjects(from map[string]string, to map[string]string) *Diff {
+	var d Diff
+
+	for k, etag := range to {
+		old, ok := from[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, k)
+		case old != etag:
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+
+	return &d
+}
+
+// objectETags Get the ETag of each object
+func objectETags(objects map[string]*Object) map[string]string {
+	etags := make(map[string]string, len(objects))
+	for k, o := range objects {
+		etags[k] = o.ETag
+	}
+
+	return etags
+}
+
+// etagsAreMD5 Check if stored ETags are computed from the content, objects encrypted with KMS get an ETag that is not
+// the md5 of their content so they can only be compared by the sha256 kept in their metadata
+func (j *Journey) etagsAreMD5() bool {
+	return j.Encryption != EncryptionKMS
+}
+
+// compareWithChecksums Check the objects can be compared with the local build, returns an error when they can not
+func (j *Journey) compareWithChecksums() (bool, error) {
+	if j.etagsAreMD5() {
+		return false, nil
+	}
+	if !j.Checksums {
+		return false, fmt.Errorf("Objects encrypted with %v do not have the md5 of their content as ETag, enable checksums to compare them by their sha256", EncryptionKMS)
+	}
+
+	return true, nil
+}
+
+// objectContent What tells the content of the object apart, its ETag or the sha256 in its metadata when ETags are not md5s
+func (j *Journey) objectContent(o *Object) string {
+	if sum := metadataValue(o.Metadata, ChecksumMetadata); !j.etagsAreMD5() && len(sum) > 0 {
+		return sum
+	}
+
+	return o.ETag
+}
+
+// localChecksums Compute the ETags of everything in the plan except metadata, relative to the version,
+// or their sha256 when the stored ETags are not md5s
+func (j *Journey) localChecksums(plan *Plan) (map[string]string, error) {
+	bySum, err := j.compareWithChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := j.GetAssetKey("")
+	sums := make(map[string]string, len(plan.Uploads))
+
+	for _, u := range plan.Uploads {
+		key := strings.TrimPrefix(u.Key, prefix)
+		// shared chunks are named by their content, a change shows up in journey-urls.json instead
+		if key == MetadataFile || j.isSharedKey(u.Key) {
+			continue
+		}
+
+		etag, sum, err := u.checksums()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to compute checksum of %v: %v", u.Path, err)
+		}
+		if !bySum {
+			sum = etag
+		}
+		sums[key] = sum
+	}
+
+	return sums, nil
+}
+
+// objectChecksums Get the ETag of each object, or the sha256 from its metadata when the stored ETags are not md5s.
+// Objects published without checksums have no sha256 and always differ
+func (j *Journey) objectChecksums(ctx context.Context, store Storage, prefix string, objects map[string]*Object) (map[string]string, error) {
+	bySum, err := j.compareWithChecksums()
+	if err != nil || !bySum {
+		return objectETags(objects), err
+	}
+
+	sums := make(map[string]string, len(objects))
+	for k := range objects {
+		o, err := store.Head(ctx, prefix+k)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the checksum of %v: %v", prefix+k, err)
+		}
+		sums[k] = metadataValue(o.Metadata, ChecksumMetadata)
+	}
+
+	return sums, nil
+}
+
+// VerifyPublished Compare the published version against the local build, returns an error listing the differences
+func (j *Journey) VerifyPublished(ctx context.Context, plan *Plan, store Storage) error {
+	local, err := j.localChecksums(plan)
+	if err != nil {
+		return err
+	}
+
+	objects, err := listVersion(ctx, store, j.GetAssetKey(""))
+	if err != nil {
+		return fmt.Errorf("Unable to list the objects of %v/%v: %v", j.Name, j.Version, err)
+	}
+
+	// metadata is annotated after publishing and is not part of the build
+	delete(objects, MetadataFile)
+
+	published, err := j.objectChecksums(ctx, store, j.GetAssetKey(""), objects)
+	if err != nil {
+		return err
+	}
+
+	d := diffObjects(published, local)
+	if !d.Empty() {
+		return fmt.Errorf("Version %v/%v is already published and does not match the local build:\n%v", j.Name, j.Version, d)
+	}
+
+	Log.Infof("Version %v/%v is already published and matches the local build", j.Name, j.Version)
+	return nil
+}
+
+// DiffAgainst Compare the local build with a published version, as if the build was published as that version.
+// journey.json and metadata.json always differ between versions and are left out
+func (j *Journey) DiffAgainst(ctx context.Context, assets map[string]string, version string, store Storage) (*Diff, error) {
+	against := *j
+	against.Version = version
+
+	plan, err := against.PlanPublish(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := against.localChecksums(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := listVersion(ctx, store, against.GetAssetKey(""))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the objects of %v/%v: %v", j.Name, version, err)
+	}
+	if _, ok := objects[JourneyFile]; !ok {
+		return nil, fmt.Errorf("Version %v/%v is not published", j.Name, version)
+	}
+
+	delete(objects, JourneyFile)
+	delete(objects, MetadataFile)
+	delete(local, JourneyFile)
+
+	published, err := against.objectChecksums(ctx, store, against.GetAssetKey(""), objects)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffObjects(published, local), nil
+}
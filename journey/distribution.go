@@ -0,0 +1,150 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+)
+
+// findDistribution Find the distribution set by distributionID, or the one serving the host of the cdn, nil when there is none
+func (j *Journey) findDistribution(ctx context.Context, sess *session.Session, host string) (*cloudfront.DistributionSummary, error) {
+	var found *cloudfront.DistributionSummary
+	err := cloudfront.New(sess).ListDistributionsPagesWithContext(ctx, &cloudfront.ListDistributionsInput{}, func(out *cloudfront.ListDistributionsOutput, last bool) bool {
+		for _, d := range out.DistributionList.Items {
+			if servesHost(d, host) || (len(j.DistributionID) > 0 && aws.StringValue(d.Id) == j.DistributionID) {
+				found = d
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the CloudFront distributions: %v", err)
+	}
+
+	return found, nil
+}
+
+// servesHost Check if the host is the domain of the distribution or one of its aliases
+func servesHost(d *cloudfront.DistributionSummary, host string) bool {
+	if strings.EqualFold(aws.StringValue(d.DomainName), host) {
+		return true
+	}
+	if d.Aliases != nil {
+		for _, alias := range d.Aliases.Items {
+			if strings.EqualFold(aws.StringValue(alias), host) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isBucketOrigin Check if the origin is the bucket, by its REST or website endpoint in any region
+func (j *Journey) isBucketOrigin(o *cloudfront.Origin) bool {
+	return strings.HasPrefix(strings.ToLower(aws.StringValue(o.DomainName)), strings.ToLower(j.Bucket)+".s3")
+}
+
+// matchPathPattern Check if the path matches a CloudFront path pattern, where * matches anything, slashes included, and ? one character
+func matchPathPattern(pattern string, path string) bool {
+	quoted := regexp.QuoteMeta("/" + strings.TrimPrefix(pattern, "/"))
+	quoted = strings.Replace(quoted, `\*`, ".*", -1)
+	quoted = strings.Replace(quoted, `\?`, ".", -1)
+
+	return regexp.MustCompile("^" + quoted + "$").MatchString(path)
+}
+
+// behaviorFor The origin and trusted signers of the cache behavior CloudFront uses for the path, the first pattern
+// that matches or the default
+func behaviorFor(d *cloudfront.DistributionSummary, path string) (pattern string, origin string, signers *cloudfront.TrustedSigners) {
+	if d.CacheBehaviors != nil {
+		for _, b := range d.CacheBehaviors.Items {
+			if matchPathPattern(aws.StringValue(b.PathPattern), path) {
+				return aws.StringValue(b.PathPattern), aws.StringValue(b.TargetOriginId), b.TrustedSigners
+			}
+		}
+	}
+
+	return "default (*)", aws.StringValue(d.DefaultCacheBehavior.TargetOriginId), d.DefaultCacheBehavior.TrustedSigners
+}
+
+// DistributionWarnings Check the CloudFront distribution of the cdn has the bucket as an origin and that the behavior
+// serving the journey sends it there, returning what looks wrong. Publishing to a bucket the distribution does not
+// serve otherwise only shows up as 403s and 404s on the cdn
+func (j *Journey) DistributionWarnings(ctx context.Context, sess *session.Session) ([]string, error) {
+	cdn, err := url.Parse(j.CDNDomain)
+	if err != nil || len(cdn.Hostname()) <= 0 {
+		return nil, fmt.Errorf("Cdn %v does not have a host", j.CDNDomain)
+	}
+
+	d, err := j.findDistribution(ctx, sess, cdn.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		if len(j.DistributionID) > 0 {
+			return []string{fmt.Sprintf("CloudFront distribution %v can not be found", j.DistributionID)}, nil
+		}
+		return []string{fmt.Sprintf("No CloudFront distribution has %v as its domain or an alias, set distributionID if the cdn is a CNAME of one", cdn.Hostname())}, nil
+	}
+
+	id := aws.StringValue(d.Id)
+	var warnings []string
+	if !aws.BoolValue(d.Enabled) {
+		warnings = append(warnings, fmt.Sprintf("CloudFront distribution %v is disabled", id))
+	}
+
+	var origin *cloudfront.Origin
+	var others []string
+	for _, o := range d.Origins.Items {
+		if j.isBucketOrigin(o) {
+			origin = o
+			break
+		}
+		others = append(others, aws.StringValue(o.DomainName))
+	}
+	if origin == nil {
+		return append(warnings, fmt.Sprintf("CloudFront distribution %v does not have the bucket %v as an origin, it serves %v", id, j.Bucket, strings.Join(others, ", "))), nil
+	}
+	if p := aws.StringValue(origin.OriginPath); len(p) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Origin %v of CloudFront distribution %v has the origin path %v, so %v is looked up in the bucket under %v", aws.StringValue(origin.Id), id, p, j.GetJourneyKey(""), strings.TrimPrefix(p, "/")))
+	}
+
+	path := strings.TrimSuffix(cdn.Path, "/") + "/" + j.GetAssetKey(j.UrlsFile())
+	pattern, target, signers := behaviorFor(d, path)
+	if target != aws.StringValue(origin.Id) {
+		warnings = append(warnings, fmt.Sprintf("CloudFront distribution %v serves %v from origin %v with the behavior %v, not the bucket origin %v", id, path, target, pattern, aws.StringValue(origin.Id)))
+	}
+	if signers != nil && aws.BoolValue(signers.Enabled) && j.SignedUrls.Type != SignCloudFront {
+		warnings = append(warnings, fmt.Sprintf("The behavior %v of CloudFront distribution %v only serves signed urls, set signedUrls type to %v", pattern, id, SignCloudFront))
+	}
+
+	return warnings, nil
+}
+
+// WarnDistribution Warn about anything DistributionWarnings finds before publishing, when checkDistribution is set.
+// It only warns, a publish is not stopped by a distribution that can not be read
+func (j *Journey) WarnDistribution(ctx context.Context, sess *session.Session) {
+	if !j.CheckDistribution {
+		return
+	}
+
+	warnings, err := j.DistributionWarnings(ctx, sess)
+	if err != nil {
+		Log.Warnf("Unable to check the CloudFront distribution: %v", err)
+		return
+	}
+	for _, w := range warnings {
+		Log.Warnf("%v", w)
+	}
+	if len(warnings) <= 0 {
+		Log.Debugf("CloudFront distribution serves %v from %v", j.GetJourneyKey(""), j.Bucket)
+	}
+}
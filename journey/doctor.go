@@ -0,0 +1,148 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Finding The result of one doctor check, with what to do about it when it failed
+type Finding struct {
+	Check  string
+	OK     bool
+	Detail string
+	Fix    string
+}
+
+// doctorTimeout How long each doctor check may take
+const doctorTimeout = 30 * time.Second
+
+// checkIdentity Find who the AWS credentials belong to
+func checkIdentity(ctx context.Context, sess *session.Session) Finding {
+	f := Finding{Check: "identity"}
+	out, err := sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		f.Detail = fmt.Sprintf("Unable to get the AWS identity: %v", err)
+		f.Fix = "Set credentials with -profile, AWS_PROFILE or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, and check -role-arn can be assumed"
+		return f
+	}
+
+	f.OK = true
+	f.Detail = fmt.Sprintf("%v (account %v)", *out.Arn, *out.Account)
+	return f
+}
+
+// checkBucketRead Check the bucket exists and the journey can be listed
+func (j *Journey) checkBucketRead(ctx context.Context, store Storage) Finding {
+	f := Finding{Check: "bucket"}
+	if _, err := store.ListPrefixes(ctx, j.GetJourneyKey("")); err != nil {
+		f.Detail = fmt.Sprintf("Unable to list %v in %v: %v", j.GetJourneyKey(""), j.Bucket, err)
+		f.Fix = fmt.Sprintf("Check the bucket %v exists in %v and the identity is allowed s3:ListBucket on it", j.Bucket, j.Region)
+		return f
+	}
+
+	f.OK = true
+	f.Detail = fmt.Sprintf("%v in %v can be listed", j.Bucket, j.Region)
+	return f
+}
+
+// checkBucketWrite Upload and delete a temporary object where the journey is published
+func (j *Journey) checkBucketWrite(ctx context.Context, store Storage) Finding {
+	f := Finding{Check: "write"}
+	key := j.GetJourneyKey(".doctor-" + strconv.FormatInt(time.Now().UnixNano(), 10))
+	resource := fmt.Sprintf("arn:aws:s3:::%v/%v*", j.Bucket, j.GetJourneyKey(""))
+
+	if err := store.Upload(ctx, key, bytes.NewReader([]byte("journey-cli doctor")), j.uploadOptions(key, "text/plain")); err != nil {
+		f.Detail = fmt.Sprintf("Unable to upload %v: %v", key, err)
+		f.Fix = fmt.Sprintf("Allow the identity s3:PutObject on %v, and the KMS key when encryption is aws:kms", resource)
+		return f
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		f.Detail = fmt.Sprintf("Uploaded %v but unable to delete it: %v", key, err)
+		f.Fix = fmt.Sprintf("Allow the identity s3:DeleteObject on %v, failed publishes are cleaned up with it, and delete %v", resource, key)
+		return f
+	}
+
+	f.OK = true
+	f.Detail = fmt.Sprintf("Uploaded and deleted %v", key)
+	return f
+}
+
+// checkCDN Check the host of the cdn resolves
+func (j *Journey) checkCDN() Finding {
+	f := Finding{Check: "cdn"}
+	u, err := url.Parse(j.CDNDomain)
+	if err != nil || len(u.Hostname()) <= 0 {
+		f.Detail = fmt.Sprintf("Cdn %v does not have a host", j.CDNDomain)
+		f.Fix = "Set cdn in journey.json or -cdn to the domain the bucket is served from"
+		return f
+	}
+
+	addrs, err := net.LookupHost(u.Hostname())
+	if err != nil {
+		f.Detail = fmt.Sprintf("Unable to resolve %v: %v", u.Hostname(), err)
+		f.Fix = "Check the cdn in journey.json, for CloudFront it is the domain of the distribution or a CNAME pointing at it"
+		return f
+	}
+
+	f.OK = true
+	f.Detail = fmt.Sprintf("%v resolves to %v", u.Hostname(), addrs[0])
+	return f
+}
+
+// checkDistribution Check the CloudFront distribution serves the journey from the bucket
+func (j *Journey) checkDistribution(ctx context.Context, sess *session.Session) Finding {
+	f := Finding{Check: "distribution"}
+	warnings, err := j.DistributionWarnings(ctx, sess)
+	if err != nil {
+		f.Detail = err.Error()
+		f.Fix = "Allow the identity cloudfront:ListDistributions"
+		return f
+	}
+	if len(warnings) > 0 {
+		f.Detail = strings.Join(warnings, "; ")
+		f.Fix = fmt.Sprintf("Add the bucket %v as an origin of the distribution and point the behavior serving /%v at it", j.Bucket, j.GetJourneyKey("*"))
+		return f
+	}
+
+	f.OK = true
+	f.Detail = fmt.Sprintf("Serves %v from %v", j.GetJourneyKey(""), j.Bucket)
+	return f
+}
+
+// Doctor Check the AWS identity, that the bucket can be listed and written to, that the cdn resolves and its distribution
+// serves the bucket, so a misconfiguration shows up before a publish fails half way. The bucket and cdn are only checked when they are set
+func (j *Journey) Doctor(ctx context.Context, sess *session.Session, store Storage) []Finding {
+	checks := []func(context.Context) Finding{
+		func(ctx context.Context) Finding { return checkIdentity(ctx, sess) },
+	}
+	if store != nil {
+		checks = append(checks,
+			func(ctx context.Context) Finding { return j.checkBucketRead(ctx, store) },
+			func(ctx context.Context) Finding { return j.checkBucketWrite(ctx, store) },
+		)
+	}
+	if len(j.CDNDomain) > 0 {
+		checks = append(checks, func(ctx context.Context) Finding { return j.checkCDN() })
+	}
+	if len(j.CDNDomain) > 0 && (j.CheckDistribution || len(j.DistributionID) > 0) {
+		checks = append(checks, func(ctx context.Context) Finding { return j.checkDistribution(ctx, sess) })
+	}
+
+	var findings []Finding
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+		findings = append(findings, check(checkCtx))
+		cancel()
+	}
+
+	return findings
+}
@@ -0,0 +1,79 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Download Fetch every object of the published version into the directory, recreating the layout of the version
+func (j *Journey) Download(ctx context.Context, store Storage, version string, dir string) error {
+	prefix := j.GetVersionKey(version, "")
+
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("Unable to list the objects of %v/%v: %v", j.Name, version, err)
+	}
+
+	published := false
+	for _, o := range objects {
+		if o.Key == prefix+JourneyFile {
+			published = true
+		}
+	}
+	if !published {
+		return fmt.Errorf("Version %v/%v is not published", j.Name, version)
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, o := range objects {
+		path := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(o.Key, prefix)))
+
+		// keys are not trusted to stay inside the directory
+		if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return fmt.Errorf("Key %v would be written outside of %v", o.Key, dir)
+		}
+
+		if err := download(ctx, store, o.Key, path); err != nil {
+			return err
+		}
+		Log.Debugf("Downloaded %v to %v", o.Key, path)
+		total += o.Size
+	}
+
+	Log.Infof("Downloaded %v files, %v of %v/%v to %v", len(objects), FormatSize(total), j.Name, version, dir)
+	return nil
+}
+
+// download Write the object at key to the file at path, creating the directories it is in
+func download(ctx context.Context, store Storage, key string, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Unable to create the directory for %v: %v", path, err)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+	defer body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Unable to create %v: %v", path, err)
+	}
+
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return fmt.Errorf("Unable to download %v: %v", key, err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,157 @@
+package journey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Preload A hint for the host application to start fetching an entrypoint early, as is the value of the link as attribute
+type Preload struct {
+	URL string `json:"url"`
+	As  string `json:"as"`
+}
+
+// webpackEntrypoints The entrypoints written by webpack-assets-manifest with entrypoints turned on
+type webpackEntrypoints struct {
+	Entrypoints map[string]struct {
+		Assets struct {
+			JS  []string `json:"js"`
+			CSS []string `json:"css"`
+		} `json:"assets"`
+	} `json:"entrypoints"`
+}
+
+// LoadEntrypoints Read the entrypoints, in load order, from the asset manifest at path when the format records them
+func LoadEntrypoints(path string, format string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEntrypoints(content, format)
+}
+
+// ParseEntrypoints Get the entrypoints, in load order, of an asset manifest, css before js, nil when the format does not record them
+func ParseEntrypoints(content []byte, format string) ([]string, error) {
+	var entrypoints []string
+
+	switch format {
+	case ManifestWebpack:
+		var m webpackEntrypoints
+		if err := json.Unmarshal(content, &m); err != nil {
+			return nil, nil
+		}
+
+		// the order of the entrypoints themselves is lost in a json object, so keep it stable by name
+		names := make([]string, 0, len(m.Entrypoints))
+		for name := range m.Entrypoints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			assets := m.Entrypoints[name].Assets
+			entrypoints = append(entrypoints, assets.CSS...)
+			entrypoints = append(entrypoints, assets.JS...)
+		}
+	case ManifestCRA:
+		var m craManifest
+		if err := json.Unmarshal(content, &m); err != nil {
+			return nil, fmt.Errorf("Unable to parse the create-react-app asset manifest: %v", err)
+		}
+
+		// create-react-app already lists css before js in load order
+		entrypoints = m.Entrypoints
+	case ManifestVite:
+		var m map[string]viteChunk
+		if err := json.Unmarshal(content, &m); err != nil {
+			return nil, fmt.Errorf("Unable to parse the vite manifest: %v", err)
+		}
+
+		keys := make([]string, 0, len(m))
+		for k, chunk := range m {
+			if chunk.IsEntry {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			entrypoints = append(entrypoints, m[k].CSS...)
+			entrypoints = append(entrypoints, m[k].File)
+		}
+	}
+
+	for i, e := range entrypoints {
+		path, err := normalizeAssetPath(e)
+		if err != nil {
+			return nil, err
+		}
+		entrypoints[i] = path
+	}
+
+	return entrypoints, nil
+}
+
+// LoadAssets Load the asset manifest of the journey, reading the entrypoints from it when journey.json does not declare them
+func (j *Journey) LoadAssets() (map[string]string, error) {
+	content, err := j.readManifestFile()
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := ParseManifest(content, j.ManifestFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(j.Entrypoints) <= 0 {
+		if j.Entrypoints, err = ParseEntrypoints(content, j.ManifestFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	return assets, nil
+}
+
+// entrypointOrder Get the load position of each entrypoint keyed by its path in the build,
+// entrypoints can be given as the path or the name in the asset manifest
+func (j *Journey) entrypointOrder(assets map[string]string) (map[string]int, error) {
+	paths := make(map[string]bool, len(assets))
+	for _, v := range assets {
+		paths[v] = true
+	}
+
+	order := make(map[string]int, len(j.Entrypoints))
+	for i, e := range j.Entrypoints {
+		if v, ok := assets[e]; ok {
+			e = v
+		} else if path, err := normalizeAssetPath(e); err == nil {
+			e = path
+		}
+		if !paths[e] {
+			return nil, fmt.Errorf("Entrypoint %v is not in the asset manifest", e)
+		}
+		if _, ok := order[e]; !ok {
+			order[e] = i
+		}
+	}
+
+	return order, nil
+}
+
+// entrypointLess Sort entrypoints first in load order, then everything else by url
+func entrypointLess(order map[string]int, a string, b string) bool {
+	ia, aok := order[a]
+	ib, bok := order[b]
+	switch {
+	case aok && bok:
+		return ia < ib
+	case aok != bok:
+		return aok
+	default:
+		return a < b
+	}
+}
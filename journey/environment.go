@@ -0,0 +1,201 @@
+package journey
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultRegion The AWS region used when neither the environment nor the flags set one
+const DefaultRegion = "us-east-1"
+
+// Environment Where a deployment environment like dev, staging or prod publishes to
+type Environment struct {
+	Bucket    string `json:"bucket"`
+	CDNDomain string `json:"cdn"`
+	Region    string `json:"region"`
+	Endpoint  string `json:"endpoint" journey:"private"`
+	PathStyle bool   `json:"pathStyle"`
+	User      string `json:"user" journey:"private"`
+	Password  string `json:"password" journey:"private"`
+
+	// Buckets in other regions the version is copied to after publishing
+	Replicas []Environment `json:"replicas"`
+
+	// Ask before moving latest or deleting versions, like for production
+	Protected bool `json:"protected"`
+}
+
+// UseEnvironment Apply the bucket, cdn and region of the named environment to the journey
+func (j *Journey) UseEnvironment(name string) error {
+	env, ok := j.Environments[name]
+	if !ok {
+		names := make([]string, 0, len(j.Environments))
+		for n := range j.Environments {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("Environment %v is not in journey.json, expected one of: %v", name, strings.Join(names, ", "))
+	}
+
+	j.Environment = name
+	if len(env.Bucket) > 0 {
+		j.Bucket = env.Bucket
+	}
+	if len(env.CDNDomain) > 0 {
+		j.CDNDomain = env.CDNDomain
+	}
+	if len(env.Region) > 0 {
+		j.Region = env.Region
+	}
+	if len(env.Endpoint) > 0 {
+		j.Endpoint = env.Endpoint
+		j.PathStyle = env.PathStyle
+	}
+	if len(env.User) > 0 {
+		j.User = env.User
+		j.Password = env.Password
+	}
+	if len(env.Replicas) > 0 {
+		j.Replicas = env.Replicas
+	}
+	if env.Protected {
+		j.Protected = true
+	}
+
+	return nil
+}
+
+// validateEndpoint Validate the endpoint does not carry a password, which would end up in logs and errors
+func validateEndpoint(name string, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.User == nil {
+		return nil
+	}
+	if _, ok := u.User.Password(); ok {
+		return fmt.Errorf("The endpoint of %v has a password in it, set user and password instead, eg: \"password\": \"${DAV_PASSWORD}\"", name)
+	}
+
+	return nil
+}
+
+// validateEndpoints Validate none of the endpoints of the journey, its environments and replicas carry a password
+func (j *Journey) validateEndpoints() error {
+	if err := validateEndpoint(j.configName(), j.Endpoint); err != nil {
+		return err
+	}
+
+	var check func(name string, env Environment) error
+	check = func(name string, env Environment) error {
+		if err := validateEndpoint(name, env.Endpoint); err != nil {
+			return err
+		}
+		for i, r := range env.Replicas {
+			if err := check(fmt.Sprintf("%v replica %v", name, i+1), r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for name, env := range j.Environments {
+		if err := check("environment "+name, env); err != nil {
+			return err
+		}
+	}
+
+	return check(j.configName(), Environment{Replicas: j.Replicas})
+}
+
+// envVar Matches ${NAME} references to environment variables in journey.json values
+var envVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv Replace every ${NAME} in the value with the environment variable, which must be set
+func expandEnv(value string) (string, error) {
+	var missing []string
+
+	expanded := envVar.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envVar.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("Environment variable %v used in %v is not set", strings.Join(missing, ", "), value)
+	}
+
+	return expanded, nil
+}
+
+// expandEnv Expand ${NAME} environment variables in the environment and its replicas
+func (e *Environment) expandEnv() error {
+	for _, v := range []*string{&e.Bucket, &e.CDNDomain, &e.Region, &e.Endpoint, &e.User, &e.Password} {
+		expanded, err := expandEnv(*v)
+		if err != nil {
+			return err
+		}
+		*v = expanded
+	}
+
+	for i := range e.Replicas {
+		if err := e.Replicas[i].expandEnv(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpandEnv Expand ${NAME} environment variables in the journey.json values and environments
+func (j *Journey) ExpandEnv() error {
+	values := []*string{
+		&j.Name, &j.Version, &j.RootID, &j.Build, &j.Manifest, &j.Bucket, &j.CDNDomain,
+		&j.Region, &j.Endpoint, &j.User, &j.Password, &j.Prefix, &j.DistributionID, &j.KMSKeyID, &j.Registry.URL, &j.Registry.Token, &j.HTMLTemplate,
+		&j.SignedUrls.KeyPairID, &j.SignedUrls.PrivateKey, &j.Purge.ServiceID, &j.Purge.ZoneID, &j.Purge.Token,
+		&j.Metrics.URL, &j.Paths.UrlsFile, &j.Paths.Keys,
+	}
+
+	for name, env := range j.Environments {
+		if err := env.expandEnv(); err != nil {
+			return err
+		}
+		j.Environments[name] = env
+	}
+	for i := range j.Replicas {
+		if err := j.Replicas[i].expandEnv(); err != nil {
+			return err
+		}
+	}
+
+	for i := range j.Chats {
+		values = append(values, &j.Chats[i])
+	}
+	for i := range j.Webhooks {
+		values = append(values, &j.Webhooks[i].URL, &j.Webhooks[i].Secret)
+	}
+
+	for _, v := range values {
+		expanded, err := expandEnv(*v)
+		if err != nil {
+			return err
+		}
+		*v = expanded
+	}
+
+	for k, v := range j.ObjectTags {
+		expanded, err := expandEnv(v)
+		if err != nil {
+			return err
+		}
+		j.ObjectTags[k] = expanded
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package journey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// DefaultRemoteEntry The file webpack Module Federation writes the container to unless the build names it otherwise
+const DefaultRemoteEntry = "remoteEntry.js"
+
+// FederationManifestFile The manifest @module-federation/enhanced writes next to the remote entry
+const FederationManifestFile = "mf-manifest.json"
+
+// exposedModule An exposed module in the container of a remote entry, like "./Button": () => ...
+var exposedModule = regexp.MustCompile(`"(\./[^"]+)"\s*:\s*(?:\(\)\s*=>|function\s*\(\s*\))`)
+
+// Federation The webpack Module Federation container of the build, found by itself when the build has a remoteEntry.js
+type Federation struct {
+	Name        string `json:"name"`
+	RemoteEntry string `json:"remoteEntry"`
+}
+
+// FederationUrls The remote entry and exposed modules listed in journey-urls.json so a shell can load the journey as a remote
+type FederationUrls struct {
+	Name        string   `json:"name,omitempty"`
+	RemoteEntry string   `json:"remoteEntry"`
+	Exposes     []string `json:"exposes"`
+}
+
+// federationManifest The parts of mf-manifest.json we need
+type federationManifest struct {
+	Name     string `json:"name"`
+	MetaData struct {
+		RemoteEntry struct {
+			Name string `json:"name"`
+			Path string `json:"path"`
+		} `json:"remoteEntry"`
+	} `json:"metaData"`
+	Exposes []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"exposes"`
+}
+
+// validate Validate the remote entry is a file inside the build
+func (f *Federation) validate() error {
+	if len(f.RemoteEntry) <= 0 {
+		return nil
+	}
+	if _, err := normalizeAssetPath(f.RemoteEntry); err != nil {
+		return fmt.Errorf("Federation remoteEntry: %v", err)
+	}
+
+	return nil
+}
+
+// loadFederationManifest Read mf-manifest.json from the build, nil when there is none
+func (j *Journey) loadFederationManifest() (*federationManifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(j.Build, FederationManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Unable to read %v: %v", FederationManifestFile, err)
+	}
+
+	var m federationManifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", FederationManifestFile, err)
+	}
+
+	return &m, nil
+}
+
+// findRemoteEntry The asset that is the remote entry, from journey.json, mf-manifest.json or a remoteEntry.js in the build,
+// and whether the build has to have it
+func (j *Journey) findRemoteEntry(assets map[string]string, m *federationManifest) (string, bool) {
+	if len(j.Federation.RemoteEntry) > 0 {
+		return j.Federation.RemoteEntry, true
+	}
+	if m != nil && len(m.MetaData.RemoteEntry.Name) > 0 {
+		return path.Join(m.MetaData.RemoteEntry.Path, m.MetaData.RemoteEntry.Name), true
+	}
+
+	var found []string
+	for _, v := range assets {
+		if path.Base(v) == DefaultRemoteEntry {
+			found = append(found, v)
+		}
+	}
+	if len(found) <= 0 {
+		return "", false
+	}
+	sort.Strings(found)
+	if len(found) > 1 {
+		Log.Warnf("The build has %v remote entries, using %v, set federation.remoteEntry in journey.json to pick another", len(found), found[0])
+	}
+
+	return found[0], false
+}
+
+// buildFederation List the remote entry and the modules it exposes when the build is a Module Federation remote,
+// nil when it is not
+func (j *Journey) buildFederation(assets map[string]string) (*FederationUrls, error) {
+	m, err := j.loadFederationManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, required := j.findRemoteEntry(assets, m)
+	if len(entry) <= 0 {
+		return nil, nil
+	}
+	entry, err = normalizeAssetPath(entry)
+	if err != nil {
+		return nil, fmt.Errorf("Remote entry: %v", err)
+	}
+
+	published := false
+	for _, v := range assets {
+		if v == entry {
+			published = true
+			break
+		}
+	}
+	if !published && required {
+		return nil, fmt.Errorf("Remote entry %v is not in the asset manifest or build of %v, check the filename of the ModuleFederationPlugin", entry, j.Name)
+	}
+	if _, err := os.Stat(j.GetAssetPath(entry)); err != nil {
+		return nil, fmt.Errorf("Remote entry %v is not in the build directory %v: %v", entry, j.Build, err)
+	}
+
+	key, err := j.assetKey(entry)
+	if err != nil {
+		return nil, err
+	}
+	f := &FederationUrls{Name: j.Federation.Name, RemoteEntry: j.CDNDomain + escapeKey(key)}
+
+	if m != nil {
+		if len(f.Name) <= 0 {
+			f.Name = m.Name
+		}
+		for _, e := range m.Exposes {
+			if len(e.Path) > 0 {
+				f.Exposes = append(f.Exposes, e.Path)
+			} else {
+				f.Exposes = append(f.Exposes, "./"+e.Name)
+			}
+		}
+	} else {
+		content, err := ioutil.ReadFile(j.GetAssetPath(entry))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the remote entry %v: %v", entry, err)
+		}
+		seen := make(map[string]bool)
+		for _, match := range exposedModule.FindAllStringSubmatch(string(content), -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				f.Exposes = append(f.Exposes, match[1])
+			}
+		}
+	}
+	sort.Strings(f.Exposes)
+
+	if len(f.Exposes) <= 0 {
+		Log.Warnf("Remote entry %v does not expose any modules", entry)
+		f.Exposes = []string{}
+	}
+	Log.Debugf("Remote entry %v exposes %v", entry, f.Exposes)
+
+	return f, nil
+}
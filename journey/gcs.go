@@ -0,0 +1,418 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// gcsEndpoint The Cloud Storage JSON API, used unless an endpoint is set for an emulator like fake-gcs-server
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// gcsScope The scope of the token objects are read and written with
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsTimeout How long a single request to Cloud Storage may take, uploads of big files included
+const gcsTimeout = 10 * time.Minute
+
+// gcsACLs The predefined ACL of Cloud Storage for each canned ACL of S3
+var gcsACLs = map[string]string{
+	ACLPrivate:                "private",
+	ACLPublicRead:             "publicRead",
+	ACLBucketOwnerFullControl: "bucketOwnerFullControl",
+}
+
+// gcsStorageClasses The Cloud Storage class each S3 storage class is stored in
+var gcsStorageClasses = map[string]string{
+	StorageClassStandard:           "STANDARD",
+	StorageClassStandardIA:         "NEARLINE",
+	StorageClassOneZoneIA:          "NEARLINE",
+	StorageClassIntelligentTiering: "STANDARD",
+	StorageClassGlacier:            "COLDLINE",
+	StorageClassDeepArchive:        "ARCHIVE",
+}
+
+// gcsS3StorageClasses The S3 storage class a Cloud Storage class is reported as
+var gcsS3StorageClasses = map[string]string{
+	"STANDARD":       StorageClassStandard,
+	"MULTI_REGIONAL": StorageClassStandard,
+	"REGIONAL":       StorageClassStandard,
+	"NEARLINE":       StorageClassStandardIA,
+	"COLDLINE":       StorageClassGlacier,
+	"ARCHIVE":        StorageClassDeepArchive,
+}
+
+// GCSStorage Storage in a Google Cloud Storage bucket, spoken to over its JSON API. Credentials are the application
+// default credentials: GOOGLE_APPLICATION_CREDENTIALS, gcloud auth application-default login or the metadata server
+type GCSStorage struct {
+	Bucket string
+	base   string
+	client *http.Client
+	acl    string
+}
+
+// gcsObject The resource of an object, the fields written on upload and read on get and list
+type gcsObject struct {
+	Name            string            `json:"name,omitempty"`
+	Size            string            `json:"size,omitempty"`
+	MD5Hash         string            `json:"md5Hash,omitempty"`
+	ETag            string            `json:"etag,omitempty"`
+	Updated         string            `json:"updated,omitempty"`
+	ContentType     string            `json:"contentType,omitempty"`
+	CacheControl    string            `json:"cacheControl,omitempty"`
+	ContentEncoding string            `json:"contentEncoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	StorageClass    string            `json:"storageClass,omitempty"`
+}
+
+// gcsObjects A page of a listing
+type gcsObjects struct {
+	Items         []*gcsObject `json:"items"`
+	Prefixes      []string     `json:"prefixes"`
+	NextPageToken string       `json:"nextPageToken"`
+}
+
+// gcsRewrite The progress of a rewrite, big objects take more than one request
+type gcsRewrite struct {
+	Done         bool   `json:"done"`
+	RewriteToken string `json:"rewriteToken"`
+	TotalBytes   string `json:"totalBytesRewritten"`
+	ObjectSize   string `json:"objectSize"`
+}
+
+// NewGCSStorage Create storage for the Cloud Storage bucket, with the default credentials of the machine
+func NewGCSStorage(bucket string, opts StorageOptions) (*GCSStorage, error) {
+	base := gcsEndpoint
+	if len(opts.Endpoint) > 0 {
+		base = strings.TrimSuffix(opts.Endpoint, "/")
+	}
+
+	client := &http.Client{Timeout: gcsTimeout}
+	creds, err := google.FindDefaultCredentials(context.Background(), gcsScope)
+	switch {
+	case err == nil:
+		client.Transport = &oauth2.Transport{Source: creds.TokenSource}
+	case len(opts.Endpoint) > 0:
+		// emulators do not check credentials, so there is no need for any
+	default:
+		return nil, fmt.Errorf("Storage backend %v needs Google credentials, set GOOGLE_APPLICATION_CREDENTIALS or run gcloud auth application-default login: %v", BackendGCS, err)
+	}
+
+	return &GCSStorage{Bucket: bucket, base: base, client: client, acl: opts.ACL}, nil
+}
+
+// objectURL The url of the key in the bucket
+func (g *GCSStorage) objectURL(bucket string, key string) string {
+	return g.base + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(key)
+}
+
+// do Send the request and decode the json it gets back into out, a missing object or bucket is ErrNotFound
+func (g *GCSStorage) do(ctx context.Context, method string, u string, body io.Reader, contentType string, out interface{}) error {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newStatusError(req, res)
+	}
+	if out == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("Unable to read the response to %v %v: %v", method, req.URL.Path, err)
+	}
+
+	return nil
+}
+
+// object Turn the resource into an object, the ETag is the hex md5 like S3 has unless the object is a composite
+func (o *gcsObject) object() *Object {
+	obj := &Object{
+		Key:          o.Name,
+		ETag:         o.ETag,
+		ContentType:  o.ContentType,
+		Metadata:     o.Metadata,
+		StorageClass: gcsS3StorageClasses[o.StorageClass],
+	}
+	obj.Size, _ = strconv.ParseInt(o.Size, 10, 64)
+	if sum, err := base64.StdEncoding.DecodeString(o.MD5Hash); err == nil && len(sum) > 0 {
+		obj.ETag = hex.EncodeToString(sum)
+	}
+	if updated, err := time.Parse(time.RFC3339Nano, o.Updated); err == nil {
+		obj.LastModified = updated
+	}
+
+	return obj
+}
+
+// writeGCSUpload Write the resource and the body as the two parts of a multipart upload
+func writeGCSUpload(mw *multipart.Writer, resource []byte, contentType string, body io.Reader) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(resource); err != nil {
+		return err
+	}
+
+	if len(contentType) <= 0 {
+		contentType = "application/octet-stream"
+	}
+	part, err = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, body); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+// Upload Upload the body with its headers, metadata, ACL and storage class in a single multipart request
+func (g *GCSStorage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	resource, err := json.Marshal(&gcsObject{
+		Name:            key,
+		ContentType:     opts.ContentType,
+		CacheControl:    opts.CacheControl,
+		ContentEncoding: opts.ContentEncoding,
+		Metadata:        opts.Metadata,
+		StorageClass:    gcsStorageClasses[opts.StorageClass],
+	})
+	if err != nil {
+		return err
+	}
+
+	acl := opts.ACL
+	if len(acl) <= 0 {
+		acl = g.acl
+	}
+	query := url.Values{"uploadType": {"multipart"}}
+	if acl, ok := gcsACLs[acl]; ok {
+		query.Set("predefinedAcl", acl)
+	}
+	u := g.base + "/upload/storage/v1/b/" + url.PathEscape(g.Bucket) + "/o?" + query.Encode()
+
+	// the body is streamed into the request, closing the reader stops the writer when the request fails early
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeGCSUpload(mw, resource, opts.ContentType, body))
+	}()
+	err = g.do(ctx, http.MethodPost, u, pr, "multipart/related; boundary="+mw.Boundary(), nil)
+	pr.Close()
+
+	return err
+}
+
+// resource Get the resource of the object
+func (g *GCSStorage) resource(ctx context.Context, key string) (*gcsObject, error) {
+	var o gcsObject
+	if err := g.do(ctx, http.MethodGet, g.objectURL(g.Bucket, key), nil, "", &o); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// Head Get the size, ETag, content type, metadata and storage class of the object
+func (g *GCSStorage) Head(ctx context.Context, key string) (*Object, error) {
+	o, err := g.resource(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.object(), nil
+}
+
+// Get Get the content of the object, the caller closes it. Objects stored gzipped are not decompressed
+func (g *GCSStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, g.objectURL(g.Bucket, key)+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	res, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, newStatusError(req, res)
+	}
+
+	return res.Body, nil
+}
+
+// rewrite Rewrite the object into another with the ACL of the storage, in as many requests as it takes. The
+// resource, when there is one, replaces the headers and metadata of the source
+func (g *GCSStorage) rewrite(ctx context.Context, bucket string, from string, to string, resource *gcsObject) error {
+	var body []byte
+	if resource != nil {
+		var err error
+		if body, err = json.Marshal(resource); err != nil {
+			return err
+		}
+	}
+
+	query := url.Values{}
+	if acl, ok := gcsACLs[g.acl]; ok {
+		query.Set("destinationPredefinedAcl", acl)
+	}
+	for {
+		u := g.objectURL(bucket, from) + "/rewriteTo/b/" + url.PathEscape(g.Bucket) + "/o/" + url.PathEscape(to) + "?" + query.Encode()
+
+		var r io.Reader
+		contentType := ""
+		if body != nil {
+			r = bytes.NewReader(body)
+			contentType = "application/json"
+		}
+
+		var progress gcsRewrite
+		if err := g.do(ctx, http.MethodPost, u, r, contentType, &progress); err != nil {
+			return err
+		}
+		if progress.Done {
+			return nil
+		}
+		Log.Debugf("Key: %v, rewritten %v of %v bytes", to, progress.TotalBytes, progress.ObjectSize)
+		query.Set("rewriteToken", progress.RewriteToken)
+	}
+}
+
+// Copy Server side copy an object to another key
+func (g *GCSStorage) Copy(ctx context.Context, from string, to string) error {
+	return g.CopyFrom(ctx, g.Bucket, from, to)
+}
+
+// CopyFrom Server side copy an object from another bucket into this one, keeping its headers and metadata
+func (g *GCSStorage) CopyFrom(ctx context.Context, bucket string, from string, to string) error {
+	return g.rewrite(ctx, bucket, from, to, nil)
+}
+
+// Delete Delete the objects, keys that do not exist are ignored like S3 does
+func (g *GCSStorage) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := g.do(ctx, http.MethodDelete, g.objectURL(g.Bucket, key), nil, "", nil); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// list Page through the objects and prefixes under the prefix, one level deep when there is a delimiter
+func (g *GCSStorage) list(ctx context.Context, prefix string, delimiter string) ([]*Object, []string, error) {
+	var objects []*Object
+	var prefixes []string
+
+	query := url.Values{"prefix": {prefix}}
+	if len(delimiter) > 0 {
+		query.Set("delimiter", delimiter)
+	}
+	for {
+		var page gcsObjects
+		err := g.do(ctx, http.MethodGet, g.base+"/storage/v1/b/"+url.PathEscape(g.Bucket)+"/o?"+query.Encode(), nil, "", &page)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, o := range page.Items {
+			objects = append(objects, o.object())
+		}
+		prefixes = append(prefixes, page.Prefixes...)
+
+		if len(page.NextPageToken) <= 0 {
+			return objects, prefixes, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// List List every object under the prefix, Cloud Storage sorts them by key like S3 does
+func (g *GCSStorage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	objects, _, err := g.list(ctx, prefix, "")
+	return objects, err
+}
+
+// ListPrefixes List the prefixes one level below the prefix
+func (g *GCSStorage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	_, prefixes, err := g.list(ctx, prefix, "/")
+	return prefixes, err
+}
+
+// ListIncompleteUploads Uploads are a single request, so nothing is ever left incomplete
+func (g *GCSStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortUpload Uploads are a single request, there is nothing to abort
+func (g *GCSStorage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	return ErrNotFound
+}
+
+// SetStorageClass Rewrite the object onto itself in the Cloud Storage class of the S3 storage class. The resource
+// sent with a rewrite replaces the one of the object, so its headers and metadata are sent along
+func (g *GCSStorage) SetStorageClass(ctx context.Context, key string, class string) error {
+	gcsClass, ok := gcsStorageClasses[class]
+	if !ok {
+		return fmt.Errorf("Storage class %v has no Cloud Storage class", class)
+	}
+
+	o, err := g.resource(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return g.rewrite(ctx, g.Bucket, key, key, &gcsObject{
+		ContentType:     o.ContentType,
+		CacheControl:    o.CacheControl,
+		ContentEncoding: o.ContentEncoding,
+		Metadata:        o.Metadata,
+		StorageClass:    gcsClass,
+	})
+}
+
+// Restore Objects in the archive classes of Cloud Storage can be read right away, there is nothing to restore
+func (g *GCSStorage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	if _, err := g.Head(ctx, key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,257 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGCS Enough of the Cloud Storage JSON API for the storage to be tested against, one object per page of a listing
+type fakeGCS struct {
+	mu      sync.Mutex
+	objects map[string]*gcsObject
+	content map[string][]byte
+	acls    map[string]string
+}
+
+func newFakeGCS() *fakeGCS {
+	return &fakeGCS{objects: make(map[string]*gcsObject), content: make(map[string][]byte), acls: make(map[string]string)}
+}
+
+func (f *fakeGCS) put(bucket string, o *gcsObject, content []byte, acl string) {
+	sum := md5.Sum(content)
+	o.Size = strconv.Itoa(len(content))
+	o.MD5Hash = base64.StdEncoding.EncodeToString(sum[:])
+	if len(o.StorageClass) <= 0 {
+		o.StorageClass = "STANDARD"
+	}
+	f.objects[bucket+"/"+o.Name] = o
+	f.content[bucket+"/"+o.Name] = content
+	f.acls[bucket+"/"+o.Name] = acl
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// the object name is escaped in the path, so it is split before it is unescaped
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	for i := range segments {
+		segments[i], _ = url.PathUnescape(segments[i])
+	}
+
+	switch {
+	case r.Method == http.MethodPost && len(segments) == 7 && segments[1] == "upload":
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		part, _ := mr.NextPart()
+		var o gcsObject
+		json.NewDecoder(part).Decode(&o)
+		part, _ = mr.NextPart()
+		content, _ := ioutil.ReadAll(part)
+		f.put(segments[5], &o, content, r.URL.Query().Get("predefinedAcl"))
+		json.NewEncoder(w).Encode(&o)
+	case r.Method == http.MethodPost && len(segments) == 12 && segments[7] == "rewriteTo":
+		src, ok := f.objects[segments[4]+"/"+segments[6]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		o := *src
+		if r.ContentLength > 0 {
+			o = gcsObject{}
+			json.NewDecoder(r.Body).Decode(&o)
+		}
+		o.Name = segments[11]
+		f.put(segments[9], &o, f.content[segments[4]+"/"+segments[6]], r.URL.Query().Get("destinationPredefinedAcl"))
+		json.NewEncoder(w).Encode(&gcsRewrite{Done: true})
+	case r.Method == http.MethodGet && len(segments) == 7:
+		o, ok := f.objects[segments[4]+"/"+segments[6]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			w.Write(f.content[segments[4]+"/"+segments[6]])
+			return
+		}
+		json.NewEncoder(w).Encode(o)
+	case r.Method == http.MethodDelete && len(segments) == 7:
+		if _, ok := f.objects[segments[4]+"/"+segments[6]]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.objects, segments[4]+"/"+segments[6])
+	case r.Method == http.MethodGet && len(segments) == 6:
+		f.list(w, r, segments[4])
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
+
+// list Page through the objects of the bucket one by one, the page token is the index of the next one
+func (f *fakeGCS) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+
+	var names []string
+	seen := make(map[string]bool)
+	for k := range f.objects {
+		name := strings.TrimPrefix(k, bucket+"/")
+		if !strings.HasPrefix(k, bucket+"/") || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if i := strings.Index(name[len(prefix):], delimiter); len(delimiter) > 0 && i >= 0 {
+			name = name[:len(prefix)+i+1]
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	var page gcsObjects
+	if start < len(names) {
+		if o, ok := f.objects[bucket+"/"+names[start]]; ok {
+			page.Items = append(page.Items, o)
+		} else {
+			page.Prefixes = append(page.Prefixes, names[start])
+		}
+	}
+	if start+1 < len(names) {
+		page.NextPageToken = strconv.Itoa(start + 1)
+	}
+	json.NewEncoder(w).Encode(&page)
+}
+
+func TestGCSStorage(t *testing.T) {
+	fake := newFakeGCS()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := &GCSStorage{Bucket: "portal", base: server.URL, client: server.Client(), acl: ACLPublicRead}
+	ctx := context.Background()
+
+	content := []byte("console.log('checkout');")
+	opts := UploadOptions{ContentType: "application/javascript", CacheControl: "max-age=60", ACL: ACLPublicRead, Metadata: map[string]string{ChecksumMetadata: "sum"}}
+	for _, key := range []string{"checkout/1.0.0/app.js", "checkout/1.0.0/js/vendor.js", "checkout/1.1.0/app.js", "cart/1.0.0/app.js"} {
+		if err := store.Upload(ctx, key, bytes.NewReader(content), opts); err != nil {
+			t.Fatalf("Upload(%v) failed: %v", key, err)
+		}
+	}
+	if acl := fake.acls["portal/checkout/1.0.0/app.js"]; acl != "publicRead" {
+		t.Errorf("predefinedAcl = %v, want publicRead", acl)
+	}
+	if o := fake.objects["portal/checkout/1.0.0/app.js"]; o.ContentType != "application/javascript" || o.CacheControl != "max-age=60" {
+		t.Errorf("resource = %+v, want its content type and Cache-Control", o)
+	}
+
+	sum := md5.Sum(content)
+	o, err := store.Head(ctx, "checkout/1.0.0/app.js")
+	if err != nil {
+		t.Fatalf("Head() failed: %v", err)
+	}
+	want := &Object{Key: "checkout/1.0.0/app.js", Size: int64(len(content)), ETag: hex.EncodeToString(sum[:]), ContentType: "application/javascript", Metadata: opts.Metadata, StorageClass: StorageClassStandard}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("Head() = %+v, want %+v", o, want)
+	}
+	if _, err := store.Head(ctx, "checkout/2.0.0/app.js"); err != ErrNotFound {
+		t.Errorf("Head() of a missing key = %v, want ErrNotFound", err)
+	}
+
+	if err := store.CopyFrom(ctx, "portal", "checkout/1.0.0/app.js", "checkout/latest/app.js"); err != nil {
+		t.Fatalf("CopyFrom() failed: %v", err)
+	}
+	r, err := store.Get(ctx, "checkout/latest/app.js")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Get() = %q, %v, want %q", got, err, content)
+	}
+	if copied := fake.objects["portal/checkout/latest/app.js"]; copied.CacheControl != "max-age=60" || len(copied.Metadata) <= 0 || fake.acls["portal/checkout/latest/app.js"] != "publicRead" {
+		t.Errorf("copy has Cache-Control %q, metadata %v and acl %q, want them kept", copied.CacheControl, copied.Metadata, fake.acls["portal/checkout/latest/app.js"])
+	}
+
+	if err := store.SetStorageClass(ctx, "checkout/1.0.0/app.js", StorageClassGlacier); err != nil {
+		t.Fatalf("SetStorageClass() failed: %v", err)
+	}
+	if o, err := store.Head(ctx, "checkout/1.0.0/app.js"); err != nil || o.StorageClass != StorageClassGlacier || o.ContentType != "application/javascript" || len(o.Metadata) <= 0 {
+		t.Errorf("Head() after SetStorageClass() = %+v, %v, want %v with its headers and metadata", o, err, StorageClassGlacier)
+	}
+	if restored, err := store.Restore(ctx, "checkout/1.0.0/app.js", 1); err != nil || !restored {
+		t.Errorf("Restore() = %v, %v, want it readable right away", restored, err)
+	}
+
+	list, err := store.List(ctx, "checkout/1.0.0/")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var keys []string
+	for _, o := range list {
+		keys = append(keys, o.Key)
+	}
+	if want := []string{"checkout/1.0.0/app.js", "checkout/1.0.0/js/vendor.js"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+
+	prefixes, err := store.ListPrefixes(ctx, "checkout/")
+	if err != nil {
+		t.Fatalf("ListPrefixes() failed: %v", err)
+	}
+	if want := []string{"checkout/1.0.0/", "checkout/1.1.0/", "checkout/latest/"}; !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("ListPrefixes() = %v, want %v", prefixes, want)
+	}
+
+	if err := store.Delete(ctx, "checkout/1.1.0/app.js", "checkout/1.1.0/missing.js"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.Head(ctx, "checkout/1.1.0/app.js"); err != ErrNotFound {
+		t.Errorf("Head() of a deleted key = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNewGCSStorageEndpoint(t *testing.T) {
+	// an emulator needs no credentials, so this works on machines without any
+	store, err := NewGCSStorage("portal", StorageOptions{Endpoint: "http://localhost:4443/"})
+	if err != nil {
+		t.Fatalf("NewGCSStorage() failed: %v", err)
+	}
+	if store.base != "http://localhost:4443" {
+		t.Errorf("NewGCSStorage() talks to %v, want http://localhost:4443", store.base)
+	}
+}
+
+func TestGCSStorageErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"message": "rate limited"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	store := &GCSStorage{Bucket: "portal", base: server.URL, client: server.Client()}
+	_, err := store.Head(context.Background(), "checkout/1.0.0/app.js")
+	if !isRetryable(err) {
+		t.Errorf("Head() = %v, want an error that is retried", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("Head() = %v, want the message of the server", err)
+	}
+}
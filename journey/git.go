@@ -0,0 +1,174 @@
+package journey
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Git The commit a version was built from
+type Git struct {
+	Commit string `json:"commit,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+}
+
+// Empty Check if nothing is known about the commit
+func (g *Git) Empty() bool {
+	return g == nil || (len(g.Commit) <= 0 && len(g.Branch) <= 0 && len(g.Tag) <= 0)
+}
+
+// runGit Run git in the directory and return its trimmed output, empty when it fails
+func runGit(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// DetectGit Find the commit, branch and tag of the repository holding dir, falling back to the CI environment
+// when git is not installed or the build does not have the repository checked out
+func DetectGit(dir string) Git {
+	g := Git{
+		Commit: runGit(dir, "rev-parse", "HEAD"),
+		Branch: runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"),
+		Tag:    runGit(dir, "describe", "--tags", "--exact-match", "HEAD"),
+	}
+
+	// a detached checkout, as most CI systems do, has no branch
+	if g.Branch == "HEAD" {
+		g.Branch = ""
+	}
+
+	if len(g.Commit) <= 0 {
+		g.Commit = ciCommit()
+	}
+	if len(g.Branch) <= 0 {
+		g.Branch = firstEnv("GITHUB_HEAD_REF", "CI_COMMIT_BRANCH", "BRANCH_NAME", "GIT_BRANCH", "CIRCLE_BRANCH", "TRAVIS_BRANCH", "BITBUCKET_BRANCH")
+		if len(g.Branch) <= 0 && firstEnv("GITHUB_REF_TYPE") == "branch" {
+			g.Branch = firstEnv("GITHUB_REF_NAME")
+		}
+	}
+	if len(g.Tag) <= 0 {
+		g.Tag = firstEnv("CI_COMMIT_TAG", "TAG_NAME", "CIRCLE_TAG", "TRAVIS_TAG", "BITBUCKET_TAG")
+		if len(g.Tag) <= 0 && firstEnv("GITHUB_REF_TYPE") == "tag" {
+			g.Tag = firstEnv("GITHUB_REF_NAME")
+		}
+	}
+
+	return g
+}
+
+// UseGit Fill in what is not already known about the commit from the repository holding journey.json
+func (j *Journey) UseGit() {
+	detected := DetectGit(filepath.Dir(j.JourneyPath))
+	if j.Git == nil {
+		j.Git = &Git{}
+	}
+	if len(j.Git.Commit) <= 0 {
+		j.Git.Commit = detected.Commit
+	}
+	if len(j.Git.Branch) <= 0 {
+		j.Git.Branch = detected.Branch
+	}
+	if len(j.Git.Tag) <= 0 {
+		j.Git.Tag = detected.Tag
+	}
+
+	if j.Git.Empty() {
+		j.Git = nil
+		Log.Debugf("No git commit found for %v", j.JourneyPath)
+	}
+}
+
+// privateTag The journey struct tag of fields left out of the published journey.json, like tokens, webhook urls and keys,
+// eg: `json:"token" journey:"private"`
+const privateTag = "private"
+
+// isPrivate Check if the field is left out of the published journey.json
+func isPrivate(f reflect.StructField) bool {
+	return f.Tag.Get("journey") == privateTag
+}
+
+// removePrivate Remove the private fields of the type from the json, at any depth like the endpoint of an environment,
+// and check if there were any
+func removePrivate(raw interface{}, t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	removed := false
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for _, item := range v {
+				removed = removePrivate(item, t.Elem()) || removed
+			}
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for k, item := range v {
+				f, ok := matchField(k, fields)
+				if !ok {
+					continue
+				}
+				if isPrivate(f) {
+					delete(v, k)
+					removed = true
+					continue
+				}
+				removed = removePrivate(item, f.Type) || removed
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, item := range v {
+				removed = removePrivate(item, t.Elem()) || removed
+			}
+		}
+	}
+
+	return removed
+}
+
+// journeyFileBody Get the journey.json published with the version, the local file, stdin or the json it was converted to,
+// with the git commit added and the private fields removed. Nil means the local file is published as it is
+func (j *Journey) journeyFileBody() ([]byte, error) {
+	content, err := j.readJourneyFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are kept as they were written
+	var config map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", j.configName(), err)
+	}
+
+	private := removePrivate(config, reflect.TypeOf(j))
+	if j.Git.Empty() && !private {
+		return j.JourneyContent, nil
+	}
+
+	if !j.Git.Empty() {
+		config["git"] = j.Git
+	}
+
+	body, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse %v into json", j.configName())
+	}
+
+	return body, nil
+}
@@ -0,0 +1,130 @@
+package journey
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// globRegexp Compile a glob into a regexp, * and ? stay inside a directory while ** crosses them
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b bytes.Buffer
+	b.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// validateGlobs Validate every glob compiles
+func validateGlobs(name string, patterns []string) error {
+	for _, p := range patterns {
+		if _, err := globRegexp(p); err != nil {
+			return fmt.Errorf("%v pattern %v is not valid: %v", name, p, err)
+		}
+	}
+
+	return nil
+}
+
+// matchAny Check if the asset path matches one of the globs, a glob without a / matches the file name in any directory
+func matchAny(patterns []string, asset string) bool {
+	for _, p := range patterns {
+		re, err := globRegexp(p)
+		if err != nil {
+			continue
+		}
+
+		name := asset
+		if !strings.Contains(p, "/") {
+			name = path.Base(asset)
+		}
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addBuildFiles Add the files in the build directory the manifest does not list, every file with includeAll
+// or the files matching include, so licences, favicons and locale bundles are published too
+func (j *Journey) addBuildFiles(assets map[string]string) (map[string]string, error) {
+	if !j.IncludeAll && len(j.Include) <= 0 {
+		return assets, nil
+	}
+
+	all := make(map[string]string, len(assets))
+	listed := make(map[string]bool, len(assets))
+	for k, v := range assets {
+		all[k] = v
+		listed[v] = true
+	}
+
+	// the manifest and journey.json are published under their own keys
+	skip := make(map[string]bool)
+	for _, p := range []string{j.Manifest, j.JourneyPath} {
+		if abs, err := filepath.Abs(p); err == nil {
+			skip[abs] = true
+		}
+	}
+
+	root := filepath.Clean(j.Build)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip, err := j.skipJunk(root, p, info); skip {
+			return err
+		}
+		if info.IsDir() || isSourceMap(p) {
+			return nil
+		}
+		if abs, err := filepath.Abs(p); err == nil && skip[abs] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if listed[rel] || (!j.IncludeAll && !matchAny(j.Include, rel)) {
+			return nil
+		}
+
+		Log.Debugf("Found %v in the build directory that is not in the manifest", rel)
+		all[rel] = rel
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to walk the build directory %v: %v", j.Build, err)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,120 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HistoryFile The object at the root of the journey that records every publish and change to latest
+const HistoryFile = "history.json"
+
+// Actions recorded in the history
+const (
+	ActionPublish       = "publish"
+	ActionSetLatest     = "setLatest"
+	ActionRollback      = "rollback"
+	ActionSetChannel    = "setChannel"
+	ActionCanary        = "canary"
+	ActionPromoteCanary = "promoteCanary"
+)
+
+// CLIVersion The version of the cli, recorded with every entry so a bad publish can be traced to the build that made it
+var CLIVersion = "dev"
+
+// HistoryEntry A single change recorded in the history
+type HistoryEntry struct {
+	Action  string    `json:"action"`
+	Channel string    `json:"channel,omitempty"`
+	Version string    `json:"version"`
+	Weight  int       `json:"weight,omitempty"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Commit  string    `json:"commit,omitempty"`
+	JobURL  string    `json:"jobUrl,omitempty"`
+	CLI     string    `json:"cli,omitempty"`
+}
+
+// firstEnv Get the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); len(v) > 0 {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// ciUser Get who ran the command, the CI user when running in a pipeline
+func ciUser() string {
+	return firstEnv("GITHUB_ACTOR", "GITLAB_USER_LOGIN", "BUILD_USER_ID", "CIRCLE_USERNAME", "BITBUCKET_STEP_TRIGGERER_UUID", "USER", "USERNAME")
+}
+
+// ciCommit Get the commit the pipeline is building
+func ciCommit() string {
+	return firstEnv("GITHUB_SHA", "CI_COMMIT_SHA", "GIT_COMMIT", "CIRCLE_SHA1", "TRAVIS_COMMIT", "BITBUCKET_COMMIT", "BUILD_SOURCEVERSION")
+}
+
+// ciJobURL Get the url of the pipeline job running the command
+func ciJobURL() string {
+	if id := os.Getenv("GITHUB_RUN_ID"); len(id) > 0 {
+		server := firstEnv("GITHUB_SERVER_URL")
+		if len(server) <= 0 {
+			server = "https://github.com"
+		}
+		return server + "/" + os.Getenv("GITHUB_REPOSITORY") + "/actions/runs/" + id
+	}
+
+	return firstEnv("CI_JOB_URL", "BUILD_URL", "CIRCLE_BUILD_URL", "TRAVIS_JOB_WEB_URL")
+}
+
+// GetHistoryKey Get the key of the history object of the journey
+func (j *Journey) GetHistoryKey() string {
+	return j.GetJourneyKey(HistoryFile)
+}
+
+// GetHistory Get every entry in the history, oldest first
+func (j *Journey) GetHistory(ctx context.Context, store Storage) ([]HistoryEntry, error) {
+	var history []HistoryEntry
+
+	body, err := store.Get(ctx, j.GetHistoryKey())
+	if err == ErrNotFound {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", j.GetHistoryKey(), err)
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(&history); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", j.GetHistoryKey(), err)
+	}
+
+	return history, nil
+}
+
+// recordHistory Append the entry to the history of the journey
+func (j *Journey) recordHistory(ctx context.Context, store Storage, entry HistoryEntry) error {
+	history, err := j.GetHistory(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	entry.Time = time.Now().UTC()
+	entry.User = ciUser()
+	entry.Commit = ciCommit()
+	entry.JobURL = ciJobURL()
+	entry.CLI = CLIVersion
+	history = append(history, entry)
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to parse the history into json")
+	}
+
+	return store.Upload(ctx, j.GetHistoryKey(), bytes.NewReader(data), j.uploadOptions(j.GetHistoryKey(), "application/json"))
+}
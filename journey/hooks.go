@@ -0,0 +1,92 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// Hooks Shell commands run before and after commands, like building before publishing
+type Hooks struct {
+	PrePublish    []string `json:"prePublish"`
+	PostPublish   []string `json:"postPublish"`
+	PostSetLatest []string `json:"postSetLatest"`
+}
+
+// Hook names, used in logs and errors
+const (
+	HookPrePublish    = "prePublish"
+	HookPostPublish   = "postPublish"
+	HookPostSetLatest = "postSetLatest"
+)
+
+// commands The commands of the hook
+func (h *Hooks) commands(hook string) []string {
+	switch hook {
+	case HookPrePublish:
+		return h.PrePublish
+	case HookPostPublish:
+		return h.PostPublish
+	case HookPostSetLatest:
+		return h.PostSetLatest
+	default:
+		return nil
+	}
+}
+
+// hookEnv The environment of hook commands, describing the version and its urls
+func (j *Journey) hookEnv() []string {
+	return append(os.Environ(),
+		"JOURNEY_NAME="+j.Name,
+		"JOURNEY_VERSION="+j.Version,
+		"JOURNEY_BUCKET="+j.Bucket,
+		"JOURNEY_CDN="+j.CDNDomain,
+		"JOURNEY_ENVIRONMENT="+j.Environment,
+		"JOURNEY_URLS="+j.CDNDomain+escapeKey(j.GetAssetKey(j.UrlsFile())),
+		"JOURNEY_LATEST_URLS="+j.CDNDomain+escapeKey(j.GetLatestKey(j.UrlsFile())),
+	)
+}
+
+// shellCommand Run the command with the shell of the platform
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// RunHook Run the commands of the hook one after the other in the directory of journey.json, stopping at the first that fails
+func (j *Journey) RunHook(ctx context.Context, hook string) error {
+	commands := j.Hooks.commands(hook)
+	if j.SkipHooks || len(commands) <= 0 {
+		return nil
+	}
+	if j.DryRun {
+		Log.Infof("Dry run, the %v hook would run %v commands", hook, len(commands))
+		return nil
+	}
+
+	dir := "."
+	if j.JourneyPath != Stdin {
+		dir = filepath.Dir(j.JourneyPath)
+	}
+
+	for _, command := range commands {
+		Log.Infof("Running %v hook: %v", hook, command)
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = dir
+		cmd.Env = j.hookEnv()
+		cmd.Stdout = Log.out
+		cmd.Stderr = Log.out
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("The %v hook %v failed: %v", hook, command, err)
+		}
+	}
+
+	return nil
+}
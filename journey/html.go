@@ -0,0 +1,87 @@
+package journey
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io/ioutil"
+)
+
+// HTMLFile The page rendered from the html template and published with the version
+const HTMLFile = "index.html"
+
+// HTMLData What the html template is rendered with
+type HTMLData struct {
+	Name     string
+	Version  string
+	RootID   string
+	Urls     *Urls
+	Metadata map[string]string
+	Git      *Git
+
+	// the link and script tags of the journey urls, entrypoints first
+	Styles  template.HTML
+	Scripts template.HTML
+	Tags    template.HTML
+}
+
+// htmlTags Build the link tags of the css and script tags of the js, with their integrity when there is one
+func htmlTags(urls *Urls) (template.HTML, template.HTML) {
+	var styles bytes.Buffer
+	for _, c := range urls.CSS {
+		fmt.Fprintf(&styles, `<link rel="stylesheet" href="%v"`, html.EscapeString(c.URL))
+		if len(c.Integrity) > 0 {
+			fmt.Fprintf(&styles, ` integrity="%v" crossorigin="anonymous"`, html.EscapeString(c.Integrity))
+		}
+		styles.WriteString(">\n")
+	}
+
+	var scripts bytes.Buffer
+	for _, s := range urls.JS {
+		fmt.Fprintf(&scripts, `<script src="%v"`, html.EscapeString(s.URL))
+		if len(s.Integrity) > 0 {
+			fmt.Fprintf(&scripts, ` integrity="%v" crossorigin="anonymous"`, html.EscapeString(s.Integrity))
+		}
+		scripts.WriteString(" defer></script>\n")
+	}
+
+	return template.HTML(styles.String()), template.HTML(scripts.String())
+}
+
+// renderHTML Render the html template of the journey for the journey urls, nil when there is no template
+func (j *Journey) renderHTML(urls *Urls) ([]byte, error) {
+	if len(j.HTMLTemplate) <= 0 {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(j.HTMLTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the html template %v: %v", j.HTMLTemplate, err)
+	}
+
+	t, err := template.New(HTMLFile).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the html template %v: %v", j.HTMLTemplate, err)
+	}
+
+	styles, scripts := htmlTags(urls)
+	data := &HTMLData{
+		Name:     j.Name,
+		Version:  j.Version,
+		RootID:   j.RootID,
+		Urls:     urls,
+		Metadata: j.Metadata,
+		Git:      j.Git,
+		Styles:   styles,
+		Scripts:  scripts,
+		Tags:     styles + scripts,
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("Unable to render the html template %v: %v", j.HTMLTemplate, err)
+	}
+
+	return b.Bytes(), nil
+}
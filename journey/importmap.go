@@ -0,0 +1,118 @@
+package journey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ImportMapFile The import map published next to the journey urls
+const ImportMapFile = "importmap.json"
+
+// importMapFiles The files built from the journey urls that channels point at along with them
+var importMapFiles = []string{ImportMapFile, SingleSpaImportMapFile, SingleSpaFile}
+
+// ImportMap A standard import map, https://github.com/WICG/import-maps
+type ImportMap struct {
+	Imports map[string]string `json:"imports"`
+}
+
+// importMapName The specifier the journey is imported with, the name of the journey unless configured otherwise
+func (j *Journey) importMapName() string {
+	if len(j.ImportMapName) > 0 {
+		return j.ImportMapName
+	}
+
+	return j.Name
+}
+
+// entryScript The url of the script that starts the journey, which import maps point the specifier of the journey at
+func (j *Journey) entryScript(urls *Urls) (string, error) {
+	if len(urls.JS) <= 0 {
+		return "", fmt.Errorf("An import map needs an entry script, %v/%v does not have any js", j.Name, j.Version)
+	}
+
+	// entrypoints are sorted first, the last of them is the one that starts the journey
+	entry := urls.JS[0].URL
+	entries := 0
+	for _, s := range urls.JS {
+		if s.Entry {
+			entry = s.URL
+			entries++
+		}
+	}
+	if entries <= 0 && len(urls.JS) > 1 {
+		Log.Warnf("%v/%v has no entrypoints, the import map points at %v", j.Name, j.Version, entry)
+	}
+
+	return entry, nil
+}
+
+// buildImportMap Map the specifier of the journey to its entry script, and the specifier with a trailing slash to the version,
+// nil when import maps are turned off
+func (j *Journey) buildImportMap(urls *Urls) ([]byte, error) {
+	if !j.ImportMap {
+		return nil, nil
+	}
+	entry, err := j.entryScript(urls)
+	if err != nil {
+		return nil, err
+	}
+
+	name := j.importMapName()
+	m := &ImportMap{Imports: map[string]string{
+		name:       entry,
+		name + "/": j.CDNDomain + escapeKey(j.GetAssetKey("")),
+	}}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the import map into json")
+	}
+
+	return data, nil
+}
+
+// importMapUploads The import map and single-spa files built from the journey urls, for the output formats of the journey
+func (j *Journey) importMapUploads(urls *Urls) ([]*Upload, error) {
+	var uploads []*Upload
+
+	importMap, err := j.buildImportMap(urls)
+	if err != nil {
+		return nil, err
+	}
+	if importMap != nil {
+		uploads = append(uploads, j.newUpload(j.GetAssetKey(ImportMapFile), "", importMap, "application/importmap+json"))
+	}
+
+	fragment, app, err := j.buildSingleSpa(urls)
+	if err != nil {
+		return nil, err
+	}
+	if fragment != nil {
+		uploads = append(uploads,
+			j.newUpload(j.GetAssetKey(SingleSpaImportMapFile), "", fragment, "application/importmap+json"),
+			j.newUpload(j.GetAssetKey(SingleSpaFile), "", app, "application/json"),
+		)
+	}
+
+	return uploads, nil
+}
+
+// pointImportMap Copy the import maps and single-spa metadata of the version to the channel when the version has them
+func (j *Journey) pointImportMap(ctx context.Context, store Storage, channel string, version string) error {
+	for _, file := range importMapFiles {
+		source := j.GetVersionKey(version, file)
+		if _, err := store.Head(ctx, source); err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("Unable to get %v: %v", source, err)
+		}
+
+		if err := store.Copy(ctx, source, j.GetChannelKey(channel, file)); err != nil {
+			return fmt.Errorf("Unable to copy %v to %v: %v", source, channel, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package journey
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// InitConfig The settings init asks for and writes to a new journey.json
+type InitConfig struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	RootID   string `json:"rootID"`
+	Build    string `json:"build"`
+	Manifest string `json:"manifest"`
+	Bucket   string `json:"bucket"`
+	CDN      string `json:"cdn"`
+}
+
+// DefaultInitConfig The answers init suggests for a create-react-app build
+func DefaultInitConfig() InitConfig {
+	return InitConfig{
+		Version:  "1.0.0",
+		Build:    "./build/",
+		Manifest: "./build/asset-manifest.json",
+	}
+}
+
+// initField A setting init asks for
+type initField struct {
+	label    string
+	value    *string
+	validate func(string) error
+}
+
+// required Validate a setting is not empty
+func required(name string) func(string) error {
+	return func(v string) error {
+		if len(v) <= 0 {
+			return fmt.Errorf("%v is required", name)
+		}
+		return nil
+	}
+}
+
+// Validate Validate the settings are complete and can be published with
+func (c *InitConfig) Validate(skipSemver bool) error {
+	for _, f := range c.fields(skipSemver) {
+		if err := f.validate(*f.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *InitConfig) fields(skipSemver bool) []initField {
+	return []initField{
+		{"Name", &c.Name, func(v string) error {
+			if err := required("name")(v); err != nil {
+				return err
+			}
+			if strings.ContainsAny(v, `/\ `) {
+				return fmt.Errorf("Name %v can not contain path separators or spaces", v)
+			}
+			return nil
+		}},
+		{"Version", &c.Version, func(v string) error {
+			if err := required("version")(v); err != nil {
+				return err
+			}
+			return validateVersion(v, skipSemver)
+		}},
+		{"Root element id", &c.RootID, required("rootID")},
+		{"Build directory", &c.Build, required("build")},
+		{"Asset manifest", &c.Manifest, required("manifest")},
+		{"Bucket", &c.Bucket, required("bucket")},
+		{"CDN domain", &c.CDN, func(v string) error {
+			if err := required("cdn")(v); err != nil {
+				return err
+			}
+			if !strings.HasPrefix(v, "http://") && !strings.HasPrefix(v, "https://") {
+				return fmt.Errorf("CDN domain %v must start with http:// or https://", v)
+			}
+			return nil
+		}},
+	}
+}
+
+// Prompt Ask for every setting on in, showing the current value as the default, until each one is valid
+func (c *InitConfig) Prompt(in io.Reader, out io.Writer, skipSemver bool) error {
+	r := bufio.NewReader(in)
+
+	for _, f := range c.fields(skipSemver) {
+		for {
+			fmt.Fprintf(out, "%v [%v]: ", f.label, *f.value)
+
+			line, err := r.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if answer := strings.TrimSpace(line); len(answer) > 0 {
+				*f.value = answer
+			}
+
+			verr := f.validate(*f.value)
+			if verr == nil {
+				break
+			}
+			if err == io.EOF {
+				return verr
+			}
+			fmt.Fprintln(out, verr)
+		}
+	}
+
+	return nil
+}
+
+// Write Write the settings to path as journey.json, an existing file is only replaced when overwrite is set
+func (c *InitConfig) Write(path string, overwrite bool) error {
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return fmt.Errorf("%v already exists, pass -force to replace it", path)
+	}
+
+	// urls are built by appending the key to the cdn domain
+	if !strings.HasSuffix(c.CDN, "/") {
+		c.CDN += "/"
+	}
+	if !strings.HasSuffix(c.Build, "/") {
+		c.Build += "/"
+	}
+
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return fmt.Errorf("Unable to parse the journey config into json")
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -2,58 +2,51 @@ package journey
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
 	"mime"
 	"os"
 	"path/filepath"
-	"sync"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/go-playground/validator.v9"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 // CSS Struct for tracking public data of a css object
 type CSS struct {
-	URL string `json:"url"`
+	URL       string `json:"url"`
+	Integrity string `json:"integrity,omitempty"`
+	Entry     bool   `json:"entry,omitempty"`
 }
 
 // JS Struct for tracking public data of a js object
 type JS struct {
-	URL    string `json:"url"`
-	RootID string `json:"rootID"`
+	URL       string `json:"url"`
+	RootID    string `json:"rootID"`
+	Integrity string `json:"integrity,omitempty"`
+	Entry     bool   `json:"entry,omitempty"`
 }
 
-// Urls The urls of the assets are tracking
+// Urls The urls of the assets are tracking, entrypoints come first in the order they load
 type Urls struct {
-	CSS []CSS `json:"css"`
-	JS  []JS  `json:"js"`
-}
-
-// Publish Publish the journey urls to the package and version
-func (urls *Urls) Publish(journey *Journey, uploader *s3manager.Uploader, wg *sync.WaitGroup) (*s3manager.UploadOutput, error) {
-	defer wg.Done()
-	log.Printf("Starting to upload static asset urls to this bucket: %v", journey.Bucket)
+	CSS     []CSS     `json:"css"`
+	JS      []JS      `json:"js"`
+	Assets  []Asset   `json:"assets,omitempty"`
+	Preload []Preload `json:"preload,omitempty"`
+	Git     *Git      `json:"git,omitempty"`
 
-	key := journey.Name + "/" + journey.Version + "/journey-urls.json"
+	// Journeys to load before this one
+	Dependencies []Dependency `json:"dependencies,omitempty"`
 
-	data, err := json.Marshal(urls)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to parse the journey urls into json")
-	}
+	// Module Federation remote entry and the modules it exposes
+	Federation *FederationUrls `json:"federation,omitempty"`
 
-	// Upload the static assest urls to S3
-	return uploader.Upload(&s3manager.UploadInput{
-		Bucket:      aws.String(journey.Bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(data),
-		ContentType: aws.String("application/javascript"),
-	})
+	// When the signed urls stop working
+	Expires *time.Time `json:"expires,omitempty"`
 }
 
 // Journey Represents the journey.json configuration
@@ -65,106 +58,861 @@ type Journey struct {
 	Manifest    string `json:"manifest" validate:"required"`
 	Bucket      string `json:"bucket" validate:"required"`
 	JourneyPath string `validate:"required"`
-	CDNDomain   string `validate:"required"`
+	CDNDomain   string `json:"cdn" validate:"required"`
+
+	// Format of the asset manifest, defaults to flat
+	ManifestFormat string `json:"manifestFormat"`
+
+	// Storage backend, defaults to s3
+	Storage string `json:"storage"`
+	Region  string `json:"region"`
+
+	// Prefix in front of the name of every key, for buckets shared by many teams, eg: frontends
+	Prefix string `json:"prefix"`
+
+	// Name of the urls file and Go template of the key of every version, defaults to journey-urls.json under {{.Name}}/{{.Version}}
+	Paths Paths `json:"paths"`
+
+	// S3 compatible endpoint like MinIO, Ceph RGW, Spaces or localstack, and whether buckets are addressed in the path.
+	// The url of the server for webdav and sftp, and of an emulator for gcs and azure
+	Endpoint  string `json:"endpoint" journey:"private"`
+	PathStyle bool   `json:"pathStyle"`
+
+	// User and password of the WebDAV or SFTP server, kept out of the endpoint, eg: "password": "${DAV_PASSWORD}"
+	User     string `json:"user" journey:"private"`
+	Password string `json:"password" journey:"private"`
+
+	// Server side encryption, AES256 or aws:kms with an optional key
+	Encryption string `json:"encryption"`
+	KMSKeyID   string `json:"kmsKeyID"`
+
+	// Canned ACL for every object, the bucket default when empty
+	ACL string `json:"acl"`
+
+	// Storage class of the uploaded version, storageClasses is keyed like cacheControl for assets that need another one
+	StorageClass   string            `json:"storageClass"`
+	StorageClasses map[string]string `json:"storageClasses"`
+
+	// Tags on every uploaded object and copy of one, eg: team, cost-center
+	ObjectTags map[string]string `json:"objectTags"`
+
+	// Ask before moving latest or deleting versions in the bucket, like for production
+	Protected bool `json:"protected"`
+
+	// Deployment environments selected with -env
+	Environments map[string]Environment `json:"environments"`
+	Environment  string                 `json:"-"`
+
+	// Buckets in other regions the version is copied to after publishing
+	Replicas []Environment `json:"replicas"`
+
+	// Asset policies
+	Symlinks      string `json:"symlinks"`
+	IncludeHidden bool   `json:"includeHidden"`
+	WarnAssetSize string `json:"warnAssetSize"`
+	MaxAssetSize  string `json:"maxAssetSize"`
+	EmptyFiles    string `json:"emptyFiles"`
+	Keys          string `json:"keys"`
+
+	// Publish files in the build directory the manifest does not list, all of them or the ones matching the globs
+	IncludeAll bool     `json:"includeAll"`
+	Include    []string `json:"include"`
+
+	// Assets matching these globs are neither uploaded nor listed in journey-urls.json
+	Exclude []string `json:"exclude"`
+
+	// Scripts and styles loaded first, in order, by path in the build or name in the manifest, read from the manifest when not set
+	Entrypoints []string `json:"entrypoints"`
+
+	// html/template rendered with the script and link tags of the version and published as index.html
+	HTMLTemplate string `json:"htmlTemplate"`
+
+	// Publish an import map of the journey, imported by its name unless importMapName is set
+	ImportMap     bool   `json:"importMap"`
+	ImportMapName string `json:"importMapName"`
+
+	// Formats the journey urls are published in besides journey-urls.json, eg: ["journey", "single-spa"]
+	OutputFormats []string  `json:"outputFormats"`
+	SingleSpa     SingleSpa `json:"singleSpa"`
+
+	// Most the files, or the entrypoints, may weigh before publishing fails, eg: entry js of 300KB gzipped
+	SizeBudgets []SizeBudget `json:"sizeBudgets"`
+
+	// Module Federation container, detected from a remoteEntry.js or mf-manifest.json in the build when not set
+	Federation Federation `json:"federation"`
+
+	// Other journeys in the bucket loaded before this one, by name and version range
+	Dependencies []Dependency `json:"dependencies"`
+
+	// Key layout, version puts every asset under the version while cas puts them under {name}/cas/ by the sha256 of their content
+	Layout string `json:"layout"`
+
+	// Chunks published once under {name}/static/ by the sha256 of their content and shared by every version that has them
+	SharedChunks []string `json:"sharedChunks"`
+
+	// Categories of the assets listed in journey-urls.json besides css and js, keyed by extension like .wasm
+	AssetTypes map[string]string `json:"assetTypes"`
+
+	// Source maps, uploaded like other assets unless private or skipped, optionally under their own prefix
+	SourceMaps      string `json:"sourceMaps"`
+	SourceMapPrefix string `json:"sourceMapPrefix"`
+
+	// Lock held while publishing or moving latest, in a DynamoDB table or advisory in the bucket, none unless set, and how long until it is stale
+	Lock      string `json:"lock"`
+	LockTable string `json:"lockTable"`
+	LockTTL   string `json:"lockTTL"`
+
+	// Multipart uploads of large assets
+	Multipart Multipart `json:"multipart"`
+
+	// Attach a sha256 to every object and check what was stored matches before counting an upload as done
+	Checksums bool `json:"checksums"`
+
+	// Headers, cacheControl is keyed by file name, extension like .js, or * for everything else
+	CacheControl map[string]string `json:"cacheControl"`
+
+	// Pre-compression of text assets, in place with a Content-Encoding or as .gz and .br variants
+	Compress         []string `json:"compress"`
+	CompressVariants bool     `json:"compressVariants"`
+
+	// Shell commands run before publishing and after publishing or setting latest
+	Hooks Hooks `json:"hooks"`
+
+	// Urls fetched through the cdn after publishing that must return 200, eg: {name}/{version}/journey-urls.json
+	SmokeTests []SmokeTest `json:"smokeTests"`
+
+	// Webhooks POSTed to after a successful publish or setLatest
+	Webhooks []Webhook `json:"webhooks" journey:"private"`
+
+	// Chat notifications after a successful publish, setLatest or rollback, eg: slack://hooks.slack.com/services/...
+	Chats []string `json:"notify" journey:"private"`
+
+	// journey-registry service published versions are registered with
+	Registry Registry `json:"registry" journey:"private"`
+
+	// CloudWatch embedded metrics or a Prometheus pushgateway the duration, size and failures of every publish are sent to
+	Metrics Metrics `json:"metrics"`
+
+	// Sign the urls in journey-urls.json for buckets that are not public
+	SignedUrls SignedUrls `json:"signedUrls"`
+
+	// CDN settings
+	DistributionID string `json:"distributionID"`
+
+	// Cdn whose cache -invalidate purges, when it is not the CloudFront distribution
+	Purge Purge `json:"purge"`
+
+	// Warn before publishing when the distribution of the cdn does not serve the journey from the bucket
+	CheckDistribution bool `json:"checkDistribution"`
+
+	// Command line options
+	VerifyExisting bool
+	Metadata       map[string]string
+	DryRun         bool
+	Concurrency    int
+	SkipSemver     bool
+	Progress       bool
+	Retries        int
+	Report         string
+	SkipRegistry   bool
+	Force          bool
+	SkipSmokeTests bool
+	SkipHooks      bool
+	Git            *Git
+	UploadTimeout  time.Duration
+	MaxBandwidth   int64
+
+	// Uploads shared with the other journeys of a workspace
+	Budget *Budget
+
+	// journey.json and the asset manifest when they are not read from their files, like from stdin, or journey.json
+	// converted from yaml or toml
+	JourneyContent  []byte `json:"-"`
+	ManifestContent []byte `json:"-"`
+
+	// shared by every upload so they stay under MaxBandwidth together
+	limiter *rateLimiter
+
+	// keys of the shared chunks, hashed once per publish
+	sharedKeys map[string]string
+
+	// AWS credentials, a named profile and a role to assume with it
+	Profile         string
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string
 }
 
 // Validate Validate the journey config is correct
 func (j *Journey) Validate(validate *validator.Validate) error {
-	return validate.Struct(j)
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		if name := strings.Split(f.Tag.Get("json"), ",")[0]; len(name) > 0 && name != "-" {
+			return name
+		}
+		return f.Name
+	})
+	if err := validate.Struct(j); err != nil {
+		return j.describeValidation(err)
+	}
+
+	cdn, err := NormalizeCDNDomain(j.CDNDomain)
+	if err != nil {
+		return err
+	}
+	j.CDNDomain = cdn
+
+	if err := validateVersion(j.Version, j.SkipSemver); err != nil {
+		return err
+	}
+
+	if err := validateSymlinkPolicy(j.Symlinks); err != nil {
+		return err
+	}
+
+	if err := validateEmptyFilePolicy(j.EmptyFiles); err != nil {
+		return err
+	}
+
+	if err := validateKeyPolicy(j.Keys); err != nil {
+		return err
+	}
+
+	if err := validateEncryption(j.Encryption, j.KMSKeyID); err != nil {
+		return err
+	}
+
+	if err := validateLock(j.Lock, j.LockTable, j.LockTTL); err != nil {
+		return err
+	}
+
+	if err := j.Purge.validate(); err != nil {
+		return err
+	}
+
+	if err := j.Metrics.validate(); err != nil {
+		return err
+	}
+
+	if (j.Storage == BackendWebDAV || j.Storage == BackendSFTP) && j.Checksums {
+		return fmt.Errorf("Checksums are kept in object metadata, which storage backend %v does not have", j.Storage)
+	}
+
+	if err := j.validateEndpoints(); err != nil {
+		return err
+	}
+
+	if err := validateReplicas(j.Replicas); err != nil {
+		return err
+	}
+
+	if err := validateNotify(j.Chats); err != nil {
+		return err
+	}
+
+	if err := validateACL(j.ACL); err != nil {
+		return err
+	}
+
+	if err := validateObjectTags(j.ObjectTags); err != nil {
+		return err
+	}
+
+	if err := j.validateStorageClasses(); err != nil {
+		return err
+	}
+
+	if err := validateSourceMapPolicy(j.SourceMaps); err != nil {
+		return err
+	}
+
+	if err := validateManifestFormat(j.ManifestFormat); err != nil {
+		return err
+	}
+
+	if err := validateCompress(j.Compress, j.CompressVariants); err != nil {
+		return err
+	}
+
+	if err := validateAssetTypes(j.AssetTypes); err != nil {
+		return err
+	}
+
+	if err := validateDependencies(j.Name, j.Dependencies); err != nil {
+		return err
+	}
+
+	if err := validatePrefix(j.Prefix); err != nil {
+		return err
+	}
+
+	if err := j.Paths.validate(j.Name, j.Environment); err != nil {
+		return err
+	}
+
+	if err := j.Federation.validate(); err != nil {
+		return err
+	}
+
+	if err := validateOutputFormats(j.OutputFormats); err != nil {
+		return err
+	}
+
+	if err := validateSizeBudgets(j.SizeBudgets); err != nil {
+		return err
+	}
+
+	if err := validateGlobs("Include", j.Include); err != nil {
+		return err
+	}
+
+	if err := validateGlobs("Exclude", j.Exclude); err != nil {
+		return err
+	}
+
+	if err := validateGlobs("SharedChunks", j.SharedChunks); err != nil {
+		return err
+	}
+
+	if err := validateLayout(j.Layout); err != nil {
+		return err
+	}
+
+	if err := validateSmokeTests(j.SmokeTests); err != nil {
+		return err
+	}
+
+	if err := j.SignedUrls.validate(); err != nil {
+		return err
+	}
+
+	if err := j.Multipart.validate(); err != nil {
+		return err
+	}
+
+	return j.validateAssetSizes()
+}
+
+// describeValidation Name the missing fields the way they are written in journey.json instead of the go fields
+func (j *Journey) describeValidation(err error) error {
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	var missing []string
+	for _, e := range errs {
+		if e.Tag() != "required" {
+			return fmt.Errorf("%v: %v is not valid", j.configName(), strconv.Quote(e.Field()))
+		}
+		missing = append(missing, strconv.Quote(e.Field()))
+	}
+
+	it := "it"
+	if len(missing) > 1 {
+		it = "them"
+	}
+
+	return fmt.Errorf("%v is missing %v, set %v in the file, with -env or with flags", j.configName(), strings.Join(missing, ", "), it)
+}
+
+// StorageOptions The settings applied to every object written to storage
+func (j *Journey) StorageOptions() StorageOptions {
+	// the part size is validated when the journey is loaded
+	partSize, _ := ParseSize(j.Multipart.PartSize)
+
+	return StorageOptions{
+		Encryption:        j.Encryption,
+		KMSKeyID:          j.KMSKeyID,
+		ACL:               j.ACL,
+		Tags:              j.ObjectTags,
+		Retries:           j.Retries,
+		Endpoint:          j.Endpoint,
+		PathStyle:         j.PathStyle,
+		User:              j.User,
+		Password:          j.Password,
+		PartSize:          partSize,
+		PartConcurrency:   j.Multipart.Concurrency,
+		LeavePartsOnError: j.Multipart.LeavePartsOnError,
+	}
 }
 
-// GetAssetPath the abs path to the asset
+// GetAssetPath the path to the asset in the build directory, for the OS the build runs on
 func (j *Journey) GetAssetPath(path string) string {
-	return j.Build + path
+	return filepath.Join(j.Build, filepath.FromSlash(toSlash(path)))
 }
 
 // GetAssetKey Get the key to use in s3 bucket
 func (j *Journey) GetAssetKey(path string) string {
-	return j.Name + "/" + j.Version + "/" + path
+	if j.Keys == KeysNormalize {
+		path = normalizeKey(path)
+	}
+
+	return j.GetJourneyKey(j.Version + "/" + toSlash(path))
+}
+
+// GetJourneyKey Get the key of a path inside the journey, under the prefix when there is one
+func (j *Journey) GetJourneyKey(path string) string {
+	prefix := strings.Trim(j.Prefix, "/")
+	if len(prefix) > 0 {
+		prefix += "/"
+	}
+
+	// the layout was validated, so the template renders
+	root, err := j.Paths.root(j.Name, j.Environment)
+	if err != nil {
+		root = j.Name
+	}
+
+	return prefix + root + "/" + path
+}
+
+// VersionExistsError The version has already been published to the bucket
+type VersionExistsError struct {
+	Name    string
+	Version string
+}
+
+func (e *VersionExistsError) Error() string {
+	return fmt.Sprintf("Version %v/%v already exists, publishing failed", e.Name, e.Version)
+}
+
+// GetVersionKey Get the key of a file in any version of the journey
+func (j *Journey) GetVersionKey(version string, file string) string {
+	return j.GetJourneyKey(version + "/" + file)
+}
+
+// reservedVersion Check if the version is a name the cli keeps for itself, latest and the shared and cas prefixes
+func reservedVersion(version string) bool {
+	return version == Latest || version == SharedPrefix || version == CASPrefix
 }
 
 // ValidateVersionNotUsed Validate that the version is not already in use, we dont want to publish over something
-func (j *Journey) ValidateVersionNotUsed(sess *session.Session) (bool, error) {
+func (j *Journey) ValidateVersionNotUsed(ctx context.Context, store Storage) (bool, error) {
+
+	if reservedVersion(j.Version) {
+		return false, fmt.Errorf("Version %v is a reserved version. Please update and try again", j.Version)
+	}
+
+	_, err := store.Head(ctx, j.GetAssetKey(JourneyFile))
+	switch {
+	case err == ErrNotFound:
+		return true, nil
+	case err != nil:
+		// access denied or a network failure says nothing about the version, so do not publish over it
+		return false, fmt.Errorf("Unable to check if version %v/%v is already published: %v", j.Name, j.Version, err)
+	}
+
+	return false, &VersionExistsError{Name: j.Name, Version: j.Version}
+}
+
+// Publish Publish the assets using the journey configuration, returns what was uploaded.
+// There is no report for a dry run or when an existing version was verified
+func (j *Journey) Publish(ctx context.Context, assets map[string]string, store Storage) (*Report, error) {
+	plan, err := j.PlanPublish(assets)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.CheckSizeBudgets(plan); err != nil {
+		return nil, err
+	}
+
+	if j.DryRun {
+		Log.Infof("Dry run, these %v files would be uploaded to %v:\n%v", len(plan.Uploads), j.Bucket, plan)
+		return nil, nil
+	}
 
-	if j.Version == "latest" {
-		return true, fmt.Errorf("Version %v is a reserved version. Please update and try again", j.Version)
+	// check to make sure a directory in S3 does not exist with the Version
+	ok, err := j.ValidateVersionNotUsed(ctx, store)
+	_, exists := err.(*VersionExistsError)
+	switch {
+	case ok:
+		Log.Infof("Version %v/%v is NOT being used already", j.Name, j.Version)
+	case exists && j.VerifyExisting:
+		return nil, j.VerifyPublished(ctx, plan, store)
+	case exists && j.Force:
+		Log.Warnf("Version %v/%v is already published and will be overwritten", j.Name, j.Version)
+	default:
+		return nil, err
 	}
 
-	svc := s3.New(sess)
-	input := &s3.HeadObjectInput{
-		Bucket: aws.String(j.Bucket),
-		Key:    aws.String(j.Name + "/" + j.Version + "/journey.json"),
+	if err := j.CheckDependencies(ctx, store); err != nil {
+		return nil, err
+	}
+
+	uploads, skipped, err := j.skipPublishedShared(ctx, store, plan.Uploads)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := svc.HeadObject(input)
+	// deleting a failed overwrite would delete the version that was there before
+	report, err := j.uploadPlan(ctx, store, uploads, skipped, ok)
 	if err != nil {
-		// I know we are returning ok, but if no item is found we can assume the version does not exist
-		return true, err
+		return report, err
 	}
 
-	return false, fmt.Errorf("Version %v/%v already exists, publishing failed", j.Name, j.Version)
+	if err := j.register(ctx, plan.Urls); err != nil {
+		return report, err
+	}
+
+	return report, j.recordHistory(ctx, store, HistoryEntry{Action: ActionPublish, Version: j.Version})
 }
 
-// Publish Publish the assets using the journey configuration
-func (j *Journey) Publish(assets map[string]string, awsConfig *aws.Config) error {
-	sess, err := session.NewSession(awsConfig)
+// Sync Publish the assets to a version that may already exist, skipping files whose content is already there.
+// There is no report for a dry run
+func (j *Journey) Sync(ctx context.Context, assets map[string]string, store Storage) (*Report, error) {
+	plan, err := j.PlanPublish(assets)
 	if err != nil {
-		log.Println("Error creating AWS session ", err)
-		return err
+		return nil, err
+	}
+	if err := j.CheckSizeBudgets(plan); err != nil {
+		return nil, err
 	}
 
-	// check to make sure a directory in S3 does not exist with the Version
-	if ok, err := j.ValidateVersionNotUsed(sess); !ok {
-		return err
+	if reservedVersion(j.Version) {
+		return nil, fmt.Errorf("Version %v is a reserved version. Please update and try again", j.Version)
+	}
+
+	if err := j.CheckDependencies(ctx, store); err != nil {
+		return nil, err
 	}
-	log.Printf("Version %v/%v is NOT being used already", j.Name, j.Version)
 
-	// Create an uploader with the session and default options
-	uploader := s3manager.NewUploader(sess)
+	local, err := j.localChecksums(plan)
+	if err != nil {
+		return nil, err
+	}
 
-	urls := j.BuildJourneyUrls(assets)
+	prefix := j.GetAssetKey("")
+	objects, err := listVersion(ctx, store, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the objects of %v/%v: %v", j.Name, j.Version, err)
+	}
+	stored, err := j.objectChecksums(ctx, store, prefix, objects)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf("Getting ready to upload %v files...", len(assets)+2)
-	var wg sync.WaitGroup
-	wg.Add(len(assets) + 3)
+	var changed []*Upload
+	var skipped []*Upload
+	for _, u := range plan.Uploads {
+		key := strings.TrimPrefix(u.Key, prefix)
+		if sum, ok := stored[key]; ok && sum == local[key] {
+			Log.Debugf("Key: %v, is unchanged and will not be uploaded", u.Key)
+			skipped = append(skipped, u)
+			continue
+		}
+		changed = append(changed, u)
+	}
 
-	for _, v := range assets {
-		go uploadToS3(j.Bucket, j.GetAssetPath(v), j.GetAssetKey(v), uploader, &wg)
+	// shared chunks are not listed with the version, they are unchanged when they exist at all
+	changed, published, err := j.skipPublishedShared(ctx, store, changed)
+	if err != nil {
+		return nil, err
+	}
+	skipped = append(skipped, published...)
+	Log.Infof("%v of %v files are unchanged in %v/%v", len(plan.Uploads)-len(changed), len(plan.Uploads), j.Name, j.Version)
+
+	if j.DryRun {
+		Log.Infof("Dry run, these %v files would be uploaded to %v:\n%v", len(changed), j.Bucket, &Plan{Uploads: changed, Urls: plan.Urls, UrlsFile: plan.UrlsFile})
+		return nil, nil
+	}
+
+	if len(changed) <= 0 {
+		return j.buildReport(nil, skipped, nil, 0)
+	}
+
+	// the version may already be live, so a failed sync must not delete anything
+	return j.uploadPlan(ctx, store, changed, skipped, false)
+}
+
+// uploadPlan Upload the planned files with a pool of workers, optionally deleting what was uploaded if any upload fails.
+// Skipped files are only counted in the progress summary and the report
+func (j *Journey) uploadPlan(ctx context.Context, store Storage, uploads []*Upload, skipped []*Upload, cleanupOnFailure bool) (*Report, error) {
+	concurrency := j.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	// journey.json marks the version as published, so it and the journey urls only go up once every asset made it
+	files, markers := j.splitPlan(uploads)
+
+	Log.Infof("Getting ready to upload %v files, %v at a time...", len(uploads), concurrency)
+	start := time.Now()
+
+	var progress *Progress
+	if j.Progress {
+		progress = NewProgress(Log.out, uploads)
+		progress.Skipped(skipped)
+		progress.Start()
+	}
+
+	results := uploadAll(ctx, store, files, concurrency, progress)
+	uploaded, failed := splitResults(results)
+	if len(failed) == 0 {
+		results = append(results, uploadAll(ctx, store, markers, 1, progress)...)
+		uploaded, failed = splitResults(results)
+	}
+
+	if progress != nil {
+		progress.Stop()
+		Log.Infof("Upload summary:\n%v", progress.Summary())
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(uploaded)
+		for i := range results {
+			results[i].URL = j.CDNDomain + escapeKey(results[i].Key)
+		}
+		e := &UploadError{Failed: failed, Uploaded: uploaded, Results: results}
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			e.TimedOut = true
+		case context.Canceled:
+			e.Interrupted = true
+		}
+		if cleanupOnFailure {
+			e.CleanedUp = j.cleanup(store, uploaded)
+		}
+
+		// the report of a failed publish has every file that was attempted, each with its error
+		attempted := make(map[string]bool, len(results))
+		for _, r := range results {
+			attempted[r.Key] = true
+		}
+		var tried []*Upload
+		for _, u := range uploads {
+			if attempted[u.Key] {
+				tried = append(tried, u)
+			}
+		}
+		report, err := j.buildReport(tried, skipped, failed, time.Since(start))
+		if err != nil {
+			return nil, e
+		}
+		if len(j.Report) > 0 {
+			if err := j.writeReport(report); err != nil {
+				Log.Errorf("%v", err)
+			}
+		}
+
+		return report, e
+	}
+
+	var total int64
+	for _, u := range uploads {
+		total += u.Size
+	}
+	duration := time.Since(start)
+	url := j.CDNDomain + j.GetAssetKey(j.UrlsFile())
+
+	Log.Event(fmt.Sprintf("Published %v/%v, %v files, %v in %v: %v", j.Name, j.Version, len(uploads), FormatSize(total), duration, url), Fields{
+		"name":     j.Name,
+		"version":  j.Version,
+		"files":    len(uploads),
+		"bytes":    total,
+		"duration": duration.Seconds(),
+		"url":      url,
+	})
+
+	report, err := j.buildReport(uploads, skipped, nil, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(j.Report) > 0 {
+		return report, j.writeReport(report)
+	}
+
+	return report, nil
+}
+
+// cleanup Delete what a failed publish already uploaded so the version can be published again,
+// this runs after an interrupt so it does not use the publish context
+func (j *Journey) cleanup(store Storage, keys []string) bool {
+	if len(keys) <= 0 {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	// shared chunks may already be used by another version being published
+	var version []string
+	for _, key := range keys {
+		if !j.isSharedKey(key) {
+			version = append(version, key)
+		}
+	}
+	if len(version) <= 0 {
+		return true
+	}
+
+	Log.Infof("Deleting the %v files already uploaded for %v/%v", len(version), j.Name, j.Version)
+	if err := store.Delete(ctx, version...); err != nil {
+		Log.Errorf("Unable to delete the partially published %v/%v: %v", j.Name, j.Version, err)
+		return false
+	}
+
+	return true
+}
+
+// uploadAll Upload with a pool of workers, returning the result of every upload in the order they finished
+func uploadAll(ctx context.Context, store Storage, uploads []*Upload, concurrency int, progress *Progress) []UploadResult {
+	jobs := make(chan *Upload)
+	results := make(chan UploadResult, len(uploads))
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for u := range jobs {
+				// once interrupted, drain the remaining jobs without starting new uploads
+				err := ctx.Err()
+				if err == nil {
+					err = u.budget.acquire(ctx)
+				}
+				if err == nil {
+					start := time.Now()
+					err = upload(ctx, store, u)
+					u.Duration = time.Since(start)
+					u.budget.release()
+				}
+
+				if progress != nil {
+					progress.Done(u, err)
+				}
+				results <- UploadResult{Key: u.Key, Size: u.Size, Duration: u.Duration, Err: err}
+			}
+		}()
 	}
 
-	// make sure to put the journey.json, and asset-manifest.json file into {bucket}/{name}/{version}/
-	go uploadToS3(j.Bucket, j.Manifest, j.GetAssetKey("asset-manifest.json"), uploader, &wg)
-	go uploadToS3(j.Bucket, j.JourneyPath, j.GetAssetKey("journey.json"), uploader, &wg)
-	go urls.Publish(j, uploader, &wg)
-	wg.Wait()
+	go func() {
+		for _, u := range uploads {
+			jobs <- u
+		}
+		close(jobs)
+	}()
+
+	var all []UploadResult
+	for range uploads {
+		r := <-results
+		if r.Err != nil {
+			Log.Errorf("Key: %v, failed to upload: %v", r.Key, r.Err)
+		}
+		all = append(all, r)
+	}
+
+	return all
+}
+
+// splitResults The keys that were uploaded and the ones that failed
+func splitResults(results []UploadResult) ([]string, map[string]error) {
+	failed := make(map[string]error)
+	var uploaded []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed[r.Key] = r.Err
+			continue
+		}
+		uploaded = append(uploaded, r.Key)
+	}
 
-	return nil
+	return uploaded, failed
 }
 
 // BuildJourneyUrls Build the Journey Urls struct to have a list of css and js objects
-func (j *Journey) BuildJourneyUrls(assets map[string]string) *Urls {
+func (j *Journey) BuildJourneyUrls(assets map[string]string) (*Urls, error) {
 	var urls Urls
 	var css []CSS
 	var js []JS
+	var others []Asset
+	var preload []Preload
+
+	order, err := j.entrypointOrder(assets)
+	if err != nil {
+		return nil, err
+	}
+	urlOrder := make(map[string]int, len(order))
 
 	for _, v := range assets {
 		// URL structure https://changeme.cloudfront.net/{j.Name}/{j.Version}/path
-		url := j.CDNDomain + j.GetAssetKey(v)
+		key, err := j.assetKey(v)
+		if err != nil {
+			return nil, err
+		}
+		url := j.CDNDomain + escapeKey(key)
+		i, entry := order[v]
+		if entry {
+			urlOrder[url] = i
+		}
 
 		switch ext := filepath.Ext(v); ext {
 		case ".css":
-			css = append(css, CSS{URL: url})
+			integrity, err := fileIntegrity(j.GetAssetPath(v))
+			if err != nil {
+				return nil, fmt.Errorf("Unable to compute the integrity of %v: %v", v, err)
+			}
+			css = append(css, CSS{URL: url, Integrity: integrity, Entry: entry})
+			if entry {
+				preload = append(preload, Preload{URL: url, As: "style"})
+			}
 		case ".js":
-			js = append(js, JS{URL: url, RootID: j.RootID})
+			integrity, err := fileIntegrity(j.GetAssetPath(v))
+			if err != nil {
+				return nil, fmt.Errorf("Unable to compute the integrity of %v: %v", v, err)
+			}
+			js = append(js, JS{URL: url, RootID: j.RootID, Integrity: integrity, Entry: entry})
+			if entry {
+				preload = append(preload, Preload{URL: url, As: "script"})
+			}
+		case ".map":
+			// source maps are for error tracking tools, not for the host application
 		default:
-			log.Printf("Do not support adding %v files to journey-urls.json", ext)
+			if t := j.getAssetType(v); len(t) > 0 {
+				others = append(others, Asset{URL: url, Type: t})
+			} else {
+				Log.Debugf("Do not support adding %v files to journey-urls.json", ext)
+			}
 		}
 	}
 
+	// sort so the same build always produces the same journey-urls.json
+	sort.Slice(css, func(a, b int) bool { return entrypointLess(urlOrder, css[a].URL, css[b].URL) })
+	sort.Slice(js, func(a, b int) bool { return entrypointLess(urlOrder, js[a].URL, js[b].URL) })
+	sort.Slice(others, func(a, b int) bool { return others[a].URL < others[b].URL })
+	sort.Slice(preload, func(a, b int) bool { return entrypointLess(urlOrder, preload[a].URL, preload[b].URL) })
+
 	urls.CSS = css
 	urls.JS = js
+	urls.Assets = others
+	urls.Preload = preload
+	if !j.Git.Empty() {
+		urls.Git = j.Git
+	}
+	urls.Dependencies = j.Dependencies
+	urls.Federation, err = j.buildFederation(assets)
+	if err != nil {
+		return nil, err
+	}
 
-	return &urls
+	return &urls, nil
+}
+
+// getCacheControl Get the Cache-Control header for the key, matching the file name first, then the extension, then *
+func (j *Journey) getCacheControl(key string) string {
+	if v, ok := j.CacheControl[filepath.Base(key)]; ok {
+		return v
+	}
+	if v, ok := j.CacheControl[filepath.Ext(key)]; ok {
+		return v
+	}
+
+	return j.CacheControl["*"]
+}
+
+// uploadOptions Build the headers for an upload to key
+func (j *Journey) uploadOptions(key string, contentType string) UploadOptions {
+	return UploadOptions{
+		ContentType:  contentType,
+		CacheControl: j.getCacheControl(key),
+	}
 }
 
 // getContentType Get the content type of a file path
@@ -179,34 +927,113 @@ func getContentType(path string) string {
 	return mimeType
 }
 
-// uploadToS3 Take a file path and key and upload to S3
-func uploadToS3(bucket string, path string, key string, uploader *s3manager.Uploader, wg *sync.WaitGroup) (*s3manager.UploadOutput, error) {
-	defer wg.Done()
-	log.Printf("Starting to upload %v, at this path: %v, to this bucket: %v", key, path, bucket)
+// DefaultConcurrency How many files are uploaded at the same time unless configured otherwise
+const DefaultConcurrency = 10
 
-	if len(path) <= 0 {
-		log.Printf("Key: %v, does not have a path and will not be uploaded", key)
-		return nil, nil
+// cleanupTimeout How long deleting a partial publish may take
+const cleanupTimeout = 2 * time.Minute
+
+// UploadResult The outcome of uploading a single file, Err is set when it failed
+type UploadResult struct {
+	Key      string
+	URL      string
+	Size     int64
+	Duration time.Duration
+	Err      error
+}
+
+// UploadError Returned by publish when one or more files failed to upload or the publish was interrupted.
+// Results has every file that was attempted, Failed and Uploaded split them by key
+type UploadError struct {
+	Failed      map[string]error
+	Uploaded    []string
+	Results     []UploadResult
+	Interrupted bool
+	TimedOut    bool
+	CleanedUp   bool
+}
+
+func (e *UploadError) Error() string {
+	keys := make([]string, 0, len(e.Failed))
+	for k := range e.Failed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var msg string
+	if e.Interrupted {
+		msg = fmt.Sprintf("Publishing was interrupted, %v files were uploaded and %v were not.", len(e.Uploaded), len(keys))
+	} else if e.TimedOut {
+		msg = fmt.Sprintf("Publishing timed out, %v files were uploaded and %v were not.", len(e.Uploaded), len(keys))
+	} else {
+		msg = fmt.Sprintf("Unable to upload %v files, publishing failed:", len(keys))
+		for _, k := range keys {
+			msg += fmt.Sprintf("\n%v: %v", k, e.Failed[k])
+		}
+	}
+
+	switch {
+	case len(e.Uploaded) <= 0:
+	case e.CleanedUp:
+		msg += fmt.Sprintf("\nThe %v files that were uploaded have been deleted again, the version can be published again.", len(e.Uploaded))
+	default:
+		msg += "\nThese files were uploaded and could not be deleted again:\n" + strings.Join(e.Uploaded, "\n")
+	}
+
+	return msg
+}
+
+// upload Take a planned upload and upload it to storage, verifying the checksums of what was stored when asked to
+func upload(ctx context.Context, store Storage, u *Upload) error {
+	if u.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.Timeout)
+		defer cancel()
+	}
+
+	if !u.Verify {
+		return put(ctx, store, u)
 	}
 
-	abs, err := filepath.Abs(path)
+	etag, sum, err := u.checksums()
 	if err != nil {
-		log.Printf("Key: %v, had an issue getting absolute file path and was not uploaded", key)
-		return nil, err
+		Log.Errorf("Key: %v, was unable to be read and will not be uploaded", u.Key)
+		return err
+	}
+	u.Metadata = map[string]string{ChecksumMetadata: sum}
+
+	if err := put(ctx, store, u); err != nil {
+		return err
+	}
+
+	return verifyUpload(ctx, store, u, etag, sum)
+}
+
+// put Upload the body or the file of the upload to storage
+func put(ctx context.Context, store Storage, u *Upload) error {
+	Log.Debugf("Starting to upload %v, at this path: %v", u.Key, u.Path)
+
+	if u.Body != nil {
+		return store.Upload(ctx, u.Key, throttle(ctx, bytes.NewReader(u.Body), u.limiter), u.UploadOptions)
+	}
+
+	if len(u.Path) <= 0 {
+		Log.Warnf("Key: %v, does not have a path and will not be uploaded", u.Key)
+		return nil
+	}
+
+	abs, err := filepath.Abs(u.Path)
+	if err != nil {
+		Log.Errorf("Key: %v, had an issue getting absolute file path and was not uploaded", u.Key)
+		return err
 	}
 
 	f, err := os.Open(abs)
 	if err != nil {
-		log.Printf("Key: %v, was unable to be opened and will not be uploaded", key)
-		return nil, err
+		Log.Errorf("Key: %v, was unable to be opened and will not be uploaded", u.Key)
+		return err
 	}
 	defer f.Close()
 
-	// Upload the file to S3.
-	return uploader.Upload(&s3manager.UploadInput{
-		Bucket:      aws.String(bucket),
-		Key:         aws.String(key),
-		Body:        f,
-		ContentType: aws.String(getContentType(abs)),
-	})
+	return store.Upload(ctx, u.Key, throttle(ctx, f, u.limiter), u.UploadOptions)
 }
@@ -0,0 +1,152 @@
+package journey
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestJourney A journey with a build of two assets in a temp dir, removed by the returned func
+func newTestJourney(t *testing.T, version string) (*Journey, func()) {
+	dir, err := ioutil.TempDir("", "journey-build")
+	if err != nil {
+		t.Fatalf("Unable to create a temp dir: %v", err)
+	}
+
+	files := map[string]string{
+		"app.js":              "console.log('checkout');",
+		"app.css":             "body { margin: 0; }",
+		"asset-manifest.json": `{"app.js": "app.js", "app.css": "app.css"}`,
+		"journey.json":        `{"name": "checkout"}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Unable to write %v: %v", name, err)
+		}
+	}
+
+	j := &Journey{
+		Name:        "checkout",
+		Version:     version,
+		RootID:      "checkout-root",
+		Build:       dir + string(filepath.Separator),
+		Manifest:    filepath.Join(dir, "asset-manifest.json"),
+		Bucket:      "portal",
+		JourneyPath: filepath.Join(dir, "journey.json"),
+		CDNDomain:   "https://cdn.example.com/",
+	}
+
+	return j, func() { os.RemoveAll(dir) }
+}
+
+// testAssets The asset manifest of the build made by newTestJourney
+func testAssets() map[string]string {
+	return map[string]string{"app.js": "app.js", "app.css": "app.css"}
+}
+
+// publishVersions Publish each version of the journey to the store, in order
+func publishVersions(t *testing.T, store Storage, versions ...string) {
+	for _, version := range versions {
+		j, cleanup := newTestJourney(t, version)
+		_, err := j.Publish(context.Background(), testAssets(), store)
+		cleanup()
+		if err != nil {
+			t.Fatalf("Publish(%v) failed: %v", version, err)
+		}
+	}
+}
+
+// storedKeys The keys in the store under the prefix, sorted
+func storedKeys(t *testing.T, store Storage, prefix string) []string {
+	objects, err := store.List(context.Background(), prefix)
+	if err != nil {
+		t.Fatalf("List(%v) failed: %v", prefix, err)
+	}
+
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		published []string
+		version   string
+		force     bool
+		dryRun    bool
+		wantErr   bool
+		wantKeys  bool
+	}{
+		{"new version", nil, "1.0.0", false, false, false, true},
+		{"dry run", nil, "1.0.0", false, true, false, false},
+		{"version exists", []string{"1.0.0"}, "1.0.0", false, false, true, true},
+		{"overwrite with force", []string{"1.0.0"}, "1.0.0", true, false, false, true},
+		{"reserved version", nil, Latest, false, false, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStorage("portal")
+			publishVersions(t, store, test.published...)
+
+			j, cleanup := newTestJourney(t, test.version)
+			defer cleanup()
+			j.Force = test.force
+			j.DryRun = test.dryRun
+
+			_, err := j.Publish(context.Background(), testAssets(), store)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Publish() = %v, want an error %v", err, test.wantErr)
+			}
+			if _, ok := err.(*VersionExistsError); test.wantErr && len(test.published) > 0 && !ok {
+				t.Errorf("Publish() = %T, want a *VersionExistsError", err)
+			}
+
+			keys := storedKeys(t, store, j.GetAssetKey(""))
+			if !test.wantKeys {
+				if len(keys) > 0 {
+					t.Errorf("Publish() uploaded %v, want nothing", keys)
+				}
+				return
+			}
+			want := []string{j.GetAssetKey("app.css"), j.GetAssetKey("app.js"), j.GetAssetKey("asset-manifest.json"), j.GetAssetKey(JourneyFile), j.GetAssetKey(JourneyUrlsFile)}
+			sort.Strings(want)
+			if !reflect.DeepEqual(keys, want) {
+				t.Errorf("Publish() uploaded %v, want %v", keys, want)
+			}
+		})
+	}
+}
+
+func TestPublishUrls(t *testing.T) {
+	store := NewMemoryStorage("portal")
+	publishVersions(t, store, "1.0.0")
+
+	j, cleanup := newTestJourney(t, "1.0.0")
+	defer cleanup()
+
+	body, err := store.Get(context.Background(), j.GetAssetKey(JourneyUrlsFile))
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	defer body.Close()
+
+	var urls Urls
+	if err := json.NewDecoder(body).Decode(&urls); err != nil {
+		t.Fatalf("Unable to parse the journey urls: %v", err)
+	}
+	want := "https://cdn.example.com/" + j.GetAssetKey("app.js")
+	if len(urls.JS) != 1 || urls.JS[0].URL != want || urls.JS[0].RootID != j.RootID {
+		t.Errorf("JS = %+v, want %v with root %v", urls.JS, want, j.RootID)
+	}
+}
@@ -0,0 +1,174 @@
+package journey
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Files the journey writes next to the assets of every version
+const (
+	JourneyFile     = "journey.json"
+	ManifestFile    = "asset-manifest.json"
+	JourneyUrlsFile = "journey-urls.json"
+	MetadataFile    = "metadata.json"
+)
+
+// Key policies for asset names that are not safe to use in a url as is
+const (
+	KeysEncode    = "encode"
+	KeysReject    = "reject"
+	KeysNormalize = "normalize"
+)
+
+// toSlash Use forward slashes in a path whatever the OS, build tools on Windows write manifests with backslashes
+func toSlash(p string) string {
+	return strings.Replace(p, `\`, "/", -1)
+}
+
+// normalizeAssetPath Turn a manifest value into a clean path relative to the build directory with forward slashes,
+// dropping the leading ./ or / some tools write
+func normalizeAssetPath(p string) (string, error) {
+	clean := path.Clean(strings.TrimLeft(toSlash(p), "/"))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("Asset %v is not a file inside the build directory", p)
+	}
+
+	return clean, nil
+}
+
+// NormalizeCDNDomain Parse the domain assets are served from into a url ending in exactly one slash, so keys can be appended to it,
+// https is assumed when there is no scheme and protocol relative urls like //cdn.example.com are kept
+func NormalizeCDNDomain(domain string) (string, error) {
+	d := strings.TrimSpace(domain)
+	if !strings.Contains(d, "://") && !strings.HasPrefix(d, "//") {
+		d = "https://" + d
+	}
+
+	u, err := url.Parse(d)
+	if err != nil {
+		return "", fmt.Errorf("CDN domain %v is not a url like https://cdn.example.com/: %v", domain, err)
+	}
+	if len(u.Host) <= 0 {
+		return "", fmt.Errorf("CDN domain %v does not have a host, use a url like https://cdn.example.com/", domain)
+	}
+	switch u.Scheme {
+	case "", "http", "https":
+	default:
+		return "", fmt.Errorf("CDN domain %v is not served over http or https", domain)
+	}
+	if len(u.RawQuery) > 0 || len(u.Fragment) > 0 || u.User != nil {
+		return "", fmt.Errorf("CDN domain %v can not have a query, fragment or user, keys are appended to it", domain)
+	}
+
+	p := strings.Trim(path.Clean("/"+u.EscapedPath()), "/")
+	if len(p) > 0 {
+		p += "/"
+	}
+	scheme := ""
+	if len(u.Scheme) > 0 {
+		scheme = u.Scheme + ":"
+	}
+
+	return scheme + "//" + u.Host + "/" + p, nil
+}
+
+// validatePrefix Validate the key prefix is a plain path that can not climb out of itself
+func validatePrefix(prefix string) error {
+	for _, part := range strings.Split(strings.Trim(prefix, "/"), "/") {
+		if part == "." || part == ".." || (len(part) <= 0 && len(prefix) > 0) {
+			return fmt.Errorf("Prefix %v is not valid, use a path like frontends or team/frontends", prefix)
+		}
+	}
+
+	return nil
+}
+
+// validateKeyPolicy Validate the key policy is one we know how to handle
+func validateKeyPolicy(policy string) error {
+	switch policy {
+	case "", KeysEncode, KeysReject, KeysNormalize:
+		return nil
+	default:
+		return fmt.Errorf("Key policy %v is not supported, use %v, %v or %v", policy, KeysEncode, KeysReject, KeysNormalize)
+	}
+}
+
+// isSafeKeyRune Check if r can be used in a key without being encoded in the url
+func isSafeKeyRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case strings.ContainsRune("/-_.~", r):
+		return true
+	}
+
+	return false
+}
+
+// isSafeKey Check if every character in the key is safe to use in a url
+func isSafeKey(key string) bool {
+	for _, r := range key {
+		if !isSafeKeyRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeKey Replace every character that is not safe in a url with a dash
+func normalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if isSafeKeyRune(r) {
+			return r
+		}
+		return '-'
+	}, key)
+}
+
+// checkKey Apply the key policy to the asset path
+func (j *Journey) checkKey(path string) error {
+	if j.Keys == KeysReject && !isSafeKey(path) {
+		return fmt.Errorf("Asset %v contains characters that are not safe in a url, rename it or change the key policy", path)
+	}
+
+	return nil
+}
+
+// escapeKey Percent encode each segment of the key so it can be used in a url
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// checkKeyCollisions Make sure no two different files for the version would be written to the same key
+func (j *Journey) checkKeyCollisions(assets map[string]string) error {
+	reserved := map[string]string{
+		j.GetAssetKey(JourneyFile):  "the journey config",
+		j.GetAssetKey(ManifestFile): "the asset manifest",
+		j.GetAssetKey(j.UrlsFile()): "the journey urls",
+		j.GetAssetKey(MetadataFile): "the version metadata",
+	}
+	paths := make(map[string]string, len(assets))
+
+	for k, v := range assets {
+		key := j.GetAssetKey(v)
+		if other, ok := reserved[key]; ok {
+			return fmt.Errorf("Manifest entry %v would be uploaded to %v, which is already used by %v", k, key, other)
+		}
+
+		// entries that point at the same file are fine, different files ending up on one key are not
+		if other, ok := paths[key]; ok && other != v {
+			return fmt.Errorf("Assets %v and %v would both be uploaded to %v", other, v, key)
+		}
+		paths[key] = v
+	}
+
+	return nil
+}
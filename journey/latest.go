@@ -0,0 +1,153 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Latest The reserved version that points at the version consumers should load
+const Latest = "latest"
+
+// LatestFile The object in the latest path that says which version latest points at, for loaders that only need the version
+const LatestFile = "latest.json"
+
+// ChannelFile The object in the path of a channel other than latest that says which version it points at
+const ChannelFile = "channel.json"
+
+// LatestInfo Which version latest, or another channel, points at, when it was moved there, by who and the version it
+// pointed at before
+type LatestInfo struct {
+	Version  string    `json:"version"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+}
+
+// GetLatestKey Get the key of a file in the latest path of the journey
+func (j *Journey) GetLatestKey(file string) string {
+	return j.GetChannelKey(Latest, file)
+}
+
+// SetLatest Copy the journey urls of the version to the latest path
+func (j *Journey) SetLatest(ctx context.Context, store Storage) error {
+	j.warnIfOlderThanLatest(ctx, store)
+
+	if err := j.pointChannel(ctx, store, Latest, j.Version); err != nil {
+		return err
+	}
+
+	return j.recordHistory(ctx, store, HistoryEntry{Action: ActionSetLatest, Version: j.Version})
+}
+
+// Rollback Point latest back at a version that was published before
+func (j *Journey) Rollback(ctx context.Context, version string, store Storage) error {
+	if len(version) <= 0 || reservedVersion(version) {
+		return fmt.Errorf("A published version is required to roll back to, got %q", version)
+	}
+
+	source := j.GetVersionKey(version, j.UrlsFile())
+	if _, err := store.Head(ctx, source); err != nil {
+		return fmt.Errorf("Unable to roll back to %v/%v, %v can not be found: %v", j.Name, version, source, err)
+	}
+
+	if err := j.pointChannel(ctx, store, Latest, version); err != nil {
+		return err
+	}
+
+	return j.recordHistory(ctx, store, HistoryEntry{Action: ActionRollback, Version: version})
+}
+
+// warnIfOlderThanLatest Warn when the version is older than the one latest points at now, going by the history
+func (j *Journey) warnIfOlderThanLatest(ctx context.Context, store Storage) {
+	history, err := j.GetHistory(ctx, store)
+	if err != nil {
+		return
+	}
+
+	current := ""
+	for _, e := range history {
+		if e.Action == ActionSetLatest || e.Action == ActionRollback || e.Action == ActionPromoteCanary {
+			current = e.Version
+		}
+	}
+	if len(current) <= 0 {
+		return
+	}
+
+	if c, err := CompareVersions(j.Version, current); err == nil && c < 0 {
+		Log.Warnf("%v/%v is older than %v which latest points at now", j.Name, j.Version, current)
+	}
+}
+
+// GetLatestInfo Get the latest.json of the journey, nil when latest was never set or was set before latest.json was written
+func (j *Journey) GetLatestInfo(ctx context.Context, store Storage) (*LatestInfo, error) {
+	return j.getPointer(ctx, store, Latest)
+}
+
+// pointerFile The object in the path of the channel that says which version it points at
+func pointerFile(channel string) string {
+	if channel == Latest {
+		return LatestFile
+	}
+
+	return ChannelFile
+}
+
+// getPointer Get the latest.json or channel.json of the channel, nil when the channel was never set or was set before
+// they were written
+func (j *Journey) getPointer(ctx context.Context, store Storage, channel string) (*LatestInfo, error) {
+	key := j.GetChannelKey(channel, pointerFile(channel))
+
+	body, err := store.Get(ctx, key)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+	defer body.Close()
+
+	var info LatestInfo
+	if err := json.NewDecoder(body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", key, err)
+	}
+
+	return &info, nil
+}
+
+// writePointer Upload the latest.json or channel.json saying the channel now points at the version
+func (j *Journey) writePointer(ctx context.Context, store Storage, channel string, version string, previous string) error {
+	info := LatestInfo{
+		Version:  version,
+		Time:     time.Now().UTC(),
+		Actor:    ciUser(),
+		Previous: previous,
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to parse the latest info into json")
+	}
+
+	key := j.GetChannelKey(channel, pointerFile(channel))
+	if err := store.Upload(ctx, key, bytes.NewReader(data), j.uploadOptions(key, "application/json")); err != nil {
+		return fmt.Errorf("Unable to upload %v: %v", key, err)
+	}
+
+	return nil
+}
+
+// InvalidateLatest Purge the cdn cache for the latest path so consumers stop getting stale files
+func (j *Journey) InvalidateLatest(ctx context.Context, sess *session.Session) error {
+	return j.InvalidateChannel(ctx, Latest, sess)
+}
+
+// InvalidateChannel Purge the cdn cache for the path of the channel
+func (j *Journey) InvalidateChannel(ctx context.Context, channel string, sess *session.Session) error {
+	return j.NewCDN(sess).Purge(ctx, channel)
+}
@@ -0,0 +1,111 @@
+package journey
+
+import (
+	"context"
+	"testing"
+)
+
+// latestVersion The version latest points at, empty when it was never set
+func latestVersion(t *testing.T, j *Journey, store Storage) string {
+	version, err := j.currentLatest(context.Background(), store)
+	if err != nil {
+		t.Fatalf("currentLatest() failed: %v", err)
+	}
+
+	return version
+}
+
+func TestSetLatest(t *testing.T) {
+	tests := []struct {
+		name         string
+		published    []string
+		version      string
+		wantErr      bool
+		wantLatest   string
+		wantPrevious string
+	}{
+		{"first latest", []string{"1.0.0"}, "1.0.0", false, "1.0.0", ""},
+		{"move latest", []string{"1.0.0", "1.1.0"}, "1.1.0", false, "1.1.0", "1.0.0"},
+		{"not published", []string{"1.0.0"}, "2.0.0", true, "1.0.0", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+			publishVersions(t, store, test.published...)
+
+			first, cleanup := newTestJourney(t, test.published[0])
+			defer cleanup()
+			if test.version != test.published[0] {
+				if err := first.SetLatest(ctx, store); err != nil {
+					t.Fatalf("SetLatest(%v) failed: %v", first.Version, err)
+				}
+			}
+
+			j, cleanup := newTestJourney(t, test.version)
+			defer cleanup()
+			if err := j.SetLatest(ctx, store); (err != nil) != test.wantErr {
+				t.Fatalf("SetLatest() = %v, want an error %v", err, test.wantErr)
+			}
+
+			if got := latestVersion(t, j, store); got != test.wantLatest {
+				t.Errorf("latest = %v, want %v", got, test.wantLatest)
+			}
+			if test.wantErr {
+				return
+			}
+
+			history, err := j.GetHistory(ctx, store)
+			if err != nil {
+				t.Fatalf("GetHistory() failed: %v", err)
+			}
+			if n := len(history); n <= 0 || history[n-1].Action != ActionSetLatest || history[n-1].Version != test.wantLatest {
+				t.Errorf("GetHistory() = %+v, want %v of %v last", history, ActionSetLatest, test.wantLatest)
+			}
+
+			info, err := j.GetLatestInfo(ctx, store)
+			if err != nil {
+				t.Fatalf("GetLatestInfo() failed: %v", err)
+			}
+			if info == nil || info.Version != test.wantLatest || info.Previous != test.wantPrevious {
+				t.Errorf("GetLatestInfo() = %+v, want version %v after %q", info, test.wantLatest, test.wantPrevious)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    string
+		wantErr    bool
+		wantLatest string
+	}{
+		{"published version", "1.0.0", false, "1.0.0"},
+		{"not published", "0.9.0", true, "1.1.0"},
+		{"reserved version", Latest, true, "1.1.0"},
+		{"no version", "", true, "1.1.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+			publishVersions(t, store, "1.0.0", "1.1.0")
+
+			j, cleanup := newTestJourney(t, "1.1.0")
+			defer cleanup()
+			if err := j.SetLatest(ctx, store); err != nil {
+				t.Fatalf("SetLatest() failed: %v", err)
+			}
+
+			if err := j.Rollback(ctx, test.version, store); (err != nil) != test.wantErr {
+				t.Fatalf("Rollback() = %v, want an error %v", err, test.wantErr)
+			}
+			if got := latestVersion(t, j, store); got != test.wantLatest {
+				t.Errorf("latest = %v, want %v", got, test.wantLatest)
+			}
+		})
+	}
+}
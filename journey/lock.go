@@ -0,0 +1,443 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Lock backends, dynamodb uses a conditional put on a table while s3 keeps an advisory lock object next to the versions.
+// Journeys are not locked unless one is set
+const (
+	LockS3       = "s3"
+	LockDynamoDB = "dynamodb"
+	LockNone     = "none"
+)
+
+// LockFile The lock object kept at {name}/.lock
+const LockFile = ".lock"
+
+// defaultLockTTL How long a lock is held before it is considered stale unless configured otherwise
+const defaultLockTTL = 30 * time.Minute
+
+// unlockTimeout How long releasing or renewing a lock may take
+const unlockTimeout = 30 * time.Second
+
+// Lock Who is changing the journey, only one publish, sync, setLatest or rollback runs at a time
+type Lock struct {
+	Owner    string    `json:"owner"`
+	Command  string    `json:"command"`
+	Version  string    `json:"version"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+}
+
+// LockedError Returned when someone else holds the lock
+type LockedError struct {
+	Name string
+	Lock *Lock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%v is locked by %v running %v for %v since %v, the lock expires at %v. If that job is gone, pass -force-unlock",
+		e.Name, e.Lock.Owner, e.Lock.Command, e.Lock.Version, e.Lock.Acquired.Format(time.RFC3339), e.Lock.Expires.Format(time.RFC3339))
+}
+
+// Locker Where the lock is kept
+type Locker interface {
+	Acquire(ctx context.Context, l *Lock) error
+	Renew(ctx context.Context, l *Lock) error
+	Release(ctx context.Context, l *Lock) error
+	ForceRelease(ctx context.Context) error
+}
+
+// validateLock Validate the lock settings
+func validateLock(backend string, table string, ttl string) error {
+	switch backend {
+	case "", LockS3, LockNone:
+	case LockDynamoDB:
+		if len(table) <= 0 {
+			return fmt.Errorf("The %v lock needs a lockTable", LockDynamoDB)
+		}
+	default:
+		return fmt.Errorf("Lock %v is not supported, use %v, %v or %v", backend, LockS3, LockDynamoDB, LockNone)
+	}
+
+	if len(ttl) > 0 {
+		if _, err := time.ParseDuration(ttl); err != nil {
+			return fmt.Errorf("lockTTL %v is not a duration like 30m: %v", ttl, err)
+		}
+	}
+
+	return nil
+}
+
+// GetLockKey Get the key of the lock object of the journey
+func (j *Journey) GetLockKey() string {
+	return j.GetJourneyKey(LockFile)
+}
+
+// NewLocker Create the locker for the lock backend, nil when locking is turned off
+func (j *Journey) NewLocker(store Storage, sess *session.Session) Locker {
+	switch j.Lock {
+	case LockS3:
+		return &s3Locker{store: store, key: j.GetLockKey()}
+	case LockDynamoDB:
+		return &dynamoLocker{svc: dynamodb.New(sess), table: j.LockTable, id: j.Bucket + "/" + j.GetLockKey()}
+	default:
+		return nil
+	}
+}
+
+// lockOwner Identify this process in the lock
+func lockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	b := make([]byte, 4)
+	rand.Read(b)
+
+	return fmt.Sprintf("%v:%v:%v", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// lockTTL How long a lock is held before it is considered stale
+func (j *Journey) lockTTL() time.Duration {
+	if len(j.LockTTL) > 0 {
+		// validated when the journey is loaded
+		if ttl, err := time.ParseDuration(j.LockTTL); err == nil {
+			return ttl
+		}
+	}
+
+	return defaultLockTTL
+}
+
+// AcquireLock Take the lock for the command, returns the lock to release once the command is done
+func (j *Journey) AcquireLock(ctx context.Context, locker Locker, command string) (*Lock, error) {
+	if locker == nil || j.DryRun {
+		return nil, nil
+	}
+
+	ttl := j.lockTTL()
+	now := time.Now().UTC()
+	l := &Lock{Owner: lockOwner(), Command: command, Version: j.Version, Acquired: now, Expires: now.Add(ttl)}
+
+	err := locker.Acquire(ctx, l)
+	if e, ok := err.(*LockedError); ok {
+		e.Name = j.Name
+		return nil, e
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to lock %v: %v", j.Name, err)
+	}
+
+	Log.Debugf("Locked %v as %v until %v", j.Name, l.Owner, l.Expires.Format(time.RFC3339))
+	return l, nil
+}
+
+// KeepLock Renew the lock every third of its ttl while the command runs, so a command that takes longer than the ttl
+// keeps it. When it can not be renewed the returned context is cancelled, and the returned func, which stops renewing,
+// says why
+func (j *Journey) KeepLock(ctx context.Context, locker Locker, l *Lock) (context.Context, func() error) {
+	if locker == nil || l == nil {
+		return ctx, func() error { return nil }
+	}
+
+	ttl := j.lockTTL()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	lost := make(chan error, 1)
+
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				lost <- nil
+				return
+			case <-ctx.Done():
+				lost <- nil
+				return
+			case <-ticker.C:
+			}
+
+			renewed := *l
+			renewed.Expires = time.Now().UTC().Add(ttl)
+			renewCtx, cancelRenew := context.WithTimeout(ctx, unlockTimeout)
+			err := locker.Renew(renewCtx, &renewed)
+			cancelRenew()
+			if e, ok := err.(*LockedError); ok {
+				e.Name = j.Name
+			}
+			if err != nil {
+				lost <- fmt.Errorf("Lost the lock on %v, stopped %v: %v", j.Name, l.Command, err)
+				cancel()
+				return
+			}
+
+			l.Expires = renewed.Expires
+			Log.Debugf("Renewed the lock on %v until %v", j.Name, l.Expires.Format(time.RFC3339))
+		}
+	}()
+
+	return ctx, func() error {
+		close(done)
+		err := <-lost
+		cancel()
+		return err
+	}
+}
+
+// ReleaseLock Release the lock, this runs after an interrupt so it does not use the command context
+func (j *Journey) ReleaseLock(locker Locker, l *Lock) {
+	if locker == nil || l == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancel()
+
+	if err := locker.Release(ctx, l); err != nil {
+		Log.Warnf("Unable to release the lock on %v, it expires at %v: %v", j.Name, l.Expires.Format(time.RFC3339), err)
+	}
+}
+
+// ForceUnlock Break the lock no matter who holds it
+func (j *Journey) ForceUnlock(ctx context.Context, locker Locker) error {
+	if locker == nil {
+		return nil
+	}
+
+	if err := locker.ForceRelease(ctx); err != nil {
+		return fmt.Errorf("Unable to remove the lock on %v: %v", j.Name, err)
+	}
+	Log.Warnf("Removed the lock on %v", j.Name)
+
+	return nil
+}
+
+// s3Locker An advisory lock object in the bucket. Storage has no conditional put, so two jobs can both find no lock,
+// both write theirs and both read back their own before the other lands. It stops a job started while another
+// is running, it does not exclude jobs started at the same time, that needs the dynamodb lock
+type s3Locker struct {
+	store Storage
+	key   string
+}
+
+// current Get the lock in the bucket, nil when there is none
+func (s *s3Locker) current(ctx context.Context) (*Lock, error) {
+	body, err := s.store.Get(ctx, s.key)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var l Lock
+	if err := json.NewDecoder(body).Decode(&l); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", s.key, err)
+	}
+
+	return &l, nil
+}
+
+// Acquire Write the lock unless someone else holds one that has not expired
+func (s *s3Locker) Acquire(ctx context.Context, l *Lock) error {
+	held, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	if held != nil {
+		if time.Now().Before(held.Expires) {
+			return &LockedError{Lock: held}
+		}
+		Log.Warnf("Taking over the stale lock of %v that expired at %v", held.Owner, held.Expires.Format(time.RFC3339))
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the lock into json")
+	}
+	if err := s.store.Upload(ctx, s.key, bytes.NewReader(data), UploadOptions{ContentType: "application/json", CacheControl: "no-cache"}); err != nil {
+		return err
+	}
+
+	held, err = s.current(ctx)
+	if err != nil {
+		return err
+	}
+	if held == nil || held.Owner != l.Owner {
+		if held == nil {
+			held = l
+		}
+		return &LockedError{Lock: held}
+	}
+
+	return nil
+}
+
+// Renew Write the lock with its new expiry if it is still ours
+func (s *s3Locker) Renew(ctx context.Context, l *Lock) error {
+	held, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	if held == nil {
+		return fmt.Errorf("The lock was removed")
+	}
+	if held.Owner != l.Owner {
+		return &LockedError{Lock: held}
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the lock into json")
+	}
+
+	return s.store.Upload(ctx, s.key, bytes.NewReader(data), UploadOptions{ContentType: "application/json", CacheControl: "no-cache"})
+}
+
+// Release Delete the lock if it is still ours
+func (s *s3Locker) Release(ctx context.Context, l *Lock) error {
+	held, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	if held == nil || held.Owner != l.Owner {
+		return nil
+	}
+
+	return s.store.Delete(ctx, s.key)
+}
+
+// ForceRelease Delete the lock
+func (s *s3Locker) ForceRelease(ctx context.Context) error {
+	return s.store.Delete(ctx, s.key)
+}
+
+// dynamoLocker A lock item in a DynamoDB table with a string partition key named id
+type dynamoLocker struct {
+	svc   *dynamodb.DynamoDB
+	table string
+	id    string
+}
+
+// Acquire Put the lock item unless there is one that has not expired
+func (d *dynamoLocker) Acquire(ctx context.Context, l *Lock) error {
+	_, err := d.svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"id":       {S: aws.String(d.id)},
+			"owner":    {S: aws.String(l.Owner)},
+			"command":  {S: aws.String(l.Command)},
+			"version":  {S: aws.String(l.Version)},
+			"acquired": {N: aws.String(fmt.Sprint(l.Acquired.Unix()))},
+			"expires":  {N: aws.String(fmt.Sprint(l.Expires.Unix()))},
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(id) OR #expires < :now"),
+		ExpressionAttributeNames: map[string]*string{"#expires": aws.String("expires")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprint(time.Now().Unix()))},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return &LockedError{Lock: d.current(ctx)}
+	}
+
+	return err
+}
+
+// current Get the lock item to report who holds it, what could not be read is left empty
+func (d *dynamoLocker) current(ctx context.Context) *Lock {
+	l := &Lock{Owner: "unknown", Command: "unknown", Version: "unknown"}
+
+	out, err := d.svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            map[string]*dynamodb.AttributeValue{"id": {S: aws.String(d.id)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || out.Item == nil {
+		return l
+	}
+
+	unix := func(name string) time.Time {
+		var sec int64
+		if v, ok := out.Item[name]; ok {
+			fmt.Sscan(aws.StringValue(v.N), &sec)
+		}
+		return time.Unix(sec, 0).UTC()
+	}
+	for name, v := range map[string]*string{"owner": &l.Owner, "command": &l.Command, "version": &l.Version} {
+		if a, ok := out.Item[name]; ok {
+			*v = aws.StringValue(a.S)
+		}
+	}
+	l.Acquired = unix("acquired")
+	l.Expires = unix("expires")
+
+	return l
+}
+
+// Renew Move the expiry of the lock item if it is still ours
+func (d *dynamoLocker) Renew(ctx context.Context, l *Lock) error {
+	_, err := d.svc.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.table),
+		Key:              map[string]*dynamodb.AttributeValue{"id": {S: aws.String(d.id)}},
+		UpdateExpression: aws.String("SET #expires = :expires"),
+		// owner is a reserved word in DynamoDB expressions
+		ConditionExpression:      aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{"#owner": aws.String("owner"), "#expires": aws.String("expires")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner":   {S: aws.String(l.Owner)},
+			":expires": {N: aws.String(fmt.Sprint(l.Expires.Unix()))},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return &LockedError{Lock: d.current(ctx)}
+	}
+
+	return err
+}
+
+// Release Delete the lock item if it is still ours
+func (d *dynamoLocker) Release(ctx context.Context, l *Lock) error {
+	_, err := d.svc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.table),
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(d.id)}},
+		// owner is a reserved word in DynamoDB expressions
+		ConditionExpression:       aws.String("#owner = :owner"),
+		ExpressionAttributeNames:  map[string]*string{"#owner": aws.String("owner")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":owner": {S: aws.String(l.Owner)}},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+
+	return err
+}
+
+// ForceRelease Delete the lock item
+func (d *dynamoLocker) ForceRelease(ctx context.Context) error {
+	_, err := d.svc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.table),
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(d.id)}},
+	})
+
+	return err
+}
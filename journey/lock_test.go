@@ -0,0 +1,196 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// holdLock Write a lock owned by someone else that expires after ttl, negative for a stale lock
+func holdLock(t *testing.T, j *Journey, store Storage, ttl time.Duration) *Lock {
+	now := time.Now().UTC()
+	l := &Lock{Owner: "ci-runner:42:cafe", Command: "publish", Version: "1.0.0", Acquired: now.Add(-time.Minute), Expires: now.Add(ttl)}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatalf("Unable to parse the lock into json: %v", err)
+	}
+	if err := store.Upload(context.Background(), j.GetLockKey(), bytes.NewReader(data), UploadOptions{ContentType: "application/json"}); err != nil {
+		t.Fatalf("Upload() failed: %v", err)
+	}
+
+	return l
+}
+
+// heldLock The lock in the store, nil when there is none
+func heldLock(t *testing.T, j *Journey, store Storage) *Lock {
+	l, err := (&s3Locker{store: store, key: j.GetLockKey()}).current(context.Background())
+	if err != nil {
+		t.Fatalf("Unable to get the lock: %v", err)
+	}
+
+	return l
+}
+
+func TestAcquireLock(t *testing.T) {
+	tests := []struct {
+		name       string
+		lock       string
+		dryRun     bool
+		held       time.Duration
+		wantLock   bool
+		wantLocked bool
+	}{
+		{"unlocked", LockS3, false, 0, true, false},
+		{"locked by another job", LockS3, false, time.Hour, false, true},
+		{"stale lock", LockS3, false, -time.Hour, true, false},
+		{"locking turned off", LockNone, false, time.Hour, false, false},
+		{"no lock set", "", false, time.Hour, false, false},
+		{"dry run", LockS3, true, time.Hour, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+
+			j, cleanup := newTestJourney(t, "1.1.0")
+			defer cleanup()
+			j.Lock = test.lock
+			j.DryRun = test.dryRun
+
+			var other *Lock
+			if test.held != 0 {
+				other = holdLock(t, j, store, test.held)
+			}
+
+			l, err := j.AcquireLock(ctx, j.NewLocker(store, nil), "publish")
+			locked, ok := err.(*LockedError)
+			if ok != test.wantLocked || (err != nil && !ok) {
+				t.Fatalf("AcquireLock() = %v, want locked %v", err, test.wantLocked)
+			}
+			if ok && (locked.Name != j.Name || locked.Lock.Owner != other.Owner) {
+				t.Errorf("LockedError = %v held by %v, want %v held by %v", locked.Name, locked.Lock.Owner, j.Name, other.Owner)
+			}
+			if (l != nil) != test.wantLock {
+				t.Fatalf("AcquireLock() = %+v, want a lock %v", l, test.wantLock)
+			}
+			if l == nil {
+				return
+			}
+
+			if held := heldLock(t, j, store); held == nil || held.Owner != l.Owner || held.Command != "publish" || held.Version != j.Version {
+				t.Errorf("lock in the bucket = %+v, want %+v", held, l)
+			}
+			if l.Expires.Sub(l.Acquired) != defaultLockTTL {
+				t.Errorf("lock is held for %v, want %v", l.Expires.Sub(l.Acquired), defaultLockTTL)
+			}
+		})
+	}
+}
+
+func TestReleaseLock(t *testing.T) {
+	tests := []struct {
+		name      string
+		takenOver bool
+		force     bool
+		wantHeld  bool
+	}{
+		{"own lock", false, false, false},
+		{"taken over by another job", true, false, true},
+		{"force unlock", true, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStorage("portal")
+
+			j, cleanup := newTestJourney(t, "1.1.0")
+			defer cleanup()
+			j.Lock = LockS3
+			locker := j.NewLocker(store, nil)
+
+			l, err := j.AcquireLock(ctx, locker, "setLatest")
+			if err != nil {
+				t.Fatalf("AcquireLock() failed: %v", err)
+			}
+			if test.takenOver {
+				holdLock(t, j, store, time.Hour)
+			}
+
+			if test.force {
+				if err := j.ForceUnlock(ctx, locker); err != nil {
+					t.Fatalf("ForceUnlock() failed: %v", err)
+				}
+			} else {
+				j.ReleaseLock(locker, l)
+			}
+
+			if held := heldLock(t, j, store); (held != nil) != test.wantHeld {
+				t.Errorf("lock in the bucket = %+v, want one %v", held, test.wantHeld)
+			}
+		})
+	}
+}
+
+func TestKeepLock(t *testing.T) {
+	tests := []struct {
+		name      string
+		takenOver bool
+		removed   bool
+		wantLost  bool
+	}{
+		{"own lock", false, false, false},
+		{"taken over by another job", true, false, true},
+		{"removed", false, true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStorage("portal")
+
+			j, cleanup := newTestJourney(t, "1.1.0")
+			defer cleanup()
+			j.Lock = LockS3
+			j.LockTTL = "300ms"
+			locker := j.NewLocker(store, nil)
+
+			l, err := j.AcquireLock(context.Background(), locker, "publish")
+			if err != nil {
+				t.Fatalf("AcquireLock() failed: %v", err)
+			}
+			acquired := l.Expires
+			switch {
+			case test.takenOver:
+				holdLock(t, j, store, time.Hour)
+			case test.removed:
+				if err := j.ForceUnlock(context.Background(), locker); err != nil {
+					t.Fatalf("ForceUnlock() failed: %v", err)
+				}
+			}
+
+			ctx, stop := j.KeepLock(context.Background(), locker, l)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+
+			done := ctx.Err()
+			lost := stop()
+			if (lost != nil) != test.wantLost || (done != nil) != test.wantLost {
+				t.Fatalf("KeepLock() lost %v with the context done %v, want it lost %v", lost, done, test.wantLost)
+			}
+			if test.wantLost {
+				return
+			}
+
+			// renewed every 100ms for a second, the lock outlived its ttl
+			if held := heldLock(t, j, store); held == nil || !held.Expires.After(acquired) || !held.Expires.Equal(l.Expires) {
+				t.Errorf("lock in the bucket = %+v, want it renewed past %v", held, acquired)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package journey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log levels, from the most to the least chatty
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames How each level is written in the logs
+var levelNames = map[int]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// Fields Structured values attached to a log entry
+type Fields map[string]interface{}
+
+// Logger A leveled logger that writes text for people or one json object per line for CI
+type Logger struct {
+	Level int
+	JSON  bool
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// Log The logger used by the journey package, configure it before publishing
+var Log = NewLogger(os.Stderr)
+
+// NewLogger Create a text logger at info level writing to out
+func NewLogger(out io.Writer) *Logger {
+	return &Logger{Level: LevelInfo, out: out}
+}
+
+// write Write the entry if the level is enabled
+func (l *Logger) write(level int, msg string, fields Fields) {
+	if level < l.Level {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.JSON {
+		entry := make(Fields, len(fields)+3)
+		for k, v := range fields {
+			entry[k] = v
+		}
+		entry["time"] = now.UTC().Format(time.RFC3339)
+		entry["level"] = levelNames[level]
+		entry["msg"] = msg
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data = []byte(fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error()))
+		}
+		fmt.Fprintf(l.out, "%s\n", data)
+		return
+	}
+
+	prefix := ""
+	if level >= LevelWarn {
+		prefix = strings.ToUpper(levelNames[level]) + ": "
+	}
+	fmt.Fprintf(l.out, "%v %v%v\n", now.Format("2006/01/02 15:04:05"), prefix, msg)
+}
+
+// Debugf Log details that are only interesting with -verbose
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.write(LevelDebug, fmt.Sprintf(format, args...), nil)
+}
+
+// Infof Log progress
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.write(LevelInfo, fmt.Sprintf(format, args...), nil)
+}
+
+// Warnf Log something that did not stop the command but should be looked at
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.write(LevelWarn, fmt.Sprintf(format, args...), nil)
+}
+
+// Errorf Log a failure
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.write(LevelError, fmt.Sprintf(format, args...), nil)
+}
+
+// Event Log a result at info level with fields CI can parse, the fields are only written in json
+func (l *Logger) Event(msg string, fields Fields) {
+	l.write(LevelInfo, msg, fields)
+}
@@ -0,0 +1,169 @@
+package journey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Asset manifest formats the journey can read
+const (
+	ManifestFlat    = "flat"
+	ManifestWebpack = "webpack"
+	ManifestVite    = "vite"
+	ManifestCRA     = "cra"
+)
+
+// craManifest The asset-manifest.json written by create-react-app 3 and later
+type craManifest struct {
+	Files       map[string]string `json:"files"`
+	Entrypoints []string          `json:"entrypoints"`
+}
+
+// viteChunk A single entry in the manifest.json written by vite
+type viteChunk struct {
+	File    string   `json:"file"`
+	Src     string   `json:"src"`
+	IsEntry bool     `json:"isEntry"`
+	CSS     []string `json:"css"`
+	Assets  []string `json:"assets"`
+	Imports []string `json:"imports"`
+}
+
+// validateManifestFormat Validate the manifest format is one we know how to read
+func validateManifestFormat(format string) error {
+	switch format {
+	case "", ManifestFlat, ManifestWebpack, ManifestVite, ManifestCRA:
+		return nil
+	default:
+		return fmt.Errorf("Manifest format %v is not supported, use %v, %v, %v or %v", format, ManifestFlat, ManifestWebpack, ManifestVite, ManifestCRA)
+	}
+}
+
+// LoadManifest Read the asset manifest at path and normalize it into a map of asset name to path in the build
+func LoadManifest(path string, format string) (map[string]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseManifest(content, format)
+}
+
+// checkManifestJSON Point at the line and column when the manifest is not json at all, before trying to read it as the format
+func checkManifestJSON(content []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return describeJSONError("asset manifest", content, err)
+	}
+
+	return nil
+}
+
+// ParseManifest Normalize the content of an asset manifest into a map of asset name to path in the build,
+// paths use forward slashes and are relative to the build directory whatever the OS wrote them
+func ParseManifest(content []byte, format string) (map[string]string, error) {
+	if err := checkManifestJSON(content); err != nil {
+		return nil, err
+	}
+
+	var assets map[string]string
+	var err error
+
+	switch format {
+	case "", ManifestFlat, ManifestWebpack:
+		assets, err = parseFlatManifest(content)
+	case ManifestCRA:
+		assets, err = parseCRAManifest(content)
+	case ManifestVite:
+		assets, err = parseViteManifest(content)
+	default:
+		return nil, validateManifestFormat(format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var outside []string
+	for k, v := range assets {
+		if assets[k], err = normalizeAssetPath(v); err != nil {
+			outside = append(outside, fmt.Sprintf("%v: %v", k, v))
+		}
+	}
+	if len(outside) > 0 {
+		sort.Strings(outside)
+		return nil, fmt.Errorf("The asset manifest has entries outside the build directory:\n%v", strings.Join(outside, "\n"))
+	}
+
+	return assets, nil
+}
+
+// parseFlatManifest Parse a manifest that is already a map of name to path, like webpack-manifest-plugin writes,
+// the entrypoints written next to the assets by webpack-assets-manifest are left to ParseEntrypoints
+func parseFlatManifest(content []byte) (map[string]string, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("Unable to parse the asset manifest as a flat map of name to path, set the manifest format if it is not: %v", err)
+	}
+
+	assets := make(map[string]string, len(m))
+	for k, raw := range m {
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			if k == "entrypoints" {
+				continue
+			}
+			return nil, fmt.Errorf("Unable to parse the asset manifest as a flat map of name to path, set the manifest format if it is not: %v is not a path", k)
+		}
+		assets[k] = v
+	}
+
+	return assets, nil
+}
+
+// parseCRAManifest Use the files of a create-react-app manifest, which are written with the public path in front
+func parseCRAManifest(content []byte) (map[string]string, error) {
+	var m craManifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("Unable to parse the create-react-app asset manifest: %v", err)
+	}
+
+	assets := make(map[string]string, len(m.Files))
+	for k, v := range m.Files {
+		assets[k] = strings.TrimPrefix(v, "/")
+	}
+
+	return assets, nil
+}
+
+// parseViteManifest Flatten the chunks of a vite manifest along with the css and static assets they pull in
+func parseViteManifest(content []byte) (map[string]string, error) {
+	var m map[string]viteChunk
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("Unable to parse the vite manifest: %v", err)
+	}
+
+	assets := make(map[string]string)
+	for k, chunk := range m {
+		if len(chunk.File) > 0 {
+			assets[k] = chunk.File
+		}
+		for i, css := range chunk.CSS {
+			assets[k+".css."+strconv.Itoa(i)] = css
+		}
+		for _, asset := range chunk.Assets {
+			assets[asset] = asset
+		}
+	}
+
+	return assets, nil
+}
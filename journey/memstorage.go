@@ -0,0 +1,212 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryObject An object held by MemoryStorage
+type memoryObject struct {
+	Object
+	data []byte
+}
+
+// MemoryStorage Storage kept in memory, stands in for a bucket when exercising publish without S3
+type MemoryStorage struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]*memoryObject
+	Bucket  string
+}
+
+// NewMemoryStorage Create empty in memory storage for the bucket
+func NewMemoryStorage(bucket string) *MemoryStorage {
+	return &MemoryStorage{
+		Bucket:  bucket,
+		buckets: map[string]map[string]*memoryObject{bucket: {}},
+	}
+}
+
+// objects Get the objects in a bucket, creating it when it does not exist
+func (m *MemoryStorage) objects(bucket string) map[string]*memoryObject {
+	if _, ok := m.buckets[bucket]; !ok {
+		m.buckets[bucket] = map[string]*memoryObject{}
+	}
+	return m.buckets[bucket]
+}
+
+// Upload Store the body at key
+func (m *MemoryStorage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("Unable to read %v: %v", key, err)
+	}
+
+	metadata := map[string]string{}
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects(m.Bucket)[key] = &memoryObject{
+		Object: Object{
+			Key:          key,
+			Size:         int64(len(data)),
+			ETag:         bytesETag(data, 0),
+			LastModified: time.Now(),
+			ContentType:  opts.ContentType,
+			Metadata:     metadata,
+			StorageClass: opts.StorageClass,
+		},
+		data: data,
+	}
+
+	return nil
+}
+
+// Head Get the object at key without its content
+func (m *MemoryStorage) Head(ctx context.Context, key string) (*Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.objects(m.Bucket)[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	object := o.Object
+	return &object, nil
+}
+
+// Get Get the content of the object at key
+func (m *MemoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.objects(m.Bucket)[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(o.data)), nil
+}
+
+// Copy Copy an object inside the bucket
+func (m *MemoryStorage) Copy(ctx context.Context, from string, to string) error {
+	return m.CopyFrom(ctx, m.Bucket, from, to)
+}
+
+// CopyFrom Copy an object from another bucket held by the same storage into this one
+func (m *MemoryStorage) CopyFrom(ctx context.Context, bucket string, from string, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.objects(bucket)[from]
+	if !ok {
+		return ErrNotFound
+	}
+
+	c := *o
+	c.Key = to
+	c.LastModified = time.Now()
+	m.objects(m.Bucket)[to] = &c
+
+	return nil
+}
+
+// SetStorageClass Record the storage class of the object
+func (m *MemoryStorage) SetStorageClass(ctx context.Context, key string, class string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.objects(m.Bucket)[key]
+	if !ok {
+		return ErrNotFound
+	}
+	o.StorageClass = class
+	o.LastModified = time.Now()
+
+	return nil
+}
+
+// Restore Objects in memory are never archived, so they can always be read
+func (m *MemoryStorage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	if _, err := m.Head(ctx, key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Delete Delete the objects, keys that do not exist are ignored like S3 does
+func (m *MemoryStorage) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects := m.objects(m.Bucket)
+	for _, k := range keys {
+		delete(objects, k)
+	}
+
+	return nil
+}
+
+// List List every object under the prefix, sorted by key like S3 does
+func (m *MemoryStorage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []*Object
+	for k, o := range m.objects(m.Bucket) {
+		if strings.HasPrefix(k, prefix) {
+			object := o.Object
+			list = append(list, &object)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Key < list[b].Key })
+
+	return list, nil
+}
+
+// ListIncompleteUploads Memory storage has no multipart uploads, so nothing is ever left incomplete
+func (m *MemoryStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortUpload Memory storage has no multipart uploads to abort
+func (m *MemoryStorage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	return ErrNotFound
+}
+
+// ListPrefixes List the common prefixes one level below the prefix, like directories
+func (m *MemoryStorage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := map[string]bool{}
+	var prefixes []string
+	for k := range m.objects(m.Bucket) {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		i := strings.Index(k[len(prefix):], "/")
+		if i < 0 {
+			continue
+		}
+		p := k[:len(prefix)+i+1]
+		if !seen[p] {
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+	}
+	sort.Strings(prefixes)
+
+	return prefixes, nil
+}
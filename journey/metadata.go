@@ -0,0 +1,68 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetMetadata Get the metadata stored next to a published version, empty if the version has none
+func (j *Journey) GetMetadata(ctx context.Context, store Storage, version string) (map[string]string, error) {
+	meta := make(map[string]string)
+	key := j.GetVersionKey(version, MetadataFile)
+
+	body, err := store.Get(ctx, key)
+	if err == ErrNotFound {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("Unable to parse %v: %v", key, err)
+	}
+
+	return meta, nil
+}
+
+// putMetadata Store the metadata next to the published version
+func (j *Journey) putMetadata(ctx context.Context, store Storage, meta map[string]string) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the metadata into json")
+	}
+
+	key := j.GetAssetKey(MetadataFile)
+	return store.Upload(ctx, key, bytes.NewReader(data), j.uploadOptions(key, "application/json"))
+}
+
+// Annotate Merge key/values into the metadata of the published version
+func (j *Journey) Annotate(ctx context.Context, meta map[string]string, store Storage) error {
+	if len(meta) <= 0 {
+		return fmt.Errorf("Nothing to annotate, pass at least one -meta key=value")
+	}
+
+	// only annotate versions that were actually published
+	if _, err := store.Head(ctx, j.GetAssetKey(JourneyFile)); err != nil {
+		return fmt.Errorf("Version %v/%v is not published: %v", j.Name, j.Version, err)
+	}
+
+	existing, err := j.GetMetadata(ctx, store, j.Version)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range meta {
+		existing[k] = v
+	}
+
+	if err := j.putMetadata(ctx, store, existing); err != nil {
+		return err
+	}
+	Log.Infof("Annotated %v/%v with %v", j.Name, j.Version, meta)
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Where publish metrics are sent
+const (
+	MetricsCloudWatch  = "cloudwatch"
+	MetricsPushgateway = "pushgateway"
+)
+
+// DefaultMetricsNamespace The CloudWatch namespace of the metrics unless one is set
+const DefaultMetricsNamespace = "JourneyCLI"
+
+// metricsTimeout How long pushing the metrics may take
+const metricsTimeout = 10 * time.Second
+
+// metricsOut Where CloudWatch embedded metrics are written, the CloudWatch agent or Lambda picks them up from stdout
+var metricsOut io.Writer = os.Stdout
+
+// Metrics Send the duration, bytes, file count and failures of every publish to CloudWatch or a Prometheus pushgateway
+type Metrics struct {
+	Type      string `json:"type"`
+	Namespace string `json:"namespace"`
+	URL       string `json:"url" journey:"private"`
+}
+
+// validate Validate the metrics have somewhere to go
+func (m *Metrics) validate() error {
+	switch m.Type {
+	case "", MetricsCloudWatch:
+	case MetricsPushgateway:
+		if len(m.URL) <= 0 {
+			return fmt.Errorf("Metrics to a %v need its url", MetricsPushgateway)
+		}
+	default:
+		return fmt.Errorf("Metrics type %v is not supported, use %v or %v", m.Type, MetricsCloudWatch, MetricsPushgateway)
+	}
+
+	return nil
+}
+
+// PublishMetrics What one publish did
+type PublishMetrics struct {
+	Duration    time.Duration
+	Bytes       int64
+	Files       int
+	FailedFiles int
+	Failed      bool
+}
+
+// NewPublishMetrics Measure the publish from its report, which marks the files that failed when the publish did
+func NewPublishMetrics(report *Report, err error, duration time.Duration) *PublishMetrics {
+	m := &PublishMetrics{Duration: duration, Failed: err != nil}
+	if report == nil {
+		return m
+	}
+
+	for _, f := range report.Files {
+		switch {
+		case len(f.Error) > 0:
+			m.FailedFiles++
+		case !f.Skipped:
+			m.Files++
+			m.Bytes += f.Size
+		}
+	}
+
+	return m
+}
+
+// EmitMetrics Send the metrics of the publish, tagged with the name and version. Metrics that can not be sent
+// are only warned about, they never fail the publish
+func (j *Journey) EmitMetrics(ctx context.Context, m *PublishMetrics) {
+	if len(j.Metrics.Type) <= 0 || j.DryRun {
+		return
+	}
+
+	var err error
+	switch j.Metrics.Type {
+	case MetricsCloudWatch:
+		err = j.writeEmbeddedMetrics(metricsOut, m)
+	case MetricsPushgateway:
+		err = j.pushMetrics(ctx, m)
+	}
+	if err != nil {
+		Log.Warnf("Unable to send the metrics of %v/%v: %v", j.Name, j.Version, err)
+	}
+}
+
+// failed 1 when the publish failed, so failures can be summed
+func (m *PublishMetrics) failed() int {
+	if m.Failed {
+		return 1
+	}
+
+	return 0
+}
+
+// writeEmbeddedMetrics Write the metrics in the CloudWatch embedded metric format. The name and environment are dimensions
+// and the version a property, so every version does not become a metric of its own
+func (j *Journey) writeEmbeddedMetrics(out io.Writer, m *PublishMetrics) error {
+	namespace := j.Metrics.Namespace
+	if len(namespace) <= 0 {
+		namespace = DefaultMetricsNamespace
+	}
+	dimensions := []string{"Name"}
+	if len(j.Environment) > 0 {
+		dimensions = append(dimensions, "Environment")
+	}
+
+	entry := map[string]interface{}{
+		"_aws": map[string]interface{}{
+			"Timestamp": time.Now().UnixNano() / int64(time.Millisecond),
+			"CloudWatchMetrics": []map[string]interface{}{{
+				"Namespace":  namespace,
+				"Dimensions": [][]string{dimensions},
+				"Metrics": []map[string]string{
+					{"Name": "Publishes", "Unit": "Count"},
+					{"Name": "PublishFailures", "Unit": "Count"},
+					{"Name": "PublishDuration", "Unit": "Seconds"},
+					{"Name": "PublishBytes", "Unit": "Bytes"},
+					{"Name": "PublishFiles", "Unit": "Count"},
+					{"Name": "PublishFailedFiles", "Unit": "Count"},
+				},
+			}},
+		},
+		"Name":               j.Name,
+		"Version":            j.Version,
+		"Publishes":          1,
+		"PublishFailures":    m.failed(),
+		"PublishDuration":    m.Duration.Seconds(),
+		"PublishBytes":       m.Bytes,
+		"PublishFiles":       m.Files,
+		"PublishFailedFiles": m.FailedFiles,
+	}
+	if len(j.Environment) > 0 {
+		entry["Environment"] = j.Environment
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the metrics into json")
+	}
+	_, err = fmt.Fprintln(out, string(data))
+
+	return err
+}
+
+// promLabel Quote a Prometheus label value
+func promLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// pushMetrics Replace the metrics of the journey on the pushgateway, grouped by job, name and environment with the version as a label
+func (j *Journey) pushMetrics(ctx context.Context, m *PublishMetrics) error {
+	labels := "{version=" + promLabel(j.Version) + "}"
+
+	var b bytes.Buffer
+	for _, metric := range []struct {
+		name  string
+		help  string
+		value interface{}
+	}{
+		{"journey_publish_timestamp_seconds", "When the journey was last published", time.Now().Unix()},
+		{"journey_publish_failed", "1 when the last publish failed", m.failed()},
+		{"journey_publish_duration_seconds", "How long the last publish took", m.Duration.Seconds()},
+		{"journey_publish_bytes", "Bytes uploaded by the last publish", m.Bytes},
+		{"journey_publish_files", "Files uploaded by the last publish", m.Files},
+		{"journey_publish_failed_files", "Files that failed to upload in the last publish", m.FailedFiles},
+	} {
+		fmt.Fprintf(&b, "# HELP %v %v\n# TYPE %v gauge\n%v%v %v\n", metric.name, metric.help, metric.name, metric.name, labels, metric.value)
+	}
+
+	u := strings.TrimSuffix(j.Metrics.URL, "/") + "/metrics/job/journey_cli/name/" + url.PathEscape(j.Name)
+	if len(j.Environment) > 0 {
+		u += "/environment/" + url.PathEscape(j.Environment)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, u, &b)
+	if err != nil {
+		return fmt.Errorf("Pushgateway url %v is not valid: %v", j.Metrics.URL, err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: metricsTimeout}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%v returned %v", u, res.Status)
+	}
+	Log.Debugf("Pushed the metrics of %v/%v to %v", j.Name, j.Version, u)
+
+	return nil
+}
@@ -0,0 +1,120 @@
+//go:build integration
+// +build integration
+
+package journey
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Run against a MinIO server with credentials in AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, eg:
+// go test -tags integration ./journey -endpoint http://localhost:9000
+var (
+	endpoint = flag.String("endpoint", "", "S3 compatible endpoint to run the integration tests against, they are skipped without one")
+	bucket   = flag.String("bucket", "journey-integration", "Bucket the integration tests publish to, created when it does not exist")
+)
+
+// newMinIOStorage Storage on the bucket of the endpoint, each test publishes a journey of its own name, removed by the
+// returned func
+func newMinIOStorage(t *testing.T) (Storage, string, func()) {
+	if len(*endpoint) <= 0 {
+		t.Skip("Pass -endpoint to run the integration tests")
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+	if err != nil {
+		t.Fatalf("Unable to create an AWS session: %v", err)
+	}
+
+	svc := s3.New(sess, &aws.Config{Endpoint: endpoint, S3ForcePathStyle: aws.Bool(true)})
+	if _, err := svc.HeadBucket(&s3.HeadBucketInput{Bucket: bucket}); err != nil {
+		if _, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: bucket}); err != nil {
+			t.Fatalf("Unable to create bucket %v: %v", *bucket, err)
+		}
+	}
+
+	store, err := NewStorage(BackendS3, *bucket, sess, StorageOptions{Endpoint: *endpoint, PathStyle: true, Retries: 3})
+	if err != nil {
+		t.Fatalf("NewStorage() failed: %v", err)
+	}
+
+	name := fmt.Sprintf("integration-%v", time.Now().UnixNano())
+	return store, name, func() {
+		objects, err := store.List(context.Background(), name+"/")
+		if err != nil {
+			t.Errorf("Unable to list %v: %v", name, err)
+			return
+		}
+		for _, o := range objects {
+			if err := store.Delete(context.Background(), o.Key); err != nil {
+				t.Errorf("Unable to delete %v: %v", o.Key, err)
+			}
+		}
+	}
+}
+
+func TestMinIO(t *testing.T) {
+	store, name, cleanup := newMinIOStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	journeys := make(map[string]*Journey)
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		j, cleanup := newTestJourney(t, version)
+		defer cleanup()
+		j.Name = name
+		j.Bucket = *bucket
+		j.Lock = LockS3
+		journeys[version] = j
+
+		locker := j.NewLocker(store, nil)
+		l, err := j.AcquireLock(ctx, locker, "publish")
+		if err != nil {
+			t.Fatalf("AcquireLock() failed: %v", err)
+		}
+		if _, err := j.AcquireLock(ctx, locker, "publish"); err == nil {
+			t.Errorf("AcquireLock() while locked = nil, want a *LockedError")
+		}
+		_, err = j.Publish(ctx, testAssets(), store)
+		j.ReleaseLock(locker, l)
+		if err != nil {
+			t.Fatalf("Publish(%v) failed: %v", version, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		run  func(j map[string]*Journey) error
+		want string
+	}{
+		{"set latest", func(j map[string]*Journey) error { return j["1.1.0"].SetLatest(ctx, store) }, "1.1.0"},
+		{"roll back", func(j map[string]*Journey) error { return j["1.1.0"].Rollback(ctx, "1.0.0", store) }, "1.0.0"},
+	}
+
+	// each step builds on the latest the one before left
+	for _, test := range tests {
+		if err := test.run(journeys); err != nil {
+			t.Fatalf("%v failed: %v", test.name, err)
+		}
+		if got := latestVersion(t, journeys["1.0.0"], store); got != test.want {
+			t.Errorf("latest after %v = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	pruned, err := journeys["1.2.0"].Prune(ctx, store, 1, 0, "", true)
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	if want := []string{"1.1.0"}; !reflect.DeepEqual(pruned, want) {
+		t.Errorf("Prune() = %v, want %v", pruned, want)
+	}
+}
@@ -0,0 +1,50 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultMultipartAge Incomplete uploads younger than this may belong to a publish that is still running
+const defaultMultipartAge = 24 * time.Hour
+
+// CleanupMultipart Abort the multipart uploads under the journey that were started longer ago than olderThan
+// and never completed, failed publishes leave their parts behind and S3 bills for them. Returns the aborted keys
+func (j *Journey) CleanupMultipart(ctx context.Context, store Storage, olderThan time.Duration) ([]string, error) {
+	if olderThan <= 0 {
+		olderThan = defaultMultipartAge
+	}
+
+	uploads, err := store.ListIncompleteUploads(ctx, j.GetJourneyKey(""))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the incomplete uploads of %v: %v", j.Name, err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var aborted []string
+	for _, u := range uploads {
+		if u.Initiated.After(cutoff) {
+			Log.Debugf("Upload of %v started %v and may still be running", u.Key, u.Initiated.Format(time.RFC3339))
+			continue
+		}
+
+		if j.DryRun {
+			Log.Infof("Upload of %v started %v would be aborted", u.Key, u.Initiated.Format(time.RFC3339))
+			aborted = append(aborted, u.Key)
+			continue
+		}
+
+		if err := store.AbortUpload(ctx, u.Key, u.UploadID); err != nil && err != ErrNotFound {
+			return aborted, fmt.Errorf("Unable to abort the upload of %v: %v", u.Key, err)
+		}
+		Log.Infof("Aborted the upload of %v started %v", u.Key, u.Initiated.Format(time.RFC3339))
+		aborted = append(aborted, u.Key)
+	}
+
+	if len(aborted) == 0 {
+		Log.Infof("No incomplete uploads of %v are older than %v", j.Name, olderThan)
+	}
+
+	return aborted, nil
+}
@@ -0,0 +1,103 @@
+package journey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Chat services notifications can be sent to, the target is the incoming webhook url with the scheme replaced, eg: slack://hooks.slack.com/services/...
+const (
+	NotifySlack = "slack"
+	NotifyTeams = "teams"
+)
+
+// parseNotifyTarget Split a notify target into the chat service and the https url of its incoming webhook
+func parseNotifyTarget(target string) (string, string, error) {
+	parts := strings.SplitN(target, "://", 2)
+	if len(parts) != 2 || len(parts[1]) <= 0 {
+		return "", "", fmt.Errorf("Notify target %v must look like %v://hooks.slack.com/services/...", target, NotifySlack)
+	}
+
+	switch parts[0] {
+	case NotifySlack, NotifyTeams:
+		return parts[0], "https://" + parts[1], nil
+	default:
+		return "", "", fmt.Errorf("Notify target %v is not supported, use %v:// or %v://", target, NotifySlack, NotifyTeams)
+	}
+}
+
+// validateNotify Validate every notify target can be sent to
+func validateNotify(targets []string) error {
+	for _, t := range targets {
+		if _, _, err := parseNotifyTarget(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Text A one line summary of the notification for people
+func (n *Notification) Text() string {
+	var what string
+	switch n.Event {
+	case EventPublish:
+		what = "was published"
+	case EventSetLatest:
+		what = "is now latest"
+	case EventRollback:
+		what = "is now latest after a rollback"
+	case EventCanary:
+		what = "is rolling out as a canary"
+	case EventPromoteCanary:
+		what = "is now latest after its canary"
+	default:
+		what = n.Event
+	}
+
+	where := ""
+	if len(n.Environment) > 0 {
+		where = " in " + n.Environment
+	}
+
+	return fmt.Sprintf("%v %v %v%v: %v", n.Name, n.Version, what, where, n.URL)
+}
+
+// chatBody Format the notification as a message for the chat service
+func chatBody(service string, n *Notification) ([]byte, error) {
+	switch service {
+	case NotifyTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  n.Name + " " + n.Version,
+			"text":     n.Text(),
+		})
+	default:
+		return json.Marshal(map[string]string{"text": n.Text()})
+	}
+}
+
+// notifyChats Send the notification to every notify target
+func (j *Journey) notifyChats(ctx context.Context, client *http.Client, n *Notification) {
+	for _, target := range j.Chats {
+		// targets are validated when the journey is loaded
+		service, url, _ := parseNotifyTarget(target)
+
+		body, err := chatBody(service, n)
+		if err != nil {
+			Log.Errorf("Unable to parse the %v message into json", service)
+			continue
+		}
+
+		w := &Webhook{URL: url}
+		if err := w.post(ctx, client, body); err != nil {
+			Log.Warnf("Unable to notify %v of %v: %v", service, n.Event, err)
+			continue
+		}
+		Log.Debugf("Notified %v of %v", service, n.Event)
+	}
+}
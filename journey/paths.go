@@ -0,0 +1,85 @@
+package journey
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultKeyTemplate The key of every version unless the paths set another one
+const DefaultKeyTemplate = "{{.Name}}/{{.Version}}"
+
+// versionPlaceholder Stands in for the version when the template is rendered for the keys shared by every version
+const versionPlaceholder = "\x00version\x00"
+
+// Paths Where the versions are kept in the bucket and what their urls file is called, for consumers that expect
+// the layout of another loader like remoteEntry-manifest.json under {team}/{name}/{version}
+type Paths struct {
+	UrlsFile string `json:"urlsFile"`
+	Keys     string `json:"keys"`
+}
+
+// pathsKey What the key template can use
+type pathsKey struct {
+	Name        string
+	Version     string
+	Environment string
+}
+
+// validate Validate the urls file is a plain file name and the key template ends in the version
+func (p *Paths) validate(name string, environment string) error {
+	switch {
+	case strings.ContainsAny(p.UrlsFile, `/\`):
+		return fmt.Errorf("Paths urlsFile %v must be a file name without a directory", p.UrlsFile)
+	case p.UrlsFile == JourneyFile, p.UrlsFile == ManifestFile, p.UrlsFile == MetadataFile:
+		return fmt.Errorf("Paths urlsFile %v is already used by the journey", p.UrlsFile)
+	}
+
+	if len(p.Keys) <= 0 {
+		return nil
+	}
+
+	root, err := p.root(name, environment)
+	if err != nil {
+		return err
+	}
+	if err := validatePrefix(root); err != nil || len(root) <= 0 || strings.Contains(root, versionPlaceholder) {
+		return fmt.Errorf("Paths keys %v must render a path like {{.Name}}/{{.Version}} with the version as its last part", p.Keys)
+	}
+
+	return nil
+}
+
+// root Render the key template without its version, the path every version of the journey is kept under
+func (p *Paths) root(name string, environment string) (string, error) {
+	if len(p.Keys) <= 0 {
+		return name, nil
+	}
+
+	t, err := template.New("keys").Option("missingkey=error").Parse(p.Keys)
+	if err != nil {
+		return "", fmt.Errorf("Unable to parse the paths keys %v: %v", p.Keys, err)
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, pathsKey{Name: name, Version: versionPlaceholder, Environment: environment}); err != nil {
+		return "", fmt.Errorf("Unable to render the paths keys %v: %v", p.Keys, err)
+	}
+
+	key := strings.Trim(b.String(), "/")
+	if !strings.HasSuffix(key, "/"+versionPlaceholder) {
+		return "", fmt.Errorf("Paths keys %v must end in /{{.Version}} so the versions can be listed", p.Keys)
+	}
+
+	return strings.TrimSuffix(key, "/"+versionPlaceholder), nil
+}
+
+// UrlsFile The name of the file listing the urls of the assets of a version
+func (j *Journey) UrlsFile() string {
+	if len(j.Paths.UrlsFile) > 0 {
+		return j.Paths.UrlsFile
+	}
+
+	return JourneyUrlsFile
+}
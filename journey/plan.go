@@ -0,0 +1,223 @@
+package journey
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Upload A single object publish writes to the bucket, either a local file at Path or a generated Body
+type Upload struct {
+	Key  string
+	Path string
+	Body []byte
+	Size int64
+	UploadOptions
+
+	// parts the uploader splits the upload into, which decides the ETag S3 gives it, zero for the default
+	PartSize int64
+
+	// checksums of what was stored are checked after the upload, the ETag only when it is the md5 of the content
+	Verify     bool
+	VerifyETag bool
+
+	// how long the upload may take, and how long it took once it is done
+	Timeout  time.Duration
+	Duration time.Duration
+
+	// slows the upload down to the bandwidth the journey may use
+	limiter *rateLimiter
+
+	// shared with the other journeys of a workspace
+	budget *Budget
+}
+
+// Plan Everything publish will upload for a version
+type Plan struct {
+	Uploads  []*Upload
+	Urls     *Urls
+	UrlsFile string
+
+	// the assets and rendered page of the version, which size budgets are checked against
+	assets map[string]string
+	page   []byte
+}
+
+// String Print the keys, content types and journey urls that would be published
+func (p *Plan) String() string {
+	var b bytes.Buffer
+
+	for _, u := range p.Uploads {
+		source := u.Path
+		if len(source) <= 0 {
+			source = "(generated)"
+		}
+		if len(u.ContentEncoding) > 0 {
+			source += " (" + u.ContentEncoding + ")"
+		}
+		if len(u.ACL) > 0 {
+			source += " (" + u.ACL + ")"
+		}
+		fmt.Fprintf(&b, "%v\t%v\t%v\t%v\n", u.Key, u.ContentType, u.CacheControl, source)
+	}
+
+	data, err := json.MarshalIndent(p.Urls, "", "  ")
+	if err == nil {
+		fmt.Fprintf(&b, "%v:\n%s\n", p.UrlsFile, data)
+	}
+
+	return b.String()
+}
+
+// splitPlan Split the uploads into assets and the markers that make the version visible,
+// the markers are ordered so journey.json, which says the version exists, goes last
+func (j *Journey) splitPlan(uploads []*Upload) ([]*Upload, []*Upload) {
+	var files []*Upload
+	var markers []*Upload
+	var config *Upload
+
+	for _, u := range uploads {
+		switch u.Key {
+		case j.GetAssetKey(JourneyFile):
+			config = u
+		case j.GetAssetKey(j.UrlsFile()), j.GetAssetKey(ImportMapFile), j.GetAssetKey(SingleSpaImportMapFile), j.GetAssetKey(SingleSpaFile), j.GetAssetKey(MetadataFile):
+			markers = append(markers, u)
+		default:
+			files = append(files, u)
+		}
+	}
+
+	if config != nil {
+		markers = append(markers, config)
+	}
+
+	return files, markers
+}
+
+// newUpload Create an upload of the file at path, or of body when there is no file
+func (j *Journey) newUpload(key string, path string, body []byte, contentType string) *Upload {
+	if j.Budget != nil && j.limiter == nil {
+		j.limiter = j.Budget.limiter
+	}
+	if j.MaxBandwidth > 0 && j.limiter == nil {
+		j.limiter = newRateLimiter(j.MaxBandwidth)
+	}
+
+	// content addressed keys are named by the sha256, so the rules match the file they are the content of
+	name := key
+	if len(path) > 0 && j.isSharedKey(key) {
+		name = path
+	}
+
+	u := &Upload{Key: key, Path: path, Body: body, Size: int64(len(body)), Timeout: j.UploadTimeout, UploadOptions: j.uploadOptions(name, contentType), limiter: j.limiter, budget: j.Budget}
+	u.StorageClass = j.getStorageClass(name)
+	u.PartSize = j.StorageOptions().PartSize
+	if j.Checksums {
+		// objects encrypted with KMS do not get the md5 of their content as the ETag
+		u.Verify = true
+		u.VerifyETag = j.Encryption != EncryptionKMS
+	}
+	if body == nil {
+		if info, err := os.Stat(path); err == nil {
+			u.Size = info.Size()
+		}
+	}
+
+	return u
+}
+
+// PlanPublish Resolve every asset and build the list of uploads for the version without touching S3
+func (j *Journey) PlanPublish(assets map[string]string) (*Plan, error) {
+	assets, err := j.addBuildFiles(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err = j.addSourceMaps(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err = j.PlanAssets(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Plan{UrlsFile: j.UrlsFile(), assets: assets}
+	p.Urls, err = j.BuildJourneyUrls(assets)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.signUrls(p.Urls); err != nil {
+		return nil, err
+	}
+
+	for _, v := range assets {
+		path := j.GetAssetPath(v)
+		key, err := j.assetKey(v)
+		if err != nil {
+			return nil, err
+		}
+		u := j.newUpload(key, path, nil, getContentType(path))
+		if isSourceMap(v) {
+			j.sourceMapUpload(u, v)
+		}
+		p.Uploads = append(p.Uploads, u)
+	}
+	sort.Slice(p.Uploads, func(a, b int) bool { return p.Uploads[a].Key < p.Uploads[b].Key })
+
+	p.Uploads, err = j.compressUploads(p.Uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := j.journeyFileBody()
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure to put the journey.json, and asset-manifest.json file into {bucket}/{name}/{version}/
+	p.Uploads = append(p.Uploads,
+		j.newUpload(j.GetAssetKey(ManifestFile), j.Manifest, j.ManifestContent, getContentType(ManifestFile)),
+		j.newUpload(j.GetAssetKey(JourneyFile), j.JourneyPath, config, getContentType(JourneyFile)),
+	)
+
+	urls, err := json.Marshal(p.Urls)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the journey urls into json")
+	}
+	p.Uploads = append(p.Uploads, j.newUpload(j.GetAssetKey(j.UrlsFile()), "", urls, "application/javascript"))
+
+	importMaps, err := j.importMapUploads(p.Urls)
+	if err != nil {
+		return nil, err
+	}
+	p.Uploads = append(p.Uploads, importMaps...)
+
+	page, err := j.renderHTML(p.Urls)
+	if err != nil {
+		return nil, err
+	}
+	if page != nil {
+		for _, u := range p.Uploads {
+			if u.Key == j.GetAssetKey(HTMLFile) {
+				return nil, fmt.Errorf("Asset %v is in the build and would be replaced by the page rendered from %v", HTMLFile, j.HTMLTemplate)
+			}
+		}
+		p.Uploads = append(p.Uploads, j.newUpload(j.GetAssetKey(HTMLFile), "", page, "text/html; charset=utf-8"))
+		p.page = page
+	}
+
+	if len(j.Metadata) > 0 {
+		meta, err := json.Marshal(j.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse the metadata into json")
+		}
+		p.Uploads = append(p.Uploads, j.newUpload(j.GetAssetKey(MetadataFile), "", meta, "application/json"))
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,142 @@
+package journey
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// progressWidth How many characters wide the bar is
+const progressWidth = 30
+
+// progressInterval How often the bar is redrawn
+const progressInterval = 200 * time.Millisecond
+
+// Progress Tracks uploads and draws a progress bar for interactive use
+type Progress struct {
+	out        io.Writer
+	totalFiles int
+	totalBytes int64
+	start      time.Time
+	stop       chan struct{}
+	stopped    sync.WaitGroup
+
+	mu           sync.Mutex
+	files        int
+	bytes        int64
+	failed       int
+	failedBytes  int64
+	skipped      int
+	skippedBytes int64
+}
+
+// NewProgress Create progress for the uploads, drawn on out
+func NewProgress(out io.Writer, uploads []*Upload) *Progress {
+	p := &Progress{out: out, totalFiles: len(uploads), stop: make(chan struct{})}
+	for _, u := range uploads {
+		p.totalBytes += u.Size
+	}
+
+	return p
+}
+
+// Start Start redrawing the bar until Stop is called
+func (p *Progress) Start() {
+	p.start = time.Now()
+	p.stopped.Add(1)
+
+	go func() {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				p.draw()
+				fmt.Fprintln(p.out)
+				return
+			case <-ticker.C:
+				p.draw()
+			}
+		}
+	}()
+}
+
+// Stop Draw the bar one last time and stop redrawing it
+func (p *Progress) Stop() {
+	close(p.stop)
+	p.stopped.Wait()
+}
+
+// Done Record an upload that finished, successfully or not
+func (p *Progress) Done(u *Upload, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.failed++
+		p.failedBytes += u.Size
+		return
+	}
+	p.files++
+	p.bytes += u.Size
+}
+
+// Skipped Record uploads that were not needed because the content was already there
+func (p *Progress) Skipped(uploads []*Upload) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, u := range uploads {
+		p.skipped++
+		p.skippedBytes += u.Size
+	}
+}
+
+// draw Draw the bar with file counts, bytes, transfer rate and ETA
+func (p *Progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	finished := p.files + p.failed
+	ratio := 1.0
+	if p.totalBytes > 0 {
+		ratio = float64(p.bytes+p.failedBytes) / float64(p.totalBytes)
+	} else if p.totalFiles > 0 {
+		ratio = float64(finished) / float64(p.totalFiles)
+	}
+	filled := int(ratio * progressWidth)
+
+	elapsed := time.Since(p.start)
+	rate := float64(p.bytes) / elapsed.Seconds()
+	eta := "?"
+	if rate > 0 {
+		remaining := float64(p.totalBytes-p.bytes-p.failedBytes) / rate
+		eta = (time.Duration(remaining) * time.Second).String()
+	}
+
+	fmt.Fprintf(p.out, "\r\033[K[%v%v] %v/%v files  %v/%v  %v/s  ETA %v",
+		strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled),
+		finished, p.totalFiles, FormatSize(p.bytes), FormatSize(p.totalBytes), FormatSize(int64(rate)), eta)
+}
+
+// Summary A table of the uploaded, skipped and failed files
+func (p *Progress) Summary() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tFILES\tSIZE")
+	fmt.Fprintf(w, "Uploaded\t%v\t%v\n", p.files, FormatSize(p.bytes))
+	fmt.Fprintf(w, "Skipped\t%v\t%v\n", p.skipped, FormatSize(p.skippedBytes))
+	fmt.Fprintf(w, "Failed\t%v\t%v\n", p.failed, FormatSize(p.failedBytes))
+	w.Flush()
+
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package journey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// rewriteUrls Point every url in the journey urls at the cdn, keeping the path inside the bucket
+func (j *Journey) rewriteUrls(urls *Urls) {
+	prefix := escapeKey(j.GetJourneyKey(""))
+
+	rewrite := func(url string) string {
+		if i := strings.Index(url, prefix); i >= 0 {
+			return j.CDNDomain + url[i:]
+		}
+		Log.Warnf("Url %v is not inside %v and is left as it is", url, prefix)
+		return url
+	}
+
+	for i := range urls.CSS {
+		urls.CSS[i].URL = rewrite(urls.CSS[i].URL)
+	}
+	for i := range urls.JS {
+		urls.JS[i].URL = rewrite(urls.JS[i].URL)
+	}
+	for i := range urls.Assets {
+		urls.Assets[i].URL = rewrite(urls.Assets[i].URL)
+	}
+	for i := range urls.Preload {
+		urls.Preload[i].URL = rewrite(urls.Preload[i].URL)
+	}
+}
+
+// Promote Server side copy the published version from another bucket into this one, the journey urls are rewritten to the cdn of this bucket
+func (j *Journey) Promote(ctx context.Context, fromBucket string, from Storage, to Storage) error {
+	if ok, err := j.ValidateVersionNotUsed(ctx, to); !ok {
+		return err
+	}
+
+	prefix := j.GetVersionKey(j.Version, "")
+	objects, err := listVersion(ctx, from, prefix)
+	if err != nil {
+		return fmt.Errorf("Unable to list %v in %v: %v", prefix, fromBucket, err)
+	}
+	if _, ok := objects[JourneyFile]; !ok {
+		return fmt.Errorf("Version %v/%v is not published in %v", j.Name, j.Version, fromBucket)
+	}
+
+	urls, err := getJourneyUrls(ctx, from, j.GetAssetKey(j.UrlsFile()))
+	if err != nil {
+		return err
+	}
+	shared, err := j.sharedUrlKeys(urls)
+	if err != nil {
+		return err
+	}
+	j.rewriteUrls(urls)
+
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the journey urls into json")
+	}
+
+	// copy the files first and journey.json last so the version only shows up once it is complete
+	var files []string
+	for file := range objects {
+		switch file {
+		case JourneyFile, j.UrlsFile():
+		default:
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	var copied []string
+	fail := func(err error) error {
+		j.cleanup(to, copied)
+		return err
+	}
+
+	for _, file := range files {
+		key := prefix + file
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+
+		Log.Debugf("Copying %v from %v", key, fromBucket)
+		if err := to.CopyFrom(ctx, fromBucket, key, key); err != nil {
+			return fail(fmt.Errorf("Unable to copy %v from %v: %v", key, fromBucket, err))
+		}
+		copied = append(copied, key)
+	}
+
+	// shared chunks may be used by other versions, so they are only copied when missing and never cleaned up
+	for _, key := range shared {
+		if _, err := to.Head(ctx, key); err == nil {
+			continue
+		} else if err != ErrNotFound {
+			return fail(fmt.Errorf("Unable to check %v: %v", key, err))
+		}
+
+		Log.Debugf("Copying %v from %v", key, fromBucket)
+		if err := to.CopyFrom(ctx, fromBucket, key, key); err != nil {
+			return fail(fmt.Errorf("Unable to copy %v from %v: %v", key, fromBucket, err))
+		}
+	}
+
+	u := j.newUpload(j.GetAssetKey(j.UrlsFile()), "", data, "application/javascript")
+	if err := upload(ctx, to, u); err != nil {
+		return fail(fmt.Errorf("Unable to upload %v: %v", u.Key, err))
+	}
+	copied = append(copied, u.Key)
+
+	key := j.GetAssetKey(JourneyFile)
+	if err := to.CopyFrom(ctx, fromBucket, key, key); err != nil {
+		return fail(fmt.Errorf("Unable to copy %v from %v: %v", key, fromBucket, err))
+	}
+
+	Log.Event(fmt.Sprintf("Promoted %v/%v from %v to %v", j.Name, j.Version, fromBucket, j.Bucket), Fields{
+		"name":    j.Name,
+		"version": j.Version,
+		"from":    fromBucket,
+		"to":      j.Bucket,
+		"files":   len(copied) + 1,
+	})
+
+	return nil
+}
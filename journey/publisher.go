@@ -0,0 +1,98 @@
+package journey
+
+import (
+	"context"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Publisher Publishes a journey to storage, for Go programs that embed publishing instead of running the cli
+type Publisher struct {
+	Journey *Journey
+	Store   Storage
+}
+
+// PublisherOption Configures a Publisher
+type PublisherOption func(*Publisher)
+
+// WithStorage Publish to the storage instead of the S3 bucket of the journey, eg: a mock in tests
+func WithStorage(store Storage) PublisherOption {
+	return func(p *Publisher) {
+		p.Store = store
+	}
+}
+
+// WithLogger Log with the logger. The journey package has one logger, so this replaces it for the whole process
+func WithLogger(l *Logger) PublisherOption {
+	return func(p *Publisher) {
+		Log = l
+	}
+}
+
+// WithConcurrency Upload this many files at the same time
+func WithConcurrency(n int) PublisherOption {
+	return func(p *Publisher) {
+		p.Journey.Concurrency = n
+	}
+}
+
+// NewPublisher Validate the journey and create a publisher for it, without a storage option the S3 bucket
+// of the journey is used with the default AWS credentials
+func NewPublisher(j *Journey, opts ...PublisherOption) (*Publisher, error) {
+	p := &Publisher{Journey: j}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if len(j.Region) <= 0 {
+		j.Region = DefaultRegion
+	}
+
+	if err := j.Validate(validator.New()); err != nil {
+		return nil, err
+	}
+
+	if p.Store == nil {
+		sess, err := j.NewSession(j.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Store, err = NewStorage(j.Storage, j.Bucket, sess, j.StorageOptions())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Publish Upload the assets as a new version, assets maps names to paths in the build like the asset manifest
+func (p *Publisher) Publish(ctx context.Context, assets map[string]string) (*Report, error) {
+	return p.Journey.Publish(ctx, assets, p.Store)
+}
+
+// Sync Upload the assets that changed in a version that may already exist
+func (p *Publisher) Sync(ctx context.Context, assets map[string]string) (*Report, error) {
+	return p.Journey.Sync(ctx, assets, p.Store)
+}
+
+// SetLatest Point latest at the version of the journey
+func (p *Publisher) SetLatest(ctx context.Context) error {
+	return p.Journey.SetLatest(ctx, p.Store)
+}
+
+// Rollback Point latest back at a previous version
+func (p *Publisher) Rollback(ctx context.Context, version string) error {
+	return p.Journey.Rollback(ctx, version, p.Store)
+}
+
+// Versions List the published versions, oldest first
+func (p *Publisher) Versions(ctx context.Context) ([]*VersionInfo, error) {
+	return p.Journey.ListVersions(ctx, p.Store)
+}
+
+// Verify Check the published version is complete
+func (p *Publisher) Verify(ctx context.Context) error {
+	return p.Journey.Verify(ctx, p.Store)
+}
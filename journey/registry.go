@@ -0,0 +1,69 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// registryTimeout How long registering a version may take
+const registryTimeout = 30 * time.Second
+
+// Registry The journey-registry service published versions are registered with
+type Registry struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// RegistryVersion The version registered with journey-registry
+type RegistryVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Urls    *Urls  `json:"urls"`
+}
+
+// register Register the published version and its urls with journey-registry so it does not have to poll the bucket
+func (j *Journey) register(ctx context.Context, urls *Urls) error {
+	if len(j.Registry.URL) <= 0 || j.SkipRegistry {
+		return nil
+	}
+
+	body, err := json.Marshal(&RegistryVersion{
+		Name:    j.Name,
+		Version: j.Version,
+		URL:     j.CDNDomain + j.GetAssetKey(j.UrlsFile()),
+		Urls:    urls,
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to parse the registry version into json")
+	}
+
+	url := strings.TrimSuffix(j.Registry.URL, "/") + "/journeys/" + j.Name + "/versions"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Registry url %v is not valid: %v", j.Registry.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(j.Registry.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+j.Registry.Token)
+	}
+
+	client := &http.Client{Timeout: registryTimeout}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Version %v/%v was published but could not be registered with %v: %v", j.Name, j.Version, url, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Version %v/%v was published but could not be registered with %v: %v", j.Name, j.Version, url, res.Status)
+	}
+
+	Log.Infof("Registered %v/%v with %v", j.Name, j.Version, j.Registry.URL)
+	return nil
+}
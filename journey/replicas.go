@@ -0,0 +1,79 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+)
+
+// replica The journey as it is published to the replica, the cdn and region fall back to the ones of the journey
+func (j *Journey) replica(r Environment) *Journey {
+	c := *j
+	c.Bucket = r.Bucket
+	if len(r.CDNDomain) > 0 {
+		c.CDNDomain = r.CDNDomain
+	}
+	if len(r.Region) > 0 {
+		c.Region = r.Region
+	}
+	if len(r.Endpoint) > 0 {
+		c.Endpoint = r.Endpoint
+		c.PathStyle = r.PathStyle
+	}
+	if len(r.User) > 0 {
+		c.User = r.User
+		c.Password = r.Password
+	}
+
+	return &c
+}
+
+// validateReplicas Validate every replica has a bucket, and normalize the cdn of the ones that have their own
+func validateReplicas(replicas []Environment) error {
+	for i, r := range replicas {
+		if len(r.Bucket) <= 0 {
+			return fmt.Errorf("Replica %v does not have a bucket", i+1)
+		}
+		if len(r.CDNDomain) > 0 {
+			cdn, err := NormalizeCDNDomain(r.CDNDomain)
+			if err != nil {
+				return fmt.Errorf("Replica %v: %v", i+1, err)
+			}
+			replicas[i].CDNDomain = cdn
+		}
+	}
+
+	return nil
+}
+
+// PublishReplicas Server side copy the published version from store to every replica bucket,
+// the journey urls of each replica point at its own cdn
+func (j *Journey) PublishReplicas(ctx context.Context, store Storage) error {
+	if len(j.Replicas) <= 0 {
+		return nil
+	}
+	if j.DryRun {
+		Log.Infof("Dry run, %v/%v would be copied to %v replicas", j.Name, j.Version, len(j.Replicas))
+		return nil
+	}
+
+	for _, r := range j.Replicas {
+		c := j.replica(r)
+
+		sess, err := c.NewSession(c.Region)
+		if err != nil {
+			return err
+		}
+
+		to, err := NewStorage(c.Storage, c.Bucket, sess, c.StorageOptions())
+		if err != nil {
+			return err
+		}
+
+		if err := c.Promote(ctx, j.Bucket, store, to); err != nil {
+			return fmt.Errorf("Unable to copy %v/%v to the replica %v in %v: %v", j.Name, j.Version, c.Bucket, c.Region, err)
+		}
+		Log.Infof("Replica %v in %v: %v", c.Bucket, c.Region, c.CDNDomain+c.GetAssetKey(c.UrlsFile()))
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package journey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Report What a publish uploaded, written for the pipeline steps that run after it
+type Report struct {
+	Name        string        `json:"name"`
+	Version     string        `json:"version"`
+	Environment string        `json:"environment,omitempty"`
+	Bucket      string        `json:"bucket"`
+	URL         string        `json:"url"`
+	Duration    float64       `json:"duration"`
+	Files       []*ReportFile `json:"files"`
+}
+
+// ReportFile A single file in the report, skipped files were already in the bucket with the same content
+type ReportFile struct {
+	Key             string  `json:"key"`
+	URL             string  `json:"url"`
+	Size            int64   `json:"size"`
+	ETag            string  `json:"etag"`
+	ContentType     string  `json:"contentType"`
+	ContentEncoding string  `json:"contentEncoding,omitempty"`
+	Duration        float64 `json:"duration"`
+	Skipped         bool    `json:"skipped,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// buildReport Build the report of the uploaded and skipped files, with the error of the uploads that failed
+func (j *Journey) buildReport(uploads []*Upload, skipped []*Upload, failed map[string]error, duration time.Duration) (*Report, error) {
+	r := &Report{
+		Name:        j.Name,
+		Version:     j.Version,
+		Environment: j.Environment,
+		Bucket:      j.Bucket,
+		URL:         j.CDNDomain + j.GetAssetKey(j.UrlsFile()),
+		Duration:    duration.Seconds(),
+	}
+
+	add := func(u *Upload, skip bool) error {
+		etag, err := u.etag()
+		if err != nil {
+			return fmt.Errorf("Unable to compute checksum of %v: %v", u.Path, err)
+		}
+
+		f := &ReportFile{
+			Key:             u.Key,
+			URL:             j.CDNDomain + escapeKey(u.Key),
+			Size:            u.Size,
+			ETag:            etag,
+			ContentType:     u.ContentType,
+			ContentEncoding: u.ContentEncoding,
+			Duration:        u.Duration.Seconds(),
+			Skipped:         skip,
+		}
+		if err, ok := failed[u.Key]; ok {
+			f.Error = err.Error()
+		}
+		r.Files = append(r.Files, f)
+		return nil
+	}
+
+	for _, u := range uploads {
+		if err := add(u, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range skipped {
+		if err := add(u, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// writeReport Write the report of the publish to the report file
+func (j *Journey) writeReport(r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to parse the report into json")
+	}
+
+	if err := ioutil.WriteFile(j.Report, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("Unable to write the report to %v: %v", j.Report, err)
+	}
+	Log.Infof("Wrote the report to %v", j.Report)
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// DefaultRetries How many times a storage operation is attempted unless configured otherwise
+const DefaultRetries = 5
+
+// Backoff between attempts, doubled after every attempt up to the max and jittered so workers do not retry in step
+const (
+	retryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff = 20 * time.Second
+)
+
+// retryStorage Storage that retries operations that failed for a reason that may go away, like throttling or a 503
+type retryStorage struct {
+	store    Storage
+	attempts int
+}
+
+// withRetries Wrap the storage so every operation is attempted up to attempts times
+func withRetries(store Storage, attempts int) Storage {
+	if attempts <= 1 {
+		return store
+	}
+
+	return &retryStorage{store: store, attempts: attempts}
+}
+
+// retry Run fn until it succeeds, fails for good, the attempts run out or ctx is done
+func (s *retryStorage) retry(ctx context.Context, op string, fn func() error) error {
+	backoff := retryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if attempt >= s.attempts {
+			return fmt.Errorf("%v failed after %v attempts: %v", op, attempt, err)
+		}
+
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		Log.Warnf("%v failed, retrying in %v: %v", op, wait, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// Upload Upload with retries, the body can only be sent again when it can be rewound
+func (s *retryStorage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	seeker, ok := body.(io.Seeker)
+	if !ok {
+		return s.store.Upload(ctx, key, body, opts)
+	}
+
+	first := true
+	return s.retry(ctx, "Uploading "+key, func() error {
+		if !first {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		return s.store.Upload(ctx, key, body, opts)
+	})
+}
+
+// Head Head with retries
+func (s *retryStorage) Head(ctx context.Context, key string) (*Object, error) {
+	var o *Object
+	err := s.retry(ctx, "Checking "+key, func() error {
+		var err error
+		o, err = s.store.Head(ctx, key)
+		return err
+	})
+
+	return o, err
+}
+
+// Get Get with retries, reading the body is not retried
+func (s *retryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := s.retry(ctx, "Getting "+key, func() error {
+		var err error
+		body, err = s.store.Get(ctx, key)
+		return err
+	})
+
+	return body, err
+}
+
+// Copy Copy with retries
+func (s *retryStorage) Copy(ctx context.Context, from string, to string) error {
+	return s.retry(ctx, "Copying "+from, func() error {
+		return s.store.Copy(ctx, from, to)
+	})
+}
+
+// CopyFrom CopyFrom with retries
+func (s *retryStorage) CopyFrom(ctx context.Context, bucket string, from string, to string) error {
+	return s.retry(ctx, "Copying "+bucket+"/"+from, func() error {
+		return s.store.CopyFrom(ctx, bucket, from, to)
+	})
+}
+
+// SetStorageClass SetStorageClass with retries
+func (s *retryStorage) SetStorageClass(ctx context.Context, key string, class string) error {
+	return s.retry(ctx, "Moving "+key+" to "+class, func() error {
+		return s.store.SetStorageClass(ctx, key, class)
+	})
+}
+
+// Restore Restore with retries
+func (s *retryStorage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	var restored bool
+	err := s.retry(ctx, "Restoring "+key, func() error {
+		var err error
+		restored, err = s.store.Restore(ctx, key, days)
+		return err
+	})
+
+	return restored, err
+}
+
+// Delete Delete with retries, deleting a key that is already gone succeeds
+func (s *retryStorage) Delete(ctx context.Context, keys ...string) error {
+	return s.retry(ctx, fmt.Sprintf("Deleting %v keys", len(keys)), func() error {
+		return s.store.Delete(ctx, keys...)
+	})
+}
+
+// ListIncompleteUploads ListIncompleteUploads with retries
+func (s *retryStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	var uploads []*IncompleteUpload
+	err := s.retry(ctx, "Listing the incomplete uploads of "+prefix, func() error {
+		var err error
+		uploads, err = s.store.ListIncompleteUploads(ctx, prefix)
+		return err
+	})
+
+	return uploads, err
+}
+
+// AbortUpload AbortUpload with retries
+func (s *retryStorage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	return s.retry(ctx, "Aborting the upload of "+key, func() error {
+		return s.store.AbortUpload(ctx, key, uploadID)
+	})
+}
+
+// List List with retries
+func (s *retryStorage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	var list []*Object
+	err := s.retry(ctx, "Listing "+prefix, func() error {
+		var err error
+		list, err = s.store.List(ctx, prefix)
+		return err
+	})
+
+	return list, err
+}
+
+// ListPrefixes ListPrefixes with retries
+func (s *retryStorage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	var prefixes []string
+	err := s.retry(ctx, "Listing "+prefix, func() error {
+		var err error
+		prefixes, err = s.store.ListPrefixes(ctx, prefix)
+		return err
+	})
+
+	return prefixes, err
+}
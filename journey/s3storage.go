@@ -0,0 +1,498 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Storage Storage backed by an S3 bucket
+type S3Storage struct {
+	Bucket   string
+	opts     StorageOptions
+	svc      S3API
+	uploader Uploader
+}
+
+// S3API The S3 calls the storage makes, satisfied by *s3.S3 and fakes pointed at MinIO or localstack
+type S3API interface {
+	HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error)
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	CopyObjectWithContext(ctx aws.Context, input *s3.CopyObjectInput, opts ...request.Option) (*s3.CopyObjectOutput, error)
+	DeleteObjectsWithContext(ctx aws.Context, input *s3.DeleteObjectsInput, opts ...request.Option) (*s3.DeleteObjectsOutput, error)
+	ListObjectsV2PagesWithContext(ctx aws.Context, input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, opts ...request.Option) error
+	ListMultipartUploadsPagesWithContext(ctx aws.Context, input *s3.ListMultipartUploadsInput, fn func(*s3.ListMultipartUploadsOutput, bool) bool, opts ...request.Option) error
+	AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput, opts ...request.Option) (*s3.AbortMultipartUploadOutput, error)
+	RestoreObjectWithContext(ctx aws.Context, input *s3.RestoreObjectInput, opts ...request.Option) (*s3.RestoreObjectOutput, error)
+}
+
+// Uploader The upload call the storage makes, satisfied by *s3manager.Uploader
+type Uploader interface {
+	UploadWithContext(ctx aws.Context, input *s3manager.UploadInput, opts ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
+}
+
+// Server side encryption settings for S3
+const (
+	EncryptionAES256 = s3.ServerSideEncryptionAes256
+	EncryptionKMS    = s3.ServerSideEncryptionAwsKms
+)
+
+// validateEncryption Validate the server side encryption settings
+func validateEncryption(encryption string, kmsKeyID string) error {
+	switch encryption {
+	case "", EncryptionAES256:
+		if len(kmsKeyID) > 0 {
+			return fmt.Errorf("kmsKeyID is only used with %v encryption", EncryptionKMS)
+		}
+		return nil
+	case EncryptionKMS:
+		return nil
+	default:
+		return fmt.Errorf("Encryption %v is not supported, use %v or %v", encryption, EncryptionAES256, EncryptionKMS)
+	}
+}
+
+// Canned ACLs that can be applied to uploaded objects
+const (
+	ACLPrivate                = s3.ObjectCannedACLPrivate
+	ACLPublicRead             = s3.ObjectCannedACLPublicRead
+	ACLBucketOwnerFullControl = s3.ObjectCannedACLBucketOwnerFullControl
+)
+
+// validateACL Validate the acl is one we know how to apply
+func validateACL(acl string) error {
+	switch acl {
+	case "", ACLPrivate, ACLPublicRead, ACLBucketOwnerFullControl:
+		return nil
+	default:
+		return fmt.Errorf("ACL %v is not supported, use %v, %v or %v", acl, ACLPrivate, ACLPublicRead, ACLBucketOwnerFullControl)
+	}
+}
+
+// validateObjectTags Validate the tags fit the limits S3 puts on object tags
+func validateObjectTags(tags map[string]string) error {
+	if len(tags) > 10 {
+		return fmt.Errorf("S3 objects can have at most 10 tags, objectTags has %v", len(tags))
+	}
+	for k, v := range tags {
+		if len(k) <= 0 || len(k) > 128 {
+			return fmt.Errorf("Object tag %v is not valid, tag keys are 1 to 128 characters", k)
+		}
+		if len(v) > 256 {
+			return fmt.Errorf("Object tag %v is not valid, tag values are at most 256 characters", k)
+		}
+	}
+
+	return nil
+}
+
+// tagging Encode the tags like the x-amz-tagging header expects them
+func tagging(tags map[string]string) string {
+	v := url.Values{}
+	for k, t := range tags {
+		v.Set(k, t)
+	}
+
+	return v.Encode()
+}
+
+// Multipart How large assets are split into parts, the part size, how many parts of a file go at once
+// and whether the parts already uploaded are kept when the upload fails
+type Multipart struct {
+	PartSize          string `json:"partSize"`
+	Concurrency       int    `json:"concurrency"`
+	LeavePartsOnError bool   `json:"leavePartsOnError"`
+}
+
+// validate Validate the part size is one S3 accepts
+func (m *Multipart) validate() error {
+	size, err := ParseSize(m.PartSize)
+	if err != nil {
+		return err
+	}
+	if size > 0 && size < s3manager.MinUploadPartSize {
+		return fmt.Errorf("Multipart partSize %v is too small, S3 needs parts of at least %v", m.PartSize, FormatSize(s3manager.MinUploadPartSize))
+	}
+	if m.Concurrency < 0 {
+		return fmt.Errorf("Multipart concurrency %v can not be negative", m.Concurrency)
+	}
+
+	return nil
+}
+
+// NewS3Storage Create storage for the bucket using the session, talking to the custom endpoint when there is one
+func NewS3Storage(bucket string, sess *session.Session, opts StorageOptions) *S3Storage {
+	config := &aws.Config{}
+	if len(opts.Endpoint) > 0 {
+		config.Endpoint = aws.String(opts.Endpoint)
+	}
+	if opts.PathStyle {
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	// withRetries attempts every request, the sdk retrying inside each attempt would multiply them
+	if opts.Retries > 1 {
+		config.MaxRetries = aws.Int(0)
+	}
+
+	svc := s3.New(sess, config)
+	uploader := s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		if opts.PartSize > 0 {
+			u.PartSize = opts.PartSize
+		}
+		if opts.PartConcurrency > 0 {
+			u.Concurrency = opts.PartConcurrency
+		}
+		u.LeavePartsOnError = opts.LeavePartsOnError
+	})
+
+	return NewS3StorageWithClients(bucket, svc, uploader, opts)
+}
+
+// NewS3StorageWithClients Create storage for the bucket using the given clients
+func NewS3StorageWithClients(bucket string, svc S3API, uploader Uploader, opts StorageOptions) *S3Storage {
+	return &S3Storage{
+		Bucket:   bucket,
+		opts:     opts,
+		svc:      svc,
+		uploader: uploader,
+	}
+}
+
+// notFound Translate the S3 not found errors into ErrNotFound
+func notFound(err error) error {
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// isRetryable Check if the request failed for a reason that may go away when it is sent again
+func isRetryable(err error) bool {
+	if isThrottled(err) {
+		return true
+	}
+	if serr, ok := err.(*statusError); ok {
+		return serr.StatusCode >= http.StatusInternalServerError || serr.StatusCode == http.StatusRequestTimeout
+	}
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "RequestError", "RequestTimeout", "InternalError":
+			return true
+		}
+	}
+
+	return false
+}
+
+// isThrottled Check if S3, or another storage backend, rejected the request because we are sending too many
+func isThrottled(err error) bool {
+	if serr, ok := err.(*statusError); ok {
+		return serr.StatusCode == http.StatusServiceUnavailable || serr.StatusCode == http.StatusTooManyRequests
+	}
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusServiceUnavailable {
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded":
+			return true
+		}
+	}
+
+	return false
+}
+
+// Upload Upload the body to the key with the headers in opts
+func (s *S3Storage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(opts.ContentType),
+	}
+
+	if len(opts.CacheControl) > 0 {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if len(opts.ContentEncoding) > 0 {
+		input.ContentEncoding = aws.String(opts.ContentEncoding)
+	}
+	if len(opts.ACL) > 0 {
+		input.ACL = aws.String(opts.ACL)
+	} else if len(s.opts.ACL) > 0 {
+		input.ACL = aws.String(s.opts.ACL)
+	}
+	if len(s.opts.Encryption) > 0 {
+		input.ServerSideEncryption = aws.String(s.opts.Encryption)
+	}
+	if len(s.opts.KMSKeyID) > 0 {
+		input.SSEKMSKeyId = aws.String(s.opts.KMSKeyID)
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = aws.StringMap(opts.Metadata)
+	}
+	if len(s.opts.Tags) > 0 {
+		input.Tagging = aws.String(tagging(s.opts.Tags))
+	}
+	if len(opts.StorageClass) > 0 {
+		input.StorageClass = aws.String(opts.StorageClass)
+	}
+
+	_, err := s.uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+// Head Get the object at key without its content
+func (s *S3Storage) Head(ctx context.Context, key string) (*Object, error) {
+	out, err := s.svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &Object{
+		Key:          key,
+		Size:         aws.Int64Value(out.ContentLength),
+		ETag:         strings.Trim(aws.StringValue(out.ETag), `"`),
+		LastModified: aws.TimeValue(out.LastModified),
+		ContentType:  aws.StringValue(out.ContentType),
+		Metadata:     aws.StringValueMap(out.Metadata),
+	}, nil
+}
+
+// Get Get the content of the object at key, the caller must close it
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return out.Body, nil
+}
+
+// Copy Server side copy an object inside the bucket
+func (s *S3Storage) Copy(ctx context.Context, from string, to string) error {
+	return s.CopyFrom(ctx, s.Bucket, from, to)
+}
+
+// CopyFrom Server side copy an object from another bucket into this one
+func (s *S3Storage) CopyFrom(ctx context.Context, bucket string, from string, to string) error {
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.Bucket),
+		CopySource: aws.String(url.PathEscape(bucket + "/" + from)),
+		Key:        aws.String(to),
+	}
+
+	// a copy is not encrypted like the source, nor does it keep its acl, unless asked to
+	if len(s.opts.ACL) > 0 {
+		input.ACL = aws.String(s.opts.ACL)
+	}
+	if len(s.opts.Encryption) > 0 {
+		input.ServerSideEncryption = aws.String(s.opts.Encryption)
+	}
+	if len(s.opts.KMSKeyID) > 0 {
+		input.SSEKMSKeyId = aws.String(s.opts.KMSKeyID)
+	}
+	// the tags of this bucket win over the ones of an object copied from another bucket
+	if len(s.opts.Tags) > 0 {
+		input.Tagging = aws.String(tagging(s.opts.Tags))
+		input.TaggingDirective = aws.String(s3.TaggingDirectiveReplace)
+	}
+
+	_, err := s.svc.CopyObjectWithContext(ctx, input)
+	return notFound(err)
+}
+
+// SetStorageClass Copy the object onto itself in the storage class, keeping its headers and metadata
+func (s *S3Storage) SetStorageClass(ctx context.Context, key string, class string) error {
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(s.Bucket),
+		CopySource:        aws.String(url.PathEscape(s.Bucket + "/" + key)),
+		Key:               aws.String(key),
+		StorageClass:      aws.String(class),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+	}
+
+	if len(s.opts.ACL) > 0 {
+		input.ACL = aws.String(s.opts.ACL)
+	}
+	if len(s.opts.Encryption) > 0 {
+		input.ServerSideEncryption = aws.String(s.opts.Encryption)
+	}
+	if len(s.opts.KMSKeyID) > 0 {
+		input.SSEKMSKeyId = aws.String(s.opts.KMSKeyID)
+	}
+
+	_, err := s.svc.CopyObjectWithContext(ctx, input)
+	return notFound(err)
+}
+
+// Restore Ask for a temporary copy of an archived object kept for days, true once the copy can be read
+func (s *S3Storage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	out, err := s.svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return false, notFound(err)
+	}
+
+	// x-amz-restore is ongoing-request="true" while restoring and "false" once the copy is there
+	switch restore := aws.StringValue(out.Restore); {
+	case strings.Contains(restore, `ongoing-request="false"`):
+		return true, nil
+	case strings.Contains(restore, `ongoing-request="true"`):
+		return false, nil
+	}
+
+	_, err = s.svc.RestoreObjectWithContext(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &s3.RestoreRequest{
+			Days:                 aws.Int64(int64(days)),
+			GlacierJobParameters: &s3.GlacierJobParameters{Tier: aws.String(s3.TierStandard)},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "RestoreAlreadyInProgress":
+			return false, nil
+		case s3.ErrCodeObjectAlreadyInActiveTierError:
+			return true, nil
+		}
+	}
+
+	return false, notFound(err)
+}
+
+// Delete Delete the objects in batches of the most S3 allows per request
+func (s *S3Storage) Delete(ctx context.Context, keys ...string) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > 1000 {
+			n = 1000
+		}
+
+		var ids []*s3.ObjectIdentifier
+		for _, k := range keys[:n] {
+			ids = append(ids, &s3.ObjectIdentifier{Key: aws.String(k)})
+		}
+
+		out, err := s.svc.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.Bucket),
+			Delete: &s3.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return fmt.Errorf("Unable to delete %v and %v other objects: %v", aws.StringValue(e.Key), len(out.Errors)-1, aws.StringValue(e.Message))
+		}
+
+		keys = keys[n:]
+	}
+
+	return nil
+}
+
+// List List every object under the prefix
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	var objects []*Object
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	err := s.svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			objects = append(objects, &Object{
+				Key:          aws.StringValue(o.Key),
+				Size:         aws.Int64Value(o.Size),
+				ETag:         strings.Trim(aws.StringValue(o.ETag), `"`),
+				LastModified: aws.TimeValue(o.LastModified),
+				StorageClass: aws.StringValue(o.StorageClass),
+			})
+		}
+		return true
+	})
+
+	return objects, err
+}
+
+// ListPrefixes List the common prefixes one level below the prefix, like directories
+func (s *S3Storage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	var prefixes []string
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+
+	err := s.svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, p := range page.CommonPrefixes {
+			prefixes = append(prefixes, aws.StringValue(p.Prefix))
+		}
+		return true
+	})
+
+	return prefixes, err
+}
+
+// ListIncompleteUploads List the multipart uploads under the prefix that were never completed or aborted
+func (s *S3Storage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	var uploads []*IncompleteUpload
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	err := s.svc.ListMultipartUploadsPagesWithContext(ctx, input, func(page *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+		for _, u := range page.Uploads {
+			uploads = append(uploads, &IncompleteUpload{
+				Key:       aws.StringValue(u.Key),
+				UploadID:  aws.StringValue(u.UploadId),
+				Initiated: aws.TimeValue(u.Initiated),
+			})
+		}
+		return true
+	})
+
+	return uploads, err
+}
+
+// AbortUpload Abort the multipart upload, which deletes the parts already uploaded
+func (s *S3Storage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	_, err := s.svc.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.Bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+
+	return notFound(err)
+}
@@ -0,0 +1,88 @@
+package journey
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// newTestSession A session that never looks for credentials or config on the machine running the tests
+func newTestSession(t *testing.T) *session.Session {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(DefaultRegion),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("Unable to create a session: %v", err)
+	}
+
+	return sess
+}
+
+func TestIsRetryable(t *testing.T) {
+	failure := func(code string, status int) error {
+		return awserr.NewRequestFailure(awserr.New(code, code, nil), status, "request-id")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"service unavailable", failure("ServiceUnavailable", http.StatusServiceUnavailable), true},
+		{"slow down", failure("SlowDown", http.StatusServiceUnavailable), true},
+		{"request timeout", failure("RequestTimeout", http.StatusBadRequest), true},
+		{"internal error", failure("InternalError", http.StatusInternalServerError), true},
+		{"throttling without a status", awserr.New("Throttling", "Rate exceeded", nil), true},
+		{"connection failed", awserr.New("RequestError", "send request failed", nil), true},
+		{"access denied", failure("AccessDenied", http.StatusForbidden), false},
+		{"no such key", failure(s3.ErrCodeNoSuchKey, http.StatusNotFound), false},
+		{"not found", failure("NotFound", http.StatusNotFound), false},
+		{"not an aws error", errors.New("boom"), false},
+		{"http service unavailable", &statusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"http too many requests", &statusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"http internal error", &statusError{StatusCode: http.StatusInternalServerError}, true},
+		{"http forbidden", &statusError{StatusCode: http.StatusForbidden}, false},
+		{"http precondition failed", &statusError{StatusCode: http.StatusPreconditionFailed}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRetryable(test.err); got != test.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewS3StorageRetries(t *testing.T) {
+	sess := newTestSession(t)
+
+	tests := []struct {
+		name    string
+		retries int
+		want    int
+	}{
+		{"wrapped, the sdk does not retry", DefaultRetries, 0},
+		{"not wrapped, the sdk retries", 1, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewS3Storage("bucket", sess, StorageOptions{Retries: test.retries})
+			svc, ok := store.svc.(*s3.S3)
+			if !ok {
+				t.Fatalf("S3Storage client is a %T, want *s3.S3", store.svc)
+			}
+			if got := svc.Client.Config.MaxRetries; got == nil || *got != test.want {
+				t.Errorf("MaxRetries = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package journey
+
+// JourneySchema JSON Schema of journey.json, point $schema at it for completion and checks in editors
+const JourneySchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "journey.json",
+  "type": "object",
+  "required": ["name", "version", "rootID", "build", "manifest", "bucket", "cdn"],
+  "additionalProperties": false,
+  "definitions": {
+    "environment": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "bucket": {"type": "string", "description": "AWS S3 bucket"},
+        "cdn": {"type": "string", "description": "Domain the bucket is served from, eg: https://cdn.example.com/"},
+        "region": {"type": "string"},
+        "endpoint": {"type": "string"},
+        "pathStyle": {"type": "boolean"},
+        "user": {"type": "string"},
+        "password": {"type": "string"},
+        "replicas": {"type": "array", "items": {"$ref": "#/definitions/environment"}},
+        "protected": {"type": "boolean", "description": "Ask before moving latest or deleting versions"}
+      }
+    },
+    "globs": {"type": "array", "items": {"type": "string"}},
+    "size": {"type": "string", "description": "A size like 500KB or 2MB"}
+  },
+  "properties": {
+    "$schema": {"type": "string"},
+    "name": {"type": "string", "minLength": 1, "description": "Name of the journey, the first part of every key"},
+    "version": {"type": "string", "minLength": 1, "description": "Version to publish, a semantic version unless -skip-semver is set"},
+    "rootID": {"type": "string", "minLength": 1, "description": "Id of the element the journey renders into"},
+    "build": {"type": "string", "minLength": 1, "description": "Build directory"},
+    "manifest": {"type": "string", "minLength": 1, "description": "Asset manifest in the build"},
+    "bucket": {"type": "string", "minLength": 1, "description": "AWS S3 bucket"},
+    "cdn": {"type": "string", "minLength": 1, "description": "Domain the bucket is served from, eg: https://cdn.example.com/"},
+    "manifestFormat": {"enum": ["", "flat", "webpack", "vite", "cra"]},
+    "storage": {"enum": ["", "s3", "webdav", "gcs", "azure", "sftp"]},
+    "region": {"type": "string"},
+    "prefix": {"type": "string"},
+    "paths": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "urlsFile": {"type": "string", "description": "Name of the urls file of every version, defaults to journey-urls.json"},
+        "keys": {"type": "string", "description": "Go template of the key of every version ending in the version, defaults to {{.Name}}/{{.Version}}"}
+      }
+    },
+    "endpoint": {"type": "string"},
+    "pathStyle": {"type": "boolean"},
+    "user": {"type": "string", "description": "User of the WebDAV or SFTP server"},
+    "password": {"type": "string", "description": "Password of the WebDAV or SFTP server, eg: ${DAV_PASSWORD}"},
+    "encryption": {"enum": ["", "AES256", "aws:kms"]},
+    "kmsKeyID": {"type": "string"},
+    "acl": {"enum": ["", "private", "public-read", "bucket-owner-full-control"]},
+    "storageClass": {"enum": ["", "STANDARD", "STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING"]},
+    "storageClasses": {"type": "object", "additionalProperties": {"enum": ["STANDARD", "STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING"]}},
+    "objectTags": {"type": "object", "maxProperties": 10, "additionalProperties": {"type": "string", "maxLength": 256}},
+    "environments": {"type": "object", "additionalProperties": {"$ref": "#/definitions/environment"}},
+    "replicas": {"type": "array", "items": {"$ref": "#/definitions/environment"}},
+    "protected": {"type": "boolean", "description": "Ask before moving latest or deleting versions"},
+    "symlinks": {"enum": ["", "follow", "skip", "error"]},
+    "includeHidden": {"type": "boolean"},
+    "warnAssetSize": {"$ref": "#/definitions/size"},
+    "maxAssetSize": {"$ref": "#/definitions/size"},
+    "emptyFiles": {"enum": ["", "warn", "error"]},
+    "keys": {"enum": ["", "encode", "reject", "normalize"]},
+    "includeAll": {"type": "boolean"},
+    "include": {"$ref": "#/definitions/globs"},
+    "exclude": {"$ref": "#/definitions/globs"},
+    "layout": {"enum": ["", "version", "cas"], "description": "Publish assets under the version, or under {name}/cas/ by the sha256 of their content"},
+    "sharedChunks": {"$ref": "#/definitions/globs", "description": "Chunks published once under {name}/static/ by the sha256 of their content"},
+    "entrypoints": {"type": "array", "items": {"type": "string"}},
+    "sizeBudgets": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["maxSize"],
+        "properties": {
+          "files": {"type": "string", "description": "Glob of the files the budget applies to, eg: **/*.js"},
+          "entry": {"type": "boolean", "description": "Only the entrypoints"},
+          "maxSize": {"type": "string", "description": "Most each file may weigh, eg: 300KB"},
+          "gzip": {"type": "boolean", "description": "Check the gzipped size"},
+          "total": {"type": "boolean", "description": "Check the size of the files together"},
+          "warn": {"type": "boolean", "description": "Only warn when the budget is exceeded"}
+        }
+      }
+    },
+    "htmlTemplate": {"type": "string"},
+    "importMap": {"type": "boolean"},
+    "dependencies": {
+      "type": "array",
+      "description": "Journeys in the bucket loaded before this one",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name", "version"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "version": {"type": "string", "minLength": 1, "description": "A version range like ^1.2.0, ~1.2.0, 1.x or >=1.0.0 <2.0.0, or latest"}
+        }
+      }
+    },
+    "importMapName": {"type": "string"},
+    "outputFormats": {"type": "array", "items": {"enum": ["journey", "single-spa"]}, "uniqueItems": true, "contains": {"const": "journey"}},
+    "singleSpa": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "activeWhen": {"type": "array", "items": {"type": "string"}, "description": "Paths the application is active on, eg: /checkout"}
+      }
+    },
+    "federation": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string", "description": "Name of the Module Federation container"},
+        "remoteEntry": {"type": "string", "description": "Remote entry in the build, found by itself when the build has a remoteEntry.js or mf-manifest.json"}
+      }
+    },
+    "assetTypes": {"type": "object", "additionalProperties": {"enum": ["font", "image", "wasm", "json"]}},
+    "sourceMaps": {"enum": ["", "public", "private", "skip"]},
+    "sourceMapPrefix": {"type": "string"},
+    "lock": {"enum": ["", "s3", "dynamodb", "none"], "description": "dynamodb for a real lock, s3 for an advisory one, none by default"},
+    "lockTable": {"type": "string"},
+    "lockTTL": {"type": "string", "description": "A duration like 30m"},
+    "multipart": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "partSize": {"$ref": "#/definitions/size"},
+        "concurrency": {"type": "integer", "minimum": 0},
+        "leavePartsOnError": {"type": "boolean"}
+      }
+    },
+    "checksums": {"type": "boolean"},
+    "cacheControl": {"type": "object", "additionalProperties": {"type": "string"}},
+    "compress": {"type": "array", "items": {"enum": ["gzip", "br"]}},
+    "compressVariants": {"type": "boolean"},
+    "webhooks": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["url"],
+        "additionalProperties": false,
+        "properties": {"url": {"type": "string"}, "secret": {"type": "string"}}
+      }
+    },
+    "notify": {"type": "array", "items": {"type": "string"}},
+    "registry": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {"url": {"type": "string"}, "token": {"type": "string"}}
+    },
+    "metrics": {
+      "type": "object",
+      "additionalProperties": false,
+      "description": "Where the duration, bytes, files and failures of every publish are sent",
+      "properties": {
+        "type": {"enum": ["", "cloudwatch", "pushgateway"]},
+        "namespace": {"type": "string", "description": "CloudWatch namespace, defaults to JourneyCLI"},
+        "url": {"type": "string", "description": "Url of the Prometheus pushgateway"}
+      }
+    },
+    "hooks": {
+      "type": "object",
+      "additionalProperties": false,
+      "description": "Shell commands run in the directory of journey.json, with JOURNEY_NAME, JOURNEY_VERSION and JOURNEY_URLS set",
+      "properties": {
+        "prePublish": {"type": "array", "items": {"type": "string"}},
+        "postPublish": {"type": "array", "items": {"type": "string"}},
+        "postSetLatest": {"type": "array", "items": {"type": "string"}}
+      }
+    },
+    "smokeTests": {
+      "type": "array",
+      "description": "Urls fetched through the cdn after publishing that must return 200",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["url"],
+        "properties": {
+          "url": {"type": "string", "minLength": 1, "description": "{cdn}, {name} and {version} are replaced, a url without a scheme is relative to the cdn"},
+          "match": {"type": "string", "description": "Regexp the body must match"}
+        }
+      }
+    },
+    "signedUrls": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "type": {"enum": ["", "cloudfront", "s3"]},
+        "expiry": {"type": "string", "description": "How long the urls are valid for, eg: 7d or 12h"},
+        "keyPairID": {"type": "string"},
+        "privateKey": {"type": "string", "description": "PEM or path of a PEM file of the CloudFront key pair"}
+      }
+    },
+    "distributionID": {"type": "string"},
+    "purge": {
+      "type": "object",
+      "additionalProperties": false,
+      "description": "Cdn whose cache -invalidate purges, the CloudFront distributionID unless set",
+      "properties": {
+        "provider": {"enum": ["", "cloudfront", "fastly", "cloudflare"]},
+        "serviceID": {"type": "string", "description": "Fastly service"},
+        "zoneID": {"type": "string", "description": "Cloudflare zone"},
+        "token": {"type": "string", "description": "Fastly or Cloudflare API token, eg: ${FASTLY_API_TOKEN}"}
+      }
+    },
+    "checkDistribution": {"type": "boolean"}
+  }
+}
+`
+
+// ManifestSchema JSON Schema of a flat asset manifest, a map of asset name to path in the build
+const ManifestSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "asset-manifest.json",
+  "type": "object",
+  "properties": {
+    "entrypoints": {"description": "Entrypoints written by webpack-assets-manifest"}
+  },
+  "additionalProperties": {"type": "string", "description": "Path of the asset relative to the build directory"}
+}
+`
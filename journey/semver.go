@@ -0,0 +1,256 @@
+package journey
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semverRegex The regex from semver.org, without a leading v
+var semverRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Semver A parsed semantic version
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease []string
+	Build      string
+}
+
+// ParseSemver Parse a semantic version like 1.2.3-beta.1+build.5
+func ParseSemver(version string) (*Semver, error) {
+	m := semverRegex.FindStringSubmatch(version)
+	if m == nil {
+		return nil, fmt.Errorf("Version %v is not a valid semantic version, eg: 1.2.3", version)
+	}
+
+	var v Semver
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	if len(m[4]) > 0 {
+		v.Prerelease = strings.Split(m[4], ".")
+	}
+	v.Build = m[5]
+
+	return &v, nil
+}
+
+// compareInts Compare two ints, returning -1, 0 or 1
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// Compare Compare with another version by semver precedence, returning -1, 0 or 1. Build metadata is ignored
+func (v *Semver) Compare(o *Semver) int {
+	if c := compareInts(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// a version without a prerelease is greater than the same version with one
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		a, b := v.Prerelease[i], o.Prerelease[i]
+		an, aErr := strconv.Atoi(a)
+		bn, bErr := strconv.Atoi(b)
+
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInts(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			// numeric identifiers have lower precedence than alphanumeric ones
+			return -1
+		case bErr == nil:
+			return 1
+		case a != b:
+			return strings.Compare(a, b)
+		}
+	}
+
+	return compareInts(len(v.Prerelease), len(o.Prerelease))
+}
+
+// CompareVersions Compare two semantic version strings, returning -1, 0 or 1
+func CompareVersions(a string, b string) (int, error) {
+	va, err := ParseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+
+	vb, err := ParseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return va.Compare(vb), nil
+}
+
+// validateVersion Validate the version can be used in a key, and is semver unless that check is skipped
+func validateVersion(version string, skipSemver bool) error {
+	if strings.ContainsAny(version, `/\`) || version == "." || version == ".." {
+		return fmt.Errorf("Version %v can not contain path separators", version)
+	}
+
+	if skipSemver {
+		return nil
+	}
+
+	_, err := ParseSemver(version)
+	return err
+}
+
+// VersionRange Versions matching every comparison, like ^1.2.0, ~1.2.0, 1.x, >=1.0.0 <2.0.0 or *
+type VersionRange struct {
+	comparisons []versionComparison
+}
+
+// versionComparison One comparison of a range, eg: >=1.2.0
+type versionComparison struct {
+	op      string
+	version *Semver
+}
+
+// match Check the version passes the comparison
+func (c versionComparison) match(v *Semver) bool {
+	n := v.Compare(c.version)
+	switch c.op {
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	default:
+		return n == 0
+	}
+}
+
+// parsePartialVersion Parse a version where the minor and patch may be left out or be x or *, eg: 1.x,
+// returning how many of the parts were given
+func parsePartialVersion(version string) (*Semver, int, error) {
+	parts := strings.SplitN(version, ".", 3)
+	nums := make([]int, 3)
+	given := 0
+	for i, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("Version %v is not a valid semantic version, eg: 1.2.3", version)
+		}
+		nums[i] = n
+		given++
+	}
+
+	return &Semver{Major: nums[0], Minor: nums[1], Patch: nums[2]}, given, nil
+}
+
+// parseComparisons Parse one part of a range, which may expand to a lower and an upper bound
+func parseComparisons(part string) ([]versionComparison, error) {
+	if part == "*" || part == "x" || part == "X" {
+		return nil, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(part, op) {
+			v, err := ParseSemver(strings.TrimPrefix(part, op))
+			if err != nil {
+				return nil, err
+			}
+			return []versionComparison{{op: op, version: v}}, nil
+		}
+	}
+
+	caret := strings.HasPrefix(part, "^")
+	tilde := strings.HasPrefix(part, "~")
+	base := strings.TrimLeft(part, "^~")
+	if v, err := ParseSemver(base); err == nil && !caret && !tilde {
+		return []versionComparison{{op: "=", version: v}}, nil
+	}
+
+	// prereleases and build metadata are only supported on exact versions and comparisons
+	v, given, err := parsePartialVersion(base)
+	if err != nil {
+		return nil, err
+	}
+	if given == 0 {
+		return nil, nil
+	}
+
+	upper := &Semver{}
+	switch {
+	case caret && v.Major > 0, given == 1:
+		upper.Major = v.Major + 1
+	case caret && v.Minor > 0, tilde, given == 2:
+		upper.Major, upper.Minor = v.Major, v.Minor+1
+	default:
+		upper.Major, upper.Minor, upper.Patch = v.Major, v.Minor, v.Patch+1
+	}
+
+	return []versionComparison{{op: ">=", version: v}, {op: "<", version: upper}}, nil
+}
+
+// ParseVersionRange Parse a range like npm writes them, comparisons separated by spaces must all match
+func ParseVersionRange(r string) (*VersionRange, error) {
+	var vr VersionRange
+	for _, part := range strings.Fields(r) {
+		comparisons, err := parseComparisons(part)
+		if err != nil {
+			return nil, fmt.Errorf("Version range %v is not valid, use a range like ^1.2.0, ~1.2.0, 1.x or >=1.0.0 <2.0.0", r)
+		}
+		vr.comparisons = append(vr.comparisons, comparisons...)
+	}
+
+	return &vr, nil
+}
+
+// Match Check the version is in the range, prereleases only match comparisons with a prerelease of the same version
+func (r *VersionRange) Match(v *Semver) bool {
+	if len(v.Prerelease) > 0 {
+		allowed := false
+		for _, c := range r.comparisons {
+			if len(c.version.Prerelease) > 0 && c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	for _, c := range r.comparisons {
+		if !c.match(v) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package journey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultServeAddr Where serve listens unless told otherwise
+const DefaultServeAddr = "localhost:8080"
+
+// serveShutdownTimeout How long requests in flight get to finish once serve is stopped
+const serveShutdownTimeout = 5 * time.Second
+
+// Serve Serve the build on addr the way the cdn would, with journey-urls.json at the version and latest paths
+// pointing at the local server, so a host application can load the journey from a dev instance
+func (j *Journey) Serve(ctx context.Context, addr string, assets map[string]string) error {
+	local := *j
+	local.CDNDomain = "http://" + addr + "/"
+
+	plan, err := local.PlanPublish(assets)
+	if err != nil {
+		return err
+	}
+
+	urls, err := json.MarshalIndent(plan.Urls, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to parse the journey urls into json")
+	}
+
+	files := make(map[string]*Upload, len(plan.Uploads))
+	for _, u := range plan.Uploads {
+		files["/"+u.Key] = u
+	}
+	files["/"+local.GetLatestKey(local.UrlsFile())] = files["/"+local.GetAssetKey(local.UrlsFile())]
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the host application runs on another origin
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		u, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", u.ContentType)
+		if len(u.ContentEncoding) > 0 {
+			w.Header().Set("Content-Encoding", u.ContentEncoding)
+		}
+
+		switch {
+		case strings.HasSuffix(u.Key, j.UrlsFile()):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(urls)
+		case u.Body != nil:
+			w.Write(u.Body)
+		default:
+			http.ServeFile(w, r, u.Path)
+		}
+		Log.Debugf("%v %v", r.Method, r.URL.Path)
+	})
+
+	server := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	Log.Infof("Serving %v/%v, point the loader at %v", j.Name, j.Version, local.CDNDomain+local.GetLatestKey(local.UrlsFile()))
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("Unable to serve on %v: %v", addr, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package journey
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// defaultRoleSessionName The session name used when assuming a role unless configured otherwise
+const defaultRoleSessionName = "journey-cli"
+
+// NewSession Create an AWS session for the region using the named profile, assuming the role when one is set
+func (j *Journey) NewSession(region string) (*session.Session, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		Profile:           j.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create an AWS session: %v", err)
+	}
+
+	if len(j.RoleARN) <= 0 {
+		return sess, nil
+	}
+
+	creds := stscreds.NewCredentials(sess, j.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = j.RoleSessionName
+		if len(p.RoleSessionName) <= 0 {
+			p.RoleSessionName = defaultRoleSessionName
+		}
+		if len(j.ExternalID) > 0 {
+			p.ExternalID = aws.String(j.ExternalID)
+		}
+	})
+	Log.Debugf("Assuming role %v", j.RoleARN)
+
+	return sess.Copy(&aws.Config{Credentials: creds}), nil
+}
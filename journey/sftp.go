@@ -0,0 +1,396 @@
+package journey
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpPort The ssh port used when the endpoint does not have one
+const sftpPort = "22"
+
+// sftpTimeout How long connecting and authenticating to the SFTP server may take
+const sftpTimeout = 30 * time.Second
+
+// sftpPartial Suffix of a file while it is being uploaded, it is renamed over the key once it is complete
+const sftpPartial = ".journey-partial"
+
+// SFTPStorage Storage on a server reachable over SSH, for origins that are not object stores and have no WebDAV.
+// The bucket is a directory under the path of the endpoint. The host key is checked against ~/.ssh/known_hosts,
+// and the user logs in with the password, or with the keys of the ssh agent when there is no password
+type SFTPStorage struct {
+	Bucket string
+	root   string
+	dial   func(ctx context.Context) (*sftp.Client, error)
+
+	mu     sync.Mutex
+	client *sftp.Client
+}
+
+// NewSFTPStorage Create storage for the directory named by the bucket on the SFTP server at the endpoint,
+// the connection is made on first use
+func NewSFTPStorage(bucket string, opts StorageOptions) (*SFTPStorage, error) {
+	if len(opts.Endpoint) <= 0 {
+		return nil, fmt.Errorf("Storage backend %v needs the url of the server as the endpoint", BackendSFTP)
+	}
+
+	base, err := url.Parse(opts.Endpoint)
+	if err != nil || base.Scheme != "sftp" || len(base.Hostname()) <= 0 {
+		return nil, fmt.Errorf("Endpoint %v is not an sftp url, eg: sftp://deploy@origin.example.com/srv/www", opts.Endpoint)
+	}
+
+	// a user name in the endpoint is used when user is not set
+	user := opts.User
+	if len(user) <= 0 && base.User != nil {
+		user = base.User.Username()
+	}
+	if len(user) <= 0 {
+		return nil, fmt.Errorf("Storage backend %v needs a user, set user or put it in the endpoint", BackendSFTP)
+	}
+
+	host := base.Host
+	if len(base.Port()) <= 0 {
+		host = net.JoinHostPort(base.Hostname(), sftpPort)
+	}
+
+	root := base.Path
+	if len(root) <= 0 {
+		root = "/"
+	}
+
+	s := &SFTPStorage{Bucket: bucket, root: root}
+	s.dial = func(ctx context.Context) (*sftp.Client, error) {
+		return dialSFTP(ctx, host, user, opts.Password)
+	}
+
+	return s, nil
+}
+
+// sftpAuth The ways to log in, the password when there is one or else the keys of the ssh agent
+func sftpAuth(password string) ([]ssh.AuthMethod, func(), error) {
+	if len(password) > 0 {
+		return []ssh.AuthMethod{ssh.Password(password)}, func() {}, nil
+	}
+
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if len(sock) <= 0 {
+		return nil, nil, fmt.Errorf("Storage backend %v needs a password or an ssh agent with SSH_AUTH_SOCK set", BackendSFTP)
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to reach the ssh agent at %v: %v", sock, err)
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(conn).Signers)}, func() { conn.Close() }, nil
+}
+
+// dialSFTP Connect to the server, check its host key and log in
+func dialSFTP(ctx context.Context, host string, user string, password string) (*sftp.Client, error) {
+	hostKeys, err := knownhosts.New(filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the known hosts to check %v with: %v", host, err)
+	}
+
+	auth, closeAuth, err := sftpAuth(password)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAuth()
+
+	dialer := net.Dialer{Timeout: sftpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to %v: %v", host, err)
+	}
+
+	config := &ssh.ClientConfig{User: user, Auth: auth, HostKeyCallback: hostKeys, Timeout: sftpTimeout}
+	c, chans, reqs, err := ssh.NewClientConn(conn, host, config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Unable to log in to %v as %v: %v", host, user, err)
+	}
+
+	client, err := sftp.NewClient(ssh.NewClient(c, chans, reqs))
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("Unable to start sftp on %v: %v", host, err)
+	}
+
+	return client, nil
+}
+
+// connect The client, connecting on first use. The connection is kept for every later request
+func (s *SFTPStorage) connect(ctx context.Context) (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil {
+		client, err := s.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.client = client
+	}
+
+	return s.client, nil
+}
+
+// Close Close the connection to the server, if one was made
+func (s *SFTPStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+
+	return err
+}
+
+// path The path of the key in the bucket, or of another bucket on the same server
+func (s *SFTPStorage) path(bucket string, key string) string {
+	return path.Join(s.root, bucket, key)
+}
+
+// sftpError Turn a missing file into ErrNotFound
+func sftpError(err error) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// write Write the body to the path through a partial file renamed over it, so the path never has half a file
+func (s *SFTPStorage) write(client *sftp.Client, p string, body io.Reader) error {
+	if err := client.MkdirAll(path.Dir(p)); err != nil {
+		return fmt.Errorf("Unable to create the directory %v: %v", path.Dir(p), err)
+	}
+
+	partial := p + sftpPartial
+	f, err := client.Create(partial)
+	if err != nil {
+		return fmt.Errorf("Unable to create %v: %v", partial, err)
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		client.Remove(partial)
+		return fmt.Errorf("Unable to write %v: %v", partial, err)
+	}
+	if err := f.Close(); err != nil {
+		client.Remove(partial)
+		return fmt.Errorf("Unable to write %v: %v", partial, err)
+	}
+
+	if err := client.PosixRename(partial, p); err != nil {
+		client.Remove(partial)
+		return fmt.Errorf("Unable to move %v to %v: %v", partial, p, err)
+	}
+
+	return nil
+}
+
+// Upload Write the body at key, creating the directories it is in. Headers, metadata, ACLs and storage classes
+// have nowhere to go
+func (s *SFTPStorage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.write(client, s.path(s.Bucket, key), body)
+}
+
+// Head Get the size and last modified time of the file, and the md5 of its content as the ETag like S3 has
+func (s *SFTPStorage) Head(ctx context.Context, key string) (*Object, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := s.path(s.Bucket, key)
+	f, err := client.Open(p)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("Unable to read %v: %v", p, err)
+	}
+
+	return &Object{Key: key, Size: info.Size(), ETag: hex.EncodeToString(h.Sum(nil)), LastModified: info.ModTime()}, nil
+}
+
+// Get Get the content of the file, the caller closes it
+func (s *SFTPStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := client.Open(s.path(s.Bucket, key))
+	if err != nil {
+		return nil, sftpError(err)
+	}
+
+	return f, nil
+}
+
+// Copy Copy the file to another key, SFTP has no copy so the content goes through the client
+func (s *SFTPStorage) Copy(ctx context.Context, from string, to string) error {
+	return s.CopyFrom(ctx, s.Bucket, from, to)
+}
+
+// CopyFrom Copy a file from another directory on the same server
+func (s *SFTPStorage) CopyFrom(ctx context.Context, bucket string, from string, to string) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := client.Open(s.path(bucket, from))
+	if err != nil {
+		return sftpError(err)
+	}
+	defer f.Close()
+
+	return s.write(client, s.path(s.Bucket, to), f)
+}
+
+// Delete Delete the files, keys that do not exist are ignored like S3 does
+func (s *SFTPStorage) Delete(ctx context.Context, keys ...string) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := client.Remove(s.path(s.Bucket, key)); err != nil && sftpError(err) != ErrNotFound {
+			return fmt.Errorf("Unable to delete %v: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+// List List every file under the prefix, sorted by key like S3 does. Listings have no ETags, Head reads the file for one
+func (s *SFTPStorage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	base := s.path(s.Bucket, "")
+	var list []*Object
+	walker := client.Walk(s.path(s.Bucket, webDAVDir(prefix)))
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("Unable to list %v: %v", walker.Path(), err)
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), base), "/")
+		info := walker.Stat()
+		if info.IsDir() {
+			// skip directories that can not hold keys with the prefix
+			if len(key) > 0 && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				walker.SkipDir()
+			}
+			continue
+		}
+		if !strings.HasPrefix(key, prefix) || strings.HasSuffix(key, sftpPartial) {
+			continue
+		}
+
+		list = append(list, &Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Key < list[b].Key })
+
+	return list, nil
+}
+
+// ListPrefixes List the directories one level below the prefix
+func (s *SFTPStorage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := webDAVDir(prefix)
+	infos, err := client.ReadDir(s.path(s.Bucket, dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list %v: %v", dir, err)
+	}
+
+	var prefixes []string
+	for _, info := range infos {
+		if p := dir + info.Name() + "/"; info.IsDir() && strings.HasPrefix(p, prefix) {
+			prefixes = append(prefixes, p)
+		}
+	}
+	sort.Strings(prefixes)
+
+	return prefixes, nil
+}
+
+// ListIncompleteUploads SFTP has no multipart uploads, a failed upload removes its partial file
+func (s *SFTPStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortUpload SFTP has no multipart uploads to abort
+func (s *SFTPStorage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	return ErrNotFound
+}
+
+// SetStorageClass SFTP servers have no storage classes
+func (s *SFTPStorage) SetStorageClass(ctx context.Context, key string, class string) error {
+	return fmt.Errorf("Storage backend %v does not have storage classes", BackendSFTP)
+}
+
+// Restore Files on an SFTP server are never archived, so they can always be read
+func (s *SFTPStorage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := client.Stat(s.path(s.Bucket, key)); err != nil {
+		return false, sftpError(err)
+	}
+
+	return true, nil
+}
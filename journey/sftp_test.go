@@ -0,0 +1,147 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// pipeConn The server end of a pair of pipes
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// newTestSFTPStorage Storage on an in process sftp server serving a temp dir, removed by the returned func
+func newTestSFTPStorage(t *testing.T) (*SFTPStorage, func()) {
+	dir, err := ioutil.TempDir("", "journey-sftp")
+	if err != nil {
+		t.Fatalf("Unable to create a temp dir: %v", err)
+	}
+
+	toServer, fromClient := io.Pipe()
+	fromServer, toClient := io.Pipe()
+	server, err := sftp.NewServer(pipeConn{toServer, toClient})
+	if err != nil {
+		t.Fatalf("Unable to start the sftp server: %v", err)
+	}
+	go server.Serve()
+
+	store := &SFTPStorage{Bucket: "portal", root: dir}
+	store.dial = func(ctx context.Context) (*sftp.Client, error) {
+		return sftp.NewClientPipe(fromServer, fromClient)
+	}
+
+	// the client waits for the server to hang up before it is closed
+	return store, func() {
+		server.Close()
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestNewSFTPStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     StorageOptions
+		wantRoot string
+		wantErr  bool
+	}{
+		{"user in the endpoint", StorageOptions{Endpoint: "sftp://deploy@origin.example.com/srv/www"}, "/srv/www", false},
+		{"user set", StorageOptions{Endpoint: "sftp://origin.example.com:2222", User: "deploy"}, "/", false},
+		{"no user", StorageOptions{Endpoint: "sftp://origin.example.com/srv/www"}, "", true},
+		{"no endpoint", StorageOptions{User: "deploy"}, "", true},
+		{"not sftp", StorageOptions{Endpoint: "https://origin.example.com/dav/", User: "deploy"}, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := NewSFTPStorage("portal", test.opts)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewSFTPStorage() = %v, want an error %v", err, test.wantErr)
+			}
+			if err == nil && store.root != test.wantRoot {
+				t.Errorf("root = %v, want %v", store.root, test.wantRoot)
+			}
+		})
+	}
+}
+
+func TestSFTPStorage(t *testing.T) {
+	store, cleanup := newTestSFTPStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	content := []byte("console.log('checkout');")
+	for _, key := range []string{"checkout/1.0.0/app.js", "checkout/1.0.0/js/vendor.js", "checkout/1.1.0/app.js", "cart/1.0.0/app.js"} {
+		if err := store.Upload(ctx, key, bytes.NewReader(content), UploadOptions{}); err != nil {
+			t.Fatalf("Upload(%v) failed: %v", key, err)
+		}
+	}
+
+	sum := md5.Sum(content)
+	o, err := store.Head(ctx, "checkout/1.0.0/app.js")
+	if err != nil {
+		t.Fatalf("Head() failed: %v", err)
+	}
+	if o.Size != int64(len(content)) || o.ETag != hex.EncodeToString(sum[:]) {
+		t.Errorf("Head() = %v bytes with ETag %v, want %v bytes with ETag %x", o.Size, o.ETag, len(content), sum)
+	}
+	if _, err := store.Head(ctx, "checkout/2.0.0/app.js"); err != ErrNotFound {
+		t.Errorf("Head() of a missing key = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Copy(ctx, "checkout/1.0.0/app.js", "checkout/latest/app.js"); err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	r, err := store.Get(ctx, "checkout/latest/app.js")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Get() = %q, %v, want %q", got, err, content)
+	}
+	if _, err := store.Get(ctx, "checkout/2.0.0/app.js"); err != ErrNotFound {
+		t.Errorf("Get() of a missing key = %v, want ErrNotFound", err)
+	}
+
+	list, err := store.List(ctx, "checkout/1.0.0/")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var keys []string
+	for _, o := range list {
+		keys = append(keys, o.Key)
+	}
+	if want := []string{"checkout/1.0.0/app.js", "checkout/1.0.0/js/vendor.js"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+	if list, err := store.List(ctx, "checkout/2.0.0/"); err != nil || len(list) > 0 {
+		t.Errorf("List() of a missing prefix = %v, %v, want nothing", list, err)
+	}
+
+	prefixes, err := store.ListPrefixes(ctx, "checkout/")
+	if err != nil {
+		t.Fatalf("ListPrefixes() failed: %v", err)
+	}
+	if want := []string{"checkout/1.0.0/", "checkout/1.1.0/", "checkout/latest/"}; !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("ListPrefixes() = %v, want %v", prefixes, want)
+	}
+
+	if err := store.Delete(ctx, "checkout/1.1.0/app.js", "checkout/1.1.0/missing.js"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.Head(ctx, "checkout/1.1.0/app.js"); err != ErrNotFound {
+		t.Errorf("Head() of a deleted key = %v, want ErrNotFound", err)
+	}
+}
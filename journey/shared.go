@@ -0,0 +1,170 @@
+package journey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SharedPrefix Where chunks shared between versions are published once, named by their content, eg: {name}/static/{sha256}.js
+const SharedPrefix = "static"
+
+// fileSHA256 The hex sha256 of the content of the file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isShared Check if the asset is published by its content, every asset in the cas layout or the shared chunks otherwise.
+// Only assets journey-urls.json lists are, since collecting content only keeps what journey urls point at. Source maps,
+// which bundles find relative to themselves, and files like LICENSE stay with the version
+func (j *Journey) isShared(asset string) bool {
+	return j.isListed(asset) && (j.Layout == LayoutCAS || matchAny(j.SharedChunks, asset))
+}
+
+// isSharedKey Check if the key is named by its content rather than part of a version
+func (j *Journey) isSharedKey(key string) bool {
+	for _, prefix := range j.contentPrefixes() {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sharedKey The key of a shared chunk, named by the sha256 of its content so every version with the same chunk uses one object.
+// The cas layout leaves out the extension
+func (j *Journey) sharedKey(asset string) (string, error) {
+	if key, ok := j.sharedKeys[asset]; ok {
+		return key, nil
+	}
+
+	sum, err := fileSHA256(j.GetAssetPath(asset))
+	if err != nil {
+		return "", fmt.Errorf("Unable to hash %v: %v", asset, err)
+	}
+
+	key := j.GetJourneyKey(SharedPrefix + "/" + sum + filepath.Ext(asset))
+	if j.Layout == LayoutCAS {
+		key = j.GetJourneyKey(CASPrefix + "/" + sum)
+	}
+	if j.sharedKeys == nil {
+		j.sharedKeys = make(map[string]string)
+	}
+	j.sharedKeys[asset] = key
+
+	return key, nil
+}
+
+// assetKey The key the asset is published to, shared chunks are outside the version
+func (j *Journey) assetKey(asset string) (string, error) {
+	if j.isShared(asset) {
+		return j.sharedKey(asset)
+	}
+
+	return j.GetAssetKey(asset), nil
+}
+
+// skipPublishedShared Leave out the shared chunks that an earlier version already published,
+// each content prefix is listed once rather than checking every chunk
+func (j *Journey) skipPublishedShared(ctx context.Context, store Storage, uploads []*Upload) ([]*Upload, []*Upload, error) {
+	published := make(map[string]bool)
+	for _, prefix := range j.contentPrefixes() {
+		used := false
+		for _, u := range uploads {
+			used = used || strings.HasPrefix(u.Key, prefix)
+		}
+		if !used {
+			continue
+		}
+
+		objects, err := store.List(ctx, prefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to list %v: %v", prefix, err)
+		}
+		for _, o := range objects {
+			published[o.Key] = true
+		}
+	}
+
+	var keep []*Upload
+	var skipped []*Upload
+	for _, u := range uploads {
+		if published[u.Key] {
+			Log.Debugf("Key: %v, is already published by another version and will not be uploaded", u.Key)
+			skipped = append(skipped, u)
+			continue
+		}
+		keep = append(keep, u)
+	}
+
+	if len(skipped) > 0 {
+		Log.Infof("%v shared chunks are already published", len(skipped))
+	}
+
+	return keep, skipped, nil
+}
+
+// urlKey The key of a journey url, found from the path of the journey in the url so signed urls and other cdns work too
+func (j *Journey) urlKey(u string) (string, error) {
+	prefix := escapeKey(j.GetJourneyKey(""))
+	i := strings.Index(u, prefix)
+	if i < 0 {
+		return "", fmt.Errorf("Url %v is not inside %v", u, prefix)
+	}
+
+	escaped := u[i:]
+	if q := strings.Index(escaped, "?"); q >= 0 {
+		escaped = escaped[:q]
+	}
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read the key of %v: %v", u, err)
+	}
+
+	return key, nil
+}
+
+// sharedUrlKeys The keys of the shared chunks the journey urls point at
+func (j *Journey) sharedUrlKeys(urls *Urls) ([]string, error) {
+	var all []string
+	for _, c := range urls.CSS {
+		all = append(all, c.URL)
+	}
+	for _, s := range urls.JS {
+		all = append(all, s.URL)
+	}
+	for _, a := range urls.Assets {
+		all = append(all, a.URL)
+	}
+
+	var keys []string
+	for _, u := range all {
+		key, err := j.urlKey(u)
+		if err != nil {
+			return nil, err
+		}
+		if j.isSharedKey(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,62 @@
+package journey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Published What the cdn serves for a version, its journey urls and the journey.json it was published with
+type Published struct {
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	Latest  *LatestInfo     `json:"latest,omitempty"`
+	Urls    *Urls           `json:"journeyUrls"`
+	Journey json.RawMessage `json:"journey"`
+}
+
+// Show Get the journey urls and journey.json of a published version. Latest shows the journey urls latest serves
+// and the journey.json of the version it points at
+func (j *Journey) Show(ctx context.Context, store Storage, version string) (*Published, error) {
+	p := &Published{Name: j.Name, Version: version}
+	urlsKey := j.GetVersionKey(version, j.UrlsFile())
+
+	if version == Latest {
+		current, err := j.currentLatest(ctx, store)
+		if err != nil {
+			return nil, err
+		}
+		if len(current) <= 0 {
+			return nil, fmt.Errorf("Latest of %v is not set", j.Name)
+		}
+
+		if p.Latest, err = j.GetLatestInfo(ctx, store); err != nil {
+			return nil, err
+		}
+		p.Version = current
+		urlsKey = j.GetLatestKey(j.UrlsFile())
+	}
+
+	urls, err := getJourneyUrls(ctx, store, urlsKey)
+	if err != nil {
+		return nil, err
+	}
+	p.Urls = urls
+
+	key := j.GetVersionKey(p.Version, JourneyFile)
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", key, err)
+	}
+	defer body.Close()
+
+	if p.Journey, err = ioutil.ReadAll(body); err != nil {
+		return nil, fmt.Errorf("Unable to read %v: %v", key, err)
+	}
+	if !json.Valid(p.Journey) {
+		return nil, fmt.Errorf("Unable to parse %v, it is not json", key)
+	}
+
+	return p, nil
+}
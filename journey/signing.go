@@ -0,0 +1,251 @@
+package journey
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cfsign "github.com/aws/aws-sdk-go/service/cloudfront/sign"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Ways of signing the urls in journey-urls.json for buckets that are not public
+const (
+	SignCloudFront = "cloudfront"
+	SignS3         = "s3"
+)
+
+// DefaultSignedURLExpiry How long signed urls are valid for when no expiry is set
+const DefaultSignedURLExpiry = 7 * 24 * time.Hour
+
+// maxPresignExpiry S3 does not accept presigned urls that are valid for longer than a week
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// SignedUrls Sign the urls with a CloudFront key pair or presign them against S3, so they work without a public bucket
+type SignedUrls struct {
+	Type       string `json:"type"`
+	Expiry     string `json:"expiry"`
+	KeyPairID  string `json:"keyPairID"`
+	PrivateKey string `json:"privateKey" journey:"private"`
+}
+
+// expiry How long the signed urls are valid for
+func (s *SignedUrls) expiry() time.Duration {
+	// the expiry is validated when the journey is loaded
+	d, _ := ParseAge(s.Expiry)
+	if d <= 0 {
+		return DefaultSignedURLExpiry
+	}
+
+	return d
+}
+
+// validate Validate the signing type is supported and has what it needs
+func (s *SignedUrls) validate() error {
+	if _, err := ParseAge(s.Expiry); err != nil {
+		return fmt.Errorf("signedUrls expiry %v is not valid, use a value like 7d or 12h", s.Expiry)
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+	case SignCloudFront:
+		if len(s.KeyPairID) <= 0 || len(s.PrivateKey) <= 0 {
+			return fmt.Errorf("Signing urls with %v needs a keyPairID and privateKey", SignCloudFront)
+		}
+	case SignS3:
+		if s.expiry() > maxPresignExpiry {
+			return fmt.Errorf("Urls presigned with %v can not be valid for longer than 7d", SignS3)
+		}
+	default:
+		return fmt.Errorf("Signed urls %v are not supported, use %v or %v", s.Type, SignCloudFront, SignS3)
+	}
+
+	return nil
+}
+
+// loadPrivateKey Read the CloudFront private key, given as the PEM itself or the path of a PEM file,
+// in PKCS #1 like CloudFront key pairs or PKCS #8 like newer openssl writes
+func loadPrivateKey(key string) (*rsa.PrivateKey, error) {
+	content := []byte(key)
+	if !strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+		var err error
+		if content, err = ioutil.ReadFile(key); err != nil {
+			return nil, fmt.Errorf("Unable to read the CloudFront private key %v: %v", key, err)
+		}
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("Unable to read the CloudFront private key, it is not PEM encoded")
+	}
+	if pk, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return pk, nil
+	}
+
+	pk, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the CloudFront private key: %v", err)
+	}
+	rsaKey, ok := pk.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("The CloudFront private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// urlSigner Get a function signing the url of a key until expires, nil when urls are not signed
+func (j *Journey) urlSigner(expires time.Time) (func(key string) (string, error), error) {
+	switch j.SignedUrls.Type {
+	case SignCloudFront:
+		pk, err := loadPrivateKey(j.SignedUrls.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		signer := cfsign.NewURLSigner(j.SignedUrls.KeyPairID, pk)
+
+		return func(key string) (string, error) {
+			return signer.Sign(j.CDNDomain+escapeKey(key), expires)
+		}, nil
+	case SignS3:
+		sess, err := j.NewSession(j.Region)
+		if err != nil {
+			return nil, err
+		}
+		config := &aws.Config{}
+		if len(j.Endpoint) > 0 {
+			config.Endpoint = aws.String(j.Endpoint)
+		}
+		if j.PathStyle {
+			config.S3ForcePathStyle = aws.Bool(true)
+		}
+		svc := s3.New(sess, config)
+
+		return func(key string) (string, error) {
+			req, _ := svc.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String(j.Bucket), Key: aws.String(key)})
+			return req.Presign(time.Until(expires))
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// signUrls Replace every url of the version with a signed one, the key is found from the path of the journey in the url
+// so urls that were already signed can be signed again
+func (j *Journey) signUrls(urls *Urls) error {
+	expires := time.Now().Add(j.SignedUrls.expiry()).UTC().Truncate(time.Second)
+	signer, err := j.urlSigner(expires)
+	if err != nil || signer == nil {
+		return err
+	}
+
+	signURL := func(u string) (string, error) {
+		key, err := j.urlKey(u)
+		if err != nil {
+			return "", fmt.Errorf("Unable to sign %v: %v", u, err)
+		}
+
+		signed, err := signer(key)
+		if err != nil {
+			return "", fmt.Errorf("Unable to sign %v: %v", u, err)
+		}
+		return signed, nil
+	}
+
+	for i := range urls.CSS {
+		if urls.CSS[i].URL, err = signURL(urls.CSS[i].URL); err != nil {
+			return err
+		}
+	}
+	for i := range urls.JS {
+		if urls.JS[i].URL, err = signURL(urls.JS[i].URL); err != nil {
+			return err
+		}
+	}
+	for i := range urls.Assets {
+		if urls.Assets[i].URL, err = signURL(urls.Assets[i].URL); err != nil {
+			return err
+		}
+	}
+	for i := range urls.Preload {
+		if urls.Preload[i].URL, err = signURL(urls.Preload[i].URL); err != nil {
+			return err
+		}
+	}
+	urls.Expires = &expires
+
+	return nil
+}
+
+// SignUrls Sign the urls of the published version again before they expire, along with every channel pointing at it
+func (j *Journey) SignUrls(ctx context.Context, store Storage) error {
+	if len(j.SignedUrls.Type) <= 0 {
+		return fmt.Errorf("Urls are not signed, set signedUrls in journey.json")
+	}
+
+	// channels hold a copy of the journey urls, find the ones pointing at the version before it changes
+	channels, err := j.ListChannels(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	key := j.GetAssetKey(j.UrlsFile())
+	urls, err := getJourneyUrls(ctx, store, key)
+	if err != nil {
+		return err
+	}
+	if err := j.signUrls(urls); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the journey urls into json")
+	}
+
+	importMaps, err := j.importMapUploads(urls)
+	if err != nil {
+		return err
+	}
+	uploads := append([]*Upload{j.newUpload(key, "", data, "application/javascript")}, importMaps...)
+	if len(j.HTMLTemplate) > 0 {
+		Log.Warnf("%v keeps the urls it was published with, publish again to render it with new signatures", HTMLFile)
+	}
+
+	if j.DryRun {
+		Log.Infof("Dry run, the urls of %v/%v would be signed until %v", j.Name, j.Version, urls.Expires.Format(time.RFC3339))
+		return nil
+	}
+
+	for _, u := range uploads {
+		if err := upload(ctx, store, u); err != nil {
+			return fmt.Errorf("Unable to upload %v: %v", u.Key, err)
+		}
+	}
+
+	// copied directly rather than with pointChannel, which would end a canary running on latest
+	for _, c := range channels {
+		if c.Version != j.Version {
+			continue
+		}
+		if err := store.Copy(ctx, key, j.GetChannelKey(c.Channel, j.UrlsFile())); err != nil {
+			return fmt.Errorf("Unable to copy %v to %v: %v", key, c.Channel, err)
+		}
+		if err := j.pointImportMap(ctx, store, c.Channel, j.Version); err != nil {
+			return err
+		}
+		Log.Infof("Signed the urls of %v again", c.Channel)
+	}
+
+	Log.Infof("Signed the urls of %v/%v until %v", j.Name, j.Version, urls.Expires.Format(time.RFC3339))
+
+	return nil
+}
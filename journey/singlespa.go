@@ -0,0 +1,97 @@
+package journey
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Formats the journey urls of a version are published in
+const (
+	OutputJourney   = "journey"
+	OutputSingleSpa = "single-spa"
+)
+
+// Files published next to the journey urls for single-spa
+const (
+	SingleSpaImportMapFile = "single-spa.importmap.json"
+	SingleSpaFile          = "single-spa.json"
+)
+
+// SingleSpa How single-spa registers the journey as an application
+type SingleSpa struct {
+	ActiveWhen []string `json:"activeWhen"`
+}
+
+// SingleSpaApplication What a single-spa root config needs to register the version with registerApplication,
+// the application is loaded by importing its name through the import map fragment
+type SingleSpaApplication struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	URL        string   `json:"url"`
+	ImportMap  string   `json:"importMap"`
+	ActiveWhen []string `json:"activeWhen,omitempty"`
+}
+
+// validateOutputFormats Validate every output format is known, journey-urls.json is always published since latest and the channels point at it
+func validateOutputFormats(formats []string) error {
+	if len(formats) <= 0 {
+		return nil
+	}
+
+	journey := false
+	for _, f := range formats {
+		switch f {
+		case OutputJourney:
+			journey = true
+		case OutputSingleSpa:
+		default:
+			return fmt.Errorf("Output format %v is not supported, use %v or %v", f, OutputJourney, OutputSingleSpa)
+		}
+	}
+	if !journey {
+		return fmt.Errorf("Output formats must include %v, the journey urls are what latest and the channels point at", OutputJourney)
+	}
+
+	return nil
+}
+
+// hasOutputFormat Check if the journey urls are also published in the format
+func (j *Journey) hasOutputFormat(format string) bool {
+	for _, f := range j.OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildSingleSpa Build the single-spa import map fragment and application of the version, nil when single-spa is not an output format
+func (j *Journey) buildSingleSpa(urls *Urls) ([]byte, []byte, error) {
+	if !j.hasOutputFormat(OutputSingleSpa) {
+		return nil, nil, nil
+	}
+	entry, err := j.entryScript(urls)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := j.importMapName()
+	importMap, err := json.MarshalIndent(&ImportMap{Imports: map[string]string{name: entry}}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse the single-spa import map into json")
+	}
+
+	app, err := json.MarshalIndent(&SingleSpaApplication{
+		Name:       name,
+		Version:    j.Version,
+		URL:        entry,
+		ImportMap:  j.CDNDomain + escapeKey(j.GetAssetKey(SingleSpaImportMapFile)),
+		ActiveWhen: j.SingleSpa.ActiveWhen,
+	}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse the single-spa application into json")
+	}
+
+	return importMap, app, nil
+}
@@ -0,0 +1,53 @@
+package journey
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits Multipliers for the size suffixes accepted in config, longest suffix first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize Parse a human readable size like 25MB or 512KB into bytes, a plain number is bytes
+func ParseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if len(s) <= 0 {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Size %v is not valid, use a number of bytes or a value like 25MB", size)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize Format bytes into a human readable size
+func FormatSize(bytes int64) string {
+	for _, unit := range sizeUnits {
+		if bytes >= unit.bytes && unit.bytes > 1 {
+			return fmt.Sprintf("%.1f%v", float64(bytes)/float64(unit.bytes), unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%dB", bytes)
+}
@@ -0,0 +1,103 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// SmokeTest A url that must return 200 through the cdn once the version is published, and optionally a regexp its body must match.
+// {cdn}, {name} and {version} in the url are replaced, and a url without a scheme is relative to the cdn
+type SmokeTest struct {
+	URL   string `json:"url"`
+	Match string `json:"match"`
+}
+
+// validateSmokeTests Validate every smoke test has a url and a regexp that compiles
+func validateSmokeTests(tests []SmokeTest) error {
+	for i, t := range tests {
+		if len(t.URL) <= 0 {
+			return fmt.Errorf("Smoke test %v does not have a url", i+1)
+		}
+		if _, err := regexp.Compile(t.Match); err != nil {
+			return fmt.Errorf("Smoke test %v match %v is not valid: %v", i+1, t.Match, err)
+		}
+	}
+
+	return nil
+}
+
+// smokeTestURL The url of the smoke test for the published version
+func (j *Journey) smokeTestURL(t SmokeTest) string {
+	u := strings.NewReplacer("{cdn}", j.CDNDomain, "{name}", j.Name, "{version}", j.Version).Replace(t.URL)
+	if !strings.Contains(u, "://") && !strings.HasPrefix(u, "//") {
+		u = j.CDNDomain + strings.TrimPrefix(u, "/")
+	}
+
+	return u
+}
+
+// smokeTest Fetch the url and check the response
+func (j *Journey) smokeTest(ctx context.Context, client *http.Client, t SmokeTest) error {
+	u := j.smokeTestURL(t)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("Url %v is not valid: %v", u, err)
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("Unable to fetch %v: %v", u, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %v", u, res.Status)
+	}
+	if len(t.Match) <= 0 {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("Unable to read %v: %v", u, err)
+	}
+	// the match was validated when the journey was loaded
+	if re := regexp.MustCompile(t.Match); !re.Match(body) {
+		return fmt.Errorf("%v does not match %v", u, t.Match)
+	}
+
+	return nil
+}
+
+// SmokeTest Check every smoke test passes through the cdn, so a misconfigured origin fails the publish instead of the first user
+func (j *Journey) SmokeTest(ctx context.Context) error {
+	if len(j.SmokeTests) <= 0 {
+		return nil
+	}
+	if j.DryRun {
+		Log.Infof("Dry run, %v smoke tests would be run", len(j.SmokeTests))
+		return nil
+	}
+
+	client := &http.Client{Timeout: verifyTimeout}
+	var failed []string
+	for _, t := range j.SmokeTests {
+		if err := j.smokeTest(ctx, client, t); err != nil {
+			Log.Warnf("%v", err)
+			failed = append(failed, err.Error())
+			continue
+		}
+		Log.Debugf("Smoke test %v passed", j.smokeTestURL(t))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%v of %v smoke tests of %v/%v failed:\n%v", len(failed), len(j.SmokeTests), j.Name, j.Version, strings.Join(failed, "\n"))
+	}
+	Log.Infof("All %v smoke tests of %v/%v passed", len(j.SmokeTests), j.Name, j.Version)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package journey
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source map policies, public maps are uploaded like any other asset
+const (
+	SourceMapsPublic  = "public"
+	SourceMapsPrivate = "private"
+	SourceMapsSkip    = "skip"
+)
+
+// validateSourceMapPolicy Validate the source map policy is one we know how to handle
+func validateSourceMapPolicy(policy string) error {
+	switch policy {
+	case "", SourceMapsPublic, SourceMapsPrivate, SourceMapsSkip:
+		return nil
+	default:
+		return fmt.Errorf("Source map policy %v is not supported, use %v, %v or %v", policy, SourceMapsPublic, SourceMapsPrivate, SourceMapsSkip)
+	}
+}
+
+// isSourceMap Check if the asset is a source map
+func isSourceMap(path string) bool {
+	return filepath.Ext(path) == ".map"
+}
+
+// addSourceMaps Add the source maps in the build directory the manifest does not list when the policy is public or private,
+// or drop every source map when they are skipped
+func (j *Journey) addSourceMaps(assets map[string]string) (map[string]string, error) {
+	all := make(map[string]string, len(assets))
+	listed := make(map[string]bool, len(assets))
+
+	for k, v := range assets {
+		if isSourceMap(v) && j.SourceMaps == SourceMapsSkip {
+			Log.Debugf("Source map %v will not be uploaded", v)
+			continue
+		}
+		all[k] = v
+		listed[v] = true
+	}
+
+	// maps the manifest leaves out are only looked for when a policy says how to publish them
+	if j.SourceMaps != SourceMapsPublic && j.SourceMaps != SourceMapsPrivate {
+		return all, nil
+	}
+
+	root := filepath.Clean(j.Build)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip, err := j.skipJunk(root, path, info); skip {
+			return err
+		}
+		if info.IsDir() || !isSourceMap(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if !listed[rel] {
+			Log.Debugf("Found source map %v that is not in the manifest", rel)
+			all[rel] = rel
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to look for source maps in %v: %v", j.Build, err)
+	}
+
+	return all, nil
+}
+
+// sourceMapUpload Apply the source map prefix and policy to the upload of a source map
+func (j *Journey) sourceMapUpload(u *Upload, asset string) {
+	if len(j.SourceMapPrefix) > 0 {
+		u.Key = j.GetAssetKey(strings.Trim(j.SourceMapPrefix, "/") + "/" + asset)
+	}
+
+	u.ContentType = "application/json"
+	if j.SourceMaps == SourceMapsPrivate {
+		u.ACL = ACLPrivate
+	}
+}
@@ -0,0 +1,56 @@
+package journey
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Stdin The path that reads journey.json or the asset manifest from standard input instead of a file
+const Stdin = "-"
+
+// configName How journey.json is named in errors, it may not be a file
+func (j *Journey) configName() string {
+	if j.JourneyPath == Stdin {
+		return "journey.json from stdin"
+	}
+
+	return j.JourneyPath
+}
+
+// readJourneyFile Get the content of journey.json, as it was read from stdin or from the file
+func (j *Journey) readJourneyFile() ([]byte, error) {
+	if j.JourneyContent != nil {
+		return j.JourneyContent, nil
+	}
+
+	content, err := ioutil.ReadFile(j.JourneyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %v: %v", j.JourneyPath, err)
+	}
+
+	return content, nil
+}
+
+// readManifestFile Get the content of the asset manifest, stdin is only read once so the manifest can be used again
+func (j *Journey) readManifestFile() ([]byte, error) {
+	if j.ManifestContent != nil {
+		return j.ManifestContent, nil
+	}
+
+	if j.Manifest == Stdin {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the asset manifest from stdin: %v", err)
+		}
+		j.ManifestContent = content
+		return content, nil
+	}
+
+	content, err := ioutil.ReadFile(j.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
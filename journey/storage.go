@@ -0,0 +1,169 @@
+package journey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Storage backends a journey can be published to
+const (
+	BackendS3     = "s3"
+	BackendGCS    = "gcs"
+	BackendAzure  = "azure"
+	BackendWebDAV = "webdav"
+	BackendSFTP   = "sftp"
+)
+
+// ErrNotFound Returned by storage when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// statusError A request to a storage backend spoken to over http that got a status it did not expect
+type statusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *statusError) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("%v %v: %v: %v", e.Method, e.URL, e.Status, e.Message)
+	}
+
+	return fmt.Sprintf("%v %v: %v", e.Method, e.URL, e.Status)
+}
+
+// newStatusError The error for the response, the query is left out of the url as it can hold a signature
+func newStatusError(req *http.Request, res *http.Response) error {
+	u := *req.URL
+	u.RawQuery = ""
+	message, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+
+	return &statusError{Method: req.Method, URL: u.String(), StatusCode: res.StatusCode, Status: res.Status, Message: strings.TrimSpace(string(message))}
+}
+
+// Object An object in storage
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	Metadata     map[string]string
+	StorageClass string
+}
+
+// IncompleteUpload A multipart upload that was started and never completed or aborted, its parts are still stored
+type IncompleteUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+}
+
+// UploadOptions Headers applied to an uploaded object
+type UploadOptions struct {
+	ContentType     string
+	CacheControl    string
+	ContentEncoding string
+	ACL             string
+	Metadata        map[string]string
+	StorageClass    string
+}
+
+// StorageOptions Settings applied to every object the storage writes
+type StorageOptions struct {
+	Encryption string
+	KMSKeyID   string
+	ACL        string
+	Retries    int
+
+	// Tags on every object written, for cost allocation, only the S3 client applies them
+	Tags map[string]string
+
+	// S3 compatible endpoint and path style addressing, the url of the WebDAV or SFTP server, or of a GCS or Azure emulator
+	Endpoint  string
+	PathStyle bool
+
+	// Credentials of the WebDAV and SFTP servers at the endpoint
+	User     string
+	Password string
+
+	// Multipart uploads, zero uses the S3 defaults
+	PartSize          int64
+	PartConcurrency   int
+	LeavePartsOnError bool
+}
+
+// Storage Where published versions live, keys are relative to the bucket or container
+type Storage interface {
+	Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error
+	Head(ctx context.Context, key string) (*Object, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Copy(ctx context.Context, from string, to string) error
+	CopyFrom(ctx context.Context, bucket string, from string, to string) error
+	Delete(ctx context.Context, keys ...string) error
+	List(ctx context.Context, prefix string) ([]*Object, error)
+	ListPrefixes(ctx context.Context, prefix string) ([]string, error)
+	ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error)
+	AbortUpload(ctx context.Context, key string, uploadID string) error
+	SetStorageClass(ctx context.Context, key string, class string) error
+	Restore(ctx context.Context, key string, days int) (bool, error)
+}
+
+// NewStorage Create the storage for the backend, an empty backend means S3
+func NewStorage(backend string, bucket string, sess *session.Session, opts StorageOptions) (Storage, error) {
+	switch backend {
+	case "", BackendS3:
+		return withRetries(NewS3Storage(bucket, sess, opts), opts.Retries), nil
+	case BackendWebDAV:
+		store, err := NewWebDAVStorage(bucket, opts)
+		if err != nil {
+			return nil, err
+		}
+		return withRetries(store, opts.Retries), nil
+	case BackendSFTP:
+		store, err := NewSFTPStorage(bucket, opts)
+		if err != nil {
+			return nil, err
+		}
+		return withRetries(store, opts.Retries), nil
+	case BackendGCS:
+		store, err := NewGCSStorage(bucket, opts)
+		if err != nil {
+			return nil, err
+		}
+		return withRetries(store, opts.Retries), nil
+	case BackendAzure:
+		store, err := NewAzureStorage(bucket, opts)
+		if err != nil {
+			return nil, err
+		}
+		return withRetries(store, opts.Retries), nil
+	default:
+		return nil, fmt.Errorf("Storage backend %v is not supported, use %v, %v, %v, %v or %v", backend, BackendS3, BackendWebDAV, BackendGCS, BackendAzure, BackendSFTP)
+	}
+}
+
+// listVersion List every object under the prefix, keyed by the path relative to the prefix
+func listVersion(ctx context.Context, store Storage, prefix string) (map[string]*Object, error) {
+	list, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]*Object, len(list))
+	for _, o := range list {
+		objects[o.Key[len(prefix):]] = o
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,83 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// S3 storage classes versions can be uploaded with or moved to
+const (
+	StorageClassStandard           = "STANDARD"
+	StorageClassStandardIA         = "STANDARD_IA"
+	StorageClassOneZoneIA          = "ONEZONE_IA"
+	StorageClassIntelligentTiering = "INTELLIGENT_TIERING"
+)
+
+// validateStorageClass Validate the storage class is one assets can be served from
+func validateStorageClass(class string) error {
+	switch class {
+	case "", StorageClassStandard, StorageClassStandardIA, StorageClassOneZoneIA, StorageClassIntelligentTiering:
+		return nil
+	default:
+		return fmt.Errorf("Storage class %v is not supported, use %v, %v, %v or %v", class, StorageClassStandard, StorageClassStandardIA, StorageClassOneZoneIA, StorageClassIntelligentTiering)
+	}
+}
+
+// validateStorageClasses Validate the storage class of the version and every override
+func (j *Journey) validateStorageClasses() error {
+	if err := validateStorageClass(j.StorageClass); err != nil {
+		return err
+	}
+	for _, class := range j.StorageClasses {
+		if err := validateStorageClass(class); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getStorageClass Get the storage class for the key, matching the file name first, then the extension, then *,
+// and falling back to the storage class of the version
+func (j *Journey) getStorageClass(key string) string {
+	if v, ok := j.StorageClasses[filepath.Base(key)]; ok {
+		return v
+	}
+	if v, ok := j.StorageClasses[filepath.Ext(key)]; ok {
+		return v
+	}
+	if v, ok := j.StorageClasses["*"]; ok {
+		return v
+	}
+
+	return j.StorageClass
+}
+
+// TransitionVersion Move every object of a published version to the storage class, objects already in it are left alone
+func (j *Journey) TransitionVersion(ctx context.Context, store Storage, version string, class string) error {
+	prefix := j.GetVersionKey(version, "")
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("Unable to list %v: %v", prefix, err)
+	}
+
+	moved := 0
+	for _, o := range objects {
+		if o.StorageClass == class || (len(o.StorageClass) <= 0 && class == StorageClassStandard) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		Log.Debugf("Moving %v to %v", o.Key, class)
+		if err := store.SetStorageClass(ctx, o.Key, class); err != nil {
+			return fmt.Errorf("Unable to move %v to %v: %v", o.Key, class, err)
+		}
+		moved++
+	}
+	Log.Infof("Moved %v objects of %v/%v to %v", moved, j.Name, version, class)
+
+	return nil
+}
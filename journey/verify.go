@@ -0,0 +1,147 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// verifyTimeout How long a request for a journey url through the cdn may take
+const verifyTimeout = 30 * time.Second
+
+// Verify Check the published version is complete: every asset in the manifest exists with a sensible content type,
+// journey-urls.json parses and every url in it can be fetched through the cdn
+func (j *Journey) Verify(ctx context.Context, store Storage) error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		Log.Warnf("%v", msg)
+		problems = append(problems, msg)
+	}
+
+	objects, err := listVersion(ctx, store, j.GetAssetKey(""))
+	if err != nil {
+		return fmt.Errorf("Unable to list the objects of %v/%v: %v", j.Name, j.Version, err)
+	}
+	if len(objects) <= 0 {
+		return fmt.Errorf("Version %v/%v is not published", j.Name, j.Version)
+	}
+
+	for _, file := range []string{JourneyFile, ManifestFile, j.UrlsFile()} {
+		if _, ok := objects[file]; !ok {
+			problem("%v is missing", file)
+		}
+	}
+
+	if _, ok := objects[ManifestFile]; ok {
+		j.verifyAssets(ctx, store, problem)
+	}
+
+	if _, ok := objects[j.UrlsFile()]; ok {
+		j.verifyUrls(ctx, store, problem)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Version %v/%v is not complete:\n%v", j.Name, j.Version, strings.Join(problems, "\n"))
+	}
+
+	Log.Infof("Version %v/%v is complete", j.Name, j.Version)
+	return nil
+}
+
+// verifyAssets Check every asset in the published manifest exists with the content type publish would give it
+func (j *Journey) verifyAssets(ctx context.Context, store Storage, problem func(string, ...interface{})) {
+	body, err := store.Get(ctx, j.GetAssetKey(ManifestFile))
+	if err != nil {
+		problem("Unable to get %v: %v", ManifestFile, err)
+		return
+	}
+	content, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		problem("Unable to read %v: %v", ManifestFile, err)
+		return
+	}
+
+	assets, err := ParseManifest(content, j.ManifestFormat)
+	if err != nil {
+		problem("%v", err)
+		return
+	}
+
+	for _, v := range assets {
+		// these were never meant to be uploaded
+		if isSourceMap(v) && j.SourceMaps == SourceMapsSkip {
+			continue
+		}
+		// shared chunks are named by the content of the local build, verifyUrls checks them through the journey urls
+		if j.isShared(v) {
+			continue
+		}
+
+		key := j.GetAssetKey(v)
+		want := getContentType(v)
+		if isSourceMap(v) {
+			u := &Upload{Key: key}
+			j.sourceMapUpload(u, v)
+			key, want = u.Key, u.ContentType
+		}
+
+		o, err := store.Head(ctx, key)
+		if err == ErrNotFound {
+			problem("%v is in the manifest but %v does not exist", v, key)
+			continue
+		}
+		if err != nil {
+			problem("Unable to check %v: %v", key, err)
+			continue
+		}
+
+		if o.ContentType != want {
+			problem("%v has content type %v, expected %v", key, o.ContentType, want)
+		}
+	}
+}
+
+// verifyUrls Check journey-urls.json parses and every url in it returns 200 through the cdn
+func (j *Journey) verifyUrls(ctx context.Context, store Storage, problem func(string, ...interface{})) {
+	urls, err := getJourneyUrls(ctx, store, j.GetAssetKey(j.UrlsFile()))
+	if err != nil {
+		problem("%v", err)
+		return
+	}
+
+	var all []string
+	for _, c := range urls.CSS {
+		all = append(all, c.URL)
+	}
+	for _, s := range urls.JS {
+		all = append(all, s.URL)
+	}
+	for _, a := range urls.Assets {
+		all = append(all, a.URL)
+	}
+
+	client := &http.Client{Timeout: verifyTimeout}
+	for _, url := range all {
+		req, err := http.NewRequest(http.MethodHead, url, nil)
+		if err != nil {
+			problem("Url %v is not valid: %v", url, err)
+			continue
+		}
+
+		res, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			problem("Unable to fetch %v: %v", url, err)
+			continue
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			problem("%v returned %v", url, res.Status)
+		}
+	}
+}
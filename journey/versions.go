@@ -0,0 +1,258 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionInfo A published version of the journey
+type VersionInfo struct {
+	Version   string
+	Published time.Time
+	Latest    bool
+	Archived  bool
+}
+
+// IsLatest Check if latest currently points at the version
+func (j *Journey) IsLatest(ctx context.Context, store Storage, version string) (bool, error) {
+	latest, err := j.currentLatest(ctx, store)
+	if err != nil {
+		return false, err
+	}
+
+	return len(latest) > 0 && latest == version, nil
+}
+
+// servedVersions The versions a channel, latest included, or a canary rollout points at, keyed by version with the channel,
+// or canary.json for the canary. They are being served, so they are never pruned, unpublished or archived
+func (j *Journey) servedVersions(ctx context.Context, store Storage) (map[string]string, error) {
+	channels, err := j.ListChannels(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	served := make(map[string]string, len(channels))
+	for _, c := range channels {
+		if len(c.Version) > 0 {
+			served[c.Version] = c.Channel
+		}
+	}
+
+	// the canary takes part of the traffic of latest until it is promoted
+	canary, err := j.GetCanary(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if canary != nil && len(canary.Canary.Version) > 0 {
+		if _, ok := served[canary.Canary.Version]; !ok {
+			served[canary.Canary.Version] = CanaryFile
+		}
+	}
+
+	return served, nil
+}
+
+// servedError Explain what has to move before the served version can be unpublished or archived
+func (j *Journey) servedError(version string, channel string, action string) error {
+	if channel == CanaryFile {
+		return fmt.Errorf("Version %v/%v is the canary of %v, promote it or set %v before %v it", j.Name, version, Latest, Latest, action)
+	}
+
+	return fmt.Errorf("Version %v/%v is %v, point %v at another version before %v it", j.Name, version, channel, channel, action)
+}
+
+// Unpublish Delete every object of the version, refusing to delete a version a channel, latest included, points at
+func (j *Journey) Unpublish(ctx context.Context, store Storage, force bool) error {
+	if reservedVersion(j.Version) {
+		return fmt.Errorf("Version %v is a reserved version and can not be unpublished", j.Version)
+	}
+
+	if !force {
+		return fmt.Errorf("Unpublishing %v/%v deletes it permanently, pass -force to confirm", j.Name, j.Version)
+	}
+
+	served, err := j.servedVersions(ctx, store)
+	if err != nil {
+		return err
+	}
+	if channel, ok := served[j.Version]; ok {
+		return j.servedError(j.Version, channel, "unpublishing")
+	}
+
+	return j.deleteVersion(ctx, store, j.Version)
+}
+
+// deleteVersion Delete every object under the version
+func (j *Journey) deleteVersion(ctx context.Context, store Storage, version string) error {
+	objects, err := store.List(ctx, j.GetVersionKey(version, ""))
+	if err != nil {
+		return fmt.Errorf("Unable to list the objects of %v/%v: %v", j.Name, version, err)
+	}
+	if len(objects) == 0 {
+		return fmt.Errorf("Version %v/%v is not published", j.Name, version)
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+
+	if err := store.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("Unable to delete %v/%v: %v", j.Name, version, err)
+	}
+	Log.Infof("Deleted %v objects of %v/%v", len(keys), j.Name, version)
+
+	return nil
+}
+
+// ParseAge Parse an age like 90d, 12h or 30m, days are not supported by time.ParseDuration
+func ParseAge(age string) (time.Duration, error) {
+	if len(age) <= 0 {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(age, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(age, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("Age %v is not valid, use a value like 90d or 12h", age)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(age)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Age %v is not valid, use a value like 90d or 12h", age)
+	}
+
+	return d, nil
+}
+
+// outsideRetention The versions, oldest first, that are neither among the newest keep nor published within olderThan,
+// the version latest points at and the served versions are never outside
+func outsideRetention(versions []*VersionInfo, served map[string]string, keep int, olderThan time.Duration) []*VersionInfo {
+	cutoff := time.Now().Add(-olderThan)
+	var outside []*VersionInfo
+	for i, v := range versions {
+		newest := len(versions) - i
+		if _, ok := served[v.Version]; ok {
+			continue
+		}
+		if v.Latest || (keep > 0 && newest <= keep) || (olderThan > 0 && v.Published.After(cutoff)) {
+			continue
+		}
+		outside = append(outside, v)
+	}
+
+	return outside
+}
+
+// Prune Delete versions outside the retention window, keeping the newest keep versions and anything
+// published within olderThan, or move them to storageClass instead when it is set. The versions channels point at, latest included, are always kept
+func (j *Journey) Prune(ctx context.Context, store Storage, keep int, olderThan time.Duration, storageClass string, force bool) ([]string, error) {
+	if keep <= 0 && olderThan <= 0 {
+		return nil, fmt.Errorf("Pruning needs a retention policy, pass -keep and/or -older-than")
+	}
+
+	versions, err := j.ListVersions(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	served, err := j.servedVersions(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	var prune []string
+	for _, v := range outsideRetention(versions, served, keep, olderThan) {
+		// archived objects can not be copied to another storage class until they are restored
+		if v.Archived && len(storageClass) > 0 {
+			continue
+		}
+		prune = append(prune, v.Version)
+	}
+
+	if len(prune) == 0 {
+		Log.Infof("No versions of %v are outside the retention policy", j.Name)
+		return nil, nil
+	}
+
+	// moving versions to a cheaper storage class keeps them, so it does not need confirming
+	if len(storageClass) > 0 {
+		if err := validateStorageClass(storageClass); err != nil {
+			return nil, err
+		}
+		if j.DryRun {
+			Log.Infof("Dry run, these versions of %v would be moved to %v: %v", j.Name, storageClass, strings.Join(prune, ", "))
+			return prune, nil
+		}
+
+		for _, version := range prune {
+			if err := j.TransitionVersion(ctx, store, version, storageClass); err != nil {
+				return prune, err
+			}
+		}
+		return prune, nil
+	}
+
+	if j.DryRun || !force {
+		Log.Infof("These versions of %v are outside the retention policy: %v", j.Name, strings.Join(prune, ", "))
+		if j.DryRun {
+			return prune, nil
+		}
+		return prune, fmt.Errorf("Pruning deletes %v versions permanently, pass -force to confirm", len(prune))
+	}
+
+	for _, version := range prune {
+		if err := j.deleteVersion(ctx, store, version); err != nil {
+			return prune, err
+		}
+	}
+
+	return prune, j.CollectContent(ctx, store)
+}
+
+// ListVersions List every published version of the journey, oldest first
+func (j *Journey) ListVersions(ctx context.Context, store Storage) ([]*VersionInfo, error) {
+	prefixes, err := store.ListPrefixes(ctx, j.GetJourneyKey(""))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the versions of %v: %v", j.Name, err)
+	}
+
+	latest, err := j.currentLatest(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*VersionInfo
+	for _, prefix := range prefixes {
+		version := strings.TrimSuffix(strings.TrimPrefix(prefix, j.GetJourneyKey("")), "/")
+		if version == Latest {
+			continue
+		}
+
+		// a version is only published once its journey.json exists
+		config, err := store.Head(ctx, j.GetVersionKey(version, JourneyFile))
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get %v/%v: %v", j.Name, version, err)
+		}
+
+		info := &VersionInfo{Version: version, Published: config.LastModified, Latest: len(latest) > 0 && version == latest}
+		if info.Archived, err = j.isArchived(ctx, store, version); err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, info)
+	}
+
+	sort.Slice(versions, func(a, b int) bool { return versions[a].Published.Before(versions[b].Published) })
+
+	return versions, nil
+}
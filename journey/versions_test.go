@@ -0,0 +1,130 @@
+package journey
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newServedStore Five published versions, latest on 1.0.0, beta on 1.1.0 and a canary of 1.3.0
+func newServedStore(t *testing.T) *MemoryStorage {
+	ctx := context.Background()
+	store := NewMemoryStorage("portal")
+	publishVersions(t, store, "1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0")
+
+	stable, cleanup := newTestJourney(t, "1.0.0")
+	defer cleanup()
+	if err := stable.SetLatest(ctx, store); err != nil {
+		t.Fatalf("SetLatest() failed: %v", err)
+	}
+
+	beta, cleanup := newTestJourney(t, "1.1.0")
+	defer cleanup()
+	if err := beta.SetChannel(ctx, "beta", store); err != nil {
+		t.Fatalf("SetChannel() failed: %v", err)
+	}
+
+	canary, cleanup := newTestJourney(t, "1.3.0")
+	defer cleanup()
+	if err := canary.StartCanary(ctx, 10, store); err != nil {
+		t.Fatalf("StartCanary() failed: %v", err)
+	}
+
+	return store
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name         string
+		keep         int
+		olderThan    time.Duration
+		storageClass string
+		force        bool
+		dryRun       bool
+		want         []string
+		wantErr      bool
+		wantDeleted  bool
+	}{
+		{"no policy", 0, 0, "", true, false, nil, true, false},
+		{"keep newest", 1, 0, "", true, false, []string{"1.2.0"}, false, true},
+		{"not confirmed", 1, 0, "", false, false, []string{"1.2.0"}, true, false},
+		{"dry run", 1, 0, "", true, true, []string{"1.2.0"}, false, false},
+		{"all kept", 5, 0, "", true, false, nil, false, false},
+		{"published recently", 0, time.Hour, "", true, false, nil, false, false},
+		{"move to a storage class", 1, 0, StorageClassStandardIA, false, false, []string{"1.2.0"}, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newServedStore(t)
+
+			j, cleanup := newTestJourney(t, "1.4.0")
+			defer cleanup()
+			j.DryRun = test.dryRun
+
+			pruned, err := j.Prune(ctx, store, test.keep, test.olderThan, test.storageClass, test.force)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Prune() = %v, want an error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(pruned, test.want) {
+				t.Errorf("Prune() = %v, want %v", pruned, test.want)
+			}
+
+			// the versions channels and the canary point at are never pruned
+			for _, version := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0"} {
+				deleted := test.wantDeleted && version == "1.2.0"
+				if keys := storedKeys(t, store, j.GetVersionKey(version, "")); (len(keys) == 0) != deleted {
+					t.Errorf("%v has %v objects left, want it deleted %v", version, len(keys), deleted)
+				}
+			}
+
+			if len(test.storageClass) > 0 {
+				o, err := store.Head(ctx, j.GetVersionKey("1.2.0", "app.js"))
+				if err != nil || o.StorageClass != test.storageClass {
+					t.Errorf("Head() = %+v, %v, want it moved to %v", o, err, test.storageClass)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		force   bool
+		wantErr bool
+	}{
+		{"unserved version", "1.2.0", true, false},
+		{"not confirmed", "1.2.0", false, true},
+		{"latest", "1.0.0", true, true},
+		{"channel", "1.1.0", true, true},
+		{"canary", "1.3.0", true, true},
+		{"not published", "2.0.0", true, true},
+		{"reserved version", Latest, true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newServedStore(t)
+
+			j, cleanup := newTestJourney(t, test.version)
+			defer cleanup()
+			before := storedKeys(t, store, j.GetAssetKey(""))
+
+			if err := j.Unpublish(context.Background(), store, test.force); (err != nil) != test.wantErr {
+				t.Fatalf("Unpublish() = %v, want an error %v", err, test.wantErr)
+			}
+
+			after := storedKeys(t, store, j.GetAssetKey(""))
+			if test.wantErr && !reflect.DeepEqual(after, before) {
+				t.Errorf("Unpublish() left %v, want %v untouched", after, before)
+			}
+			if !test.wantErr && len(after) > 0 {
+				t.Errorf("Unpublish() left %v, want nothing", after)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webDAVTimeout How long a single request to the WebDAV server may take, uploads of big files included
+const webDAVTimeout = 10 * time.Minute
+
+// WebDAVStorage Storage on a WebDAV server, like nginx with the dav module, for origins that are not object stores.
+// The bucket is a directory under the endpoint, and the user and password are sent as basic auth
+type WebDAVStorage struct {
+	Bucket   string
+	base     *url.URL
+	client   *http.Client
+	user     string
+	password string
+
+	mu          sync.Mutex
+	collections map[string]bool
+}
+
+// NewWebDAVStorage Create storage for the directory named by the bucket on the WebDAV server at the endpoint
+func NewWebDAVStorage(bucket string, opts StorageOptions) (*WebDAVStorage, error) {
+	if len(opts.Endpoint) <= 0 {
+		return nil, fmt.Errorf("Storage backend %v needs the url of the server as the endpoint", BackendWebDAV)
+	}
+
+	base, err := url.Parse(opts.Endpoint)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("Endpoint %v is not an http or https url", opts.Endpoint)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	// a user name in the endpoint is used when user is not set
+	user := opts.User
+	if len(user) <= 0 && base.User != nil {
+		user = base.User.Username()
+	}
+	base.User = nil
+
+	return &WebDAVStorage{
+		Bucket:      bucket,
+		base:        base,
+		client:      &http.Client{Timeout: webDAVTimeout},
+		user:        user,
+		password:    opts.Password,
+		collections: make(map[string]bool),
+	}, nil
+}
+
+// url The url of the key in the bucket, or of another bucket on the same server
+func (w *WebDAVStorage) url(bucket string, key string) string {
+	u := *w.base
+	u.Path = w.base.Path + bucket + "/" + key
+
+	return u.String()
+}
+
+// request Send a request to the server with the user and password
+func (w *WebDAVStorage) request(ctx context.Context, method string, u string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if len(w.user) > 0 {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	return w.client.Do(req.WithContext(ctx))
+}
+
+// do Send a request and close the response, failing on anything but the expected statuses
+func (w *WebDAVStorage) do(ctx context.Context, method string, u string, body io.Reader, header http.Header, ok ...int) error {
+	res, err := w.request(ctx, method, u, body, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	for _, status := range ok {
+		if res.StatusCode == status {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%v %v: %v", method, u, res.Status)
+}
+
+// makeCollections Create the directories the key is in, WebDAV does not create them on upload like S3 does
+func (w *WebDAVStorage) makeCollections(ctx context.Context, key string) error {
+	dir := w.Bucket
+	segments := strings.Split(key, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		dir += "/" + segment
+
+		w.mu.Lock()
+		made := w.collections[dir]
+		w.mu.Unlock()
+		if made {
+			continue
+		}
+
+		u := *w.base
+		u.Path = w.base.Path + dir + "/"
+		// 405 is returned when the directory already exists
+		if err := w.do(ctx, "MKCOL", u.String(), nil, nil, http.StatusCreated, http.StatusMethodNotAllowed); err != nil {
+			return fmt.Errorf("Unable to create the directory %v: %v", dir, err)
+		}
+
+		w.mu.Lock()
+		w.collections[dir] = true
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Upload Put the body at key, creating the directories it is in. Metadata, ACLs and storage classes have nowhere to go
+func (w *WebDAVStorage) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) error {
+	if err := w.makeCollections(ctx, key); err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if len(opts.ContentType) > 0 {
+		header.Set("Content-Type", opts.ContentType)
+	}
+	if len(opts.CacheControl) > 0 {
+		header.Set("Cache-Control", opts.CacheControl)
+	}
+	if len(opts.ContentEncoding) > 0 {
+		header.Set("Content-Encoding", opts.ContentEncoding)
+	}
+
+	return w.do(ctx, http.MethodPut, w.url(w.Bucket, key), body, header, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+}
+
+// Head Get the size, ETag, type and last modified time of the object
+func (w *WebDAVStorage) Head(ctx context.Context, key string) (*Object, error) {
+	res, err := w.request(ctx, http.MethodHead, w.url(w.Bucket, key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HEAD %v: %v", w.url(w.Bucket, key), res.Status)
+	}
+
+	o := &Object{
+		Key:         key,
+		Size:        res.ContentLength,
+		ETag:        webDAVETag(res.Header.Get("ETag")),
+		ContentType: res.Header.Get("Content-Type"),
+	}
+	if modified, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		o.LastModified = modified
+	}
+
+	return o, nil
+}
+
+// Get Get the content of the object, the caller closes it
+func (w *WebDAVStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := w.request(ctx, http.MethodGet, w.url(w.Bucket, key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("GET %v: %v", w.url(w.Bucket, key), res.Status)
+	}
+
+	return res.Body, nil
+}
+
+// Copy Copy the object to another key with a WebDAV COPY
+func (w *WebDAVStorage) Copy(ctx context.Context, from string, to string) error {
+	return w.CopyFrom(ctx, w.Bucket, from, to)
+}
+
+// CopyFrom Copy an object from another directory on the same server
+func (w *WebDAVStorage) CopyFrom(ctx context.Context, bucket string, from string, to string) error {
+	if err := w.makeCollections(ctx, to); err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Destination", w.url(w.Bucket, to))
+	header.Set("Overwrite", "T")
+
+	return w.do(ctx, "COPY", w.url(bucket, from), nil, header, http.StatusCreated, http.StatusNoContent)
+}
+
+// Delete Delete the objects, keys that do not exist are ignored like S3 does
+func (w *WebDAVStorage) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		err := w.do(ctx, http.MethodDelete, w.url(w.Bucket, key), nil, nil, http.StatusNoContent, http.StatusOK)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webDAVMultistatus The response to a PROPFIND
+type webDAVMultistatus struct {
+	Responses []struct {
+		Href         string    `xml:"href"`
+		Collection   *struct{} `xml:"propstat>prop>resourcetype>collection"`
+		Length       string    `xml:"propstat>prop>getcontentlength"`
+		ETag         string    `xml:"propstat>prop>getetag"`
+		LastModified string    `xml:"propstat>prop>getlastmodified"`
+		ContentType  string    `xml:"propstat>prop>getcontenttype"`
+	} `xml:"response"`
+}
+
+// webDAVETag Trim the quotes and weak marker from an ETag
+func webDAVETag(etag string) string {
+	return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+}
+
+// webDAVDir The directory a key prefix is in
+func webDAVDir(prefix string) string {
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		return prefix[:i+1]
+	}
+
+	return ""
+}
+
+// propfind List the directory of the key one level deep, returning the objects and the directories in it by key
+func (w *WebDAVStorage) propfind(ctx context.Context, dir string) ([]*Object, []string, error) {
+	header := http.Header{}
+	header.Set("Depth", "1")
+	header.Set("Content-Type", "application/xml")
+	body := `<?xml version="1.0" encoding="utf-8"?><propfind xmlns="DAV:"><allprop/></propfind>`
+
+	res, err := w.request(ctx, "PROPFIND", w.url(w.Bucket, dir), bytes.NewReader([]byte(body)), header)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil, nil
+	}
+	if res.StatusCode != http.StatusMultiStatus {
+		return nil, nil, fmt.Errorf("PROPFIND %v: %v", w.url(w.Bucket, dir), res.Status)
+	}
+
+	var ms webDAVMultistatus
+	if err := xml.NewDecoder(res.Body).Decode(&ms); err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse the listing of %v: %v", w.url(w.Bucket, dir), err)
+	}
+
+	root := w.base.Path + w.Bucket + "/"
+	var objects []*Object
+	var dirs []string
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to read %v in the listing of %v: %v", r.Href, dir, err)
+		}
+		if !strings.HasPrefix(href.Path, root) {
+			continue
+		}
+		key := strings.TrimPrefix(href.Path, root)
+
+		// the directory itself is part of its listing
+		if strings.TrimSuffix(key, "/") == strings.TrimSuffix(dir, "/") {
+			continue
+		}
+		if r.Collection != nil {
+			dirs = append(dirs, strings.TrimSuffix(key, "/")+"/")
+			continue
+		}
+
+		o := &Object{Key: key, ETag: webDAVETag(r.ETag), ContentType: r.ContentType}
+		o.Size, _ = strconv.ParseInt(r.Length, 10, 64)
+		if modified, err := http.ParseTime(r.LastModified); err == nil {
+			o.LastModified = modified
+		}
+		objects = append(objects, o)
+	}
+
+	return objects, dirs, nil
+}
+
+// List List every object under the prefix, sorted by key like S3 does. The server has to support PROPFIND,
+// for nginx that is the dav-ext module
+func (w *WebDAVStorage) List(ctx context.Context, prefix string) ([]*Object, error) {
+	var list []*Object
+	pending := []string{webDAVDir(prefix)}
+	for len(pending) > 0 {
+		objects, dirs, err := w.propfind(ctx, pending[0])
+		if err != nil {
+			return nil, err
+		}
+		pending = pending[1:]
+
+		for _, o := range objects {
+			if strings.HasPrefix(o.Key, prefix) {
+				list = append(list, o)
+			}
+		}
+		for _, d := range dirs {
+			if strings.HasPrefix(d, prefix) || strings.HasPrefix(prefix, d) {
+				pending = append(pending, d)
+			}
+		}
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Key < list[b].Key })
+
+	return list, nil
+}
+
+// ListPrefixes List the directories one level below the prefix
+func (w *WebDAVStorage) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	_, dirs, err := w.propfind(ctx, webDAVDir(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, d := range dirs {
+		if strings.HasPrefix(d, prefix) {
+			prefixes = append(prefixes, d)
+		}
+	}
+	sort.Strings(prefixes)
+
+	return prefixes, nil
+}
+
+// ListIncompleteUploads WebDAV has no multipart uploads, so nothing is ever left incomplete
+func (w *WebDAVStorage) ListIncompleteUploads(ctx context.Context, prefix string) ([]*IncompleteUpload, error) {
+	return nil, nil
+}
+
+// AbortUpload WebDAV has no multipart uploads to abort
+func (w *WebDAVStorage) AbortUpload(ctx context.Context, key string, uploadID string) error {
+	return ErrNotFound
+}
+
+// SetStorageClass WebDAV servers have no storage classes
+func (w *WebDAVStorage) SetStorageClass(ctx context.Context, key string, class string) error {
+	return fmt.Errorf("Storage backend %v does not have storage classes", BackendWebDAV)
+}
+
+// Restore Objects on a WebDAV server are never archived, so they can always be read
+func (w *WebDAVStorage) Restore(ctx context.Context, key string, days int) (bool, error) {
+	if _, err := w.Head(ctx, key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package journey
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Events webhooks are notified of
+const (
+	EventPublish       = "publish"
+	EventSetLatest     = "setLatest"
+	EventRollback      = "rollback"
+	EventCanary        = "canary"
+	EventPromoteCanary = "promoteCanary"
+)
+
+// SignatureHeader The header holding the hex HMAC-SHA256 of the body when the webhook has a secret
+const SignatureHeader = "X-Journey-Signature"
+
+// webhookTimeout How long a webhook may take to respond
+const webhookTimeout = 10 * time.Second
+
+// Webhook A url that is POSTed a Notification, signed with the secret when there is one
+type Webhook struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// Notification What webhooks are sent after a successful command
+type Notification struct {
+	Event       string    `json:"event"`
+	Status      string    `json:"status"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Environment string    `json:"environment,omitempty"`
+	Bucket      string    `json:"bucket"`
+	URL         string    `json:"url"`
+	LatestURL   string    `json:"latestUrl"`
+	Time        time.Time `json:"time"`
+}
+
+// newNotification Describe the event for the version
+func (j *Journey) newNotification(event string, version string) *Notification {
+	return &Notification{
+		Event:       event,
+		Status:      "success",
+		Name:        j.Name,
+		Version:     version,
+		Environment: j.Environment,
+		Bucket:      j.Bucket,
+		URL:         j.CDNDomain + j.GetVersionKey(version, j.UrlsFile()),
+		LatestURL:   j.CDNDomain + j.GetLatestKey(j.UrlsFile()),
+		Time:        time.Now().UTC(),
+	}
+}
+
+// sign Compute the signature of the body with the secret
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post POST the body to the webhook
+func (w *Webhook) post(ctx context.Context, client *http.Client, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) > 0 {
+		req.Header.Set(SignatureHeader, sign(w.Secret, body))
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook returned %v", res.Status)
+	}
+
+	return nil
+}
+
+// Notify Send the event for the version to every webhook and chat, the command already succeeded so failures are only logged
+func (j *Journey) Notify(ctx context.Context, event string, version string) {
+	if (len(j.Webhooks) <= 0 && len(j.Chats) <= 0) || j.DryRun {
+		return
+	}
+
+	n := j.newNotification(event, version)
+	client := &http.Client{Timeout: webhookTimeout}
+	j.notifyChats(ctx, client, n)
+
+	if len(j.Webhooks) <= 0 {
+		return
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		Log.Errorf("Unable to parse the notification into json")
+		return
+	}
+
+	for _, w := range j.Webhooks {
+		if err := w.post(ctx, client, body); err != nil {
+			Log.Warnf("Unable to notify %v of %v: %v", w.URL, event, err)
+			continue
+		}
+		Log.Debugf("Notified %v of %v", w.URL, event)
+	}
+}
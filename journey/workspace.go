@@ -0,0 +1,167 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceFile The file listing the journeys of a monorepo that are published together
+const WorkspaceFile = "journeys.json"
+
+// Workspace Represents the journeys.json configuration, the journey.json of every package or the directory holding it
+type Workspace struct {
+	Journeys []string `json:"journeys"`
+}
+
+// Paths The journey.json of every package, relative paths are from the directory holding journeys.json
+func (w *Workspace) Paths(workspacePath string) ([]string, error) {
+	if len(w.Journeys) <= 0 {
+		return nil, fmt.Errorf("%v does not list any journeys", workspacePath)
+	}
+
+	dir := filepath.Dir(workspacePath)
+	seen := make(map[string]bool)
+	var paths []string
+	for _, p := range w.Journeys {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			p = filepath.Join(p, "journey.json")
+		}
+		if seen[p] {
+			return nil, fmt.Errorf("%v lists %v more than once", workspacePath, p)
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
+
+// InDir Read the build, manifest and html template of the journey from dir when they are relative,
+// so the journey.json of a package points at its own build
+func (j *Journey) InDir(dir string) {
+	for _, p := range []*string{&j.Build, &j.Manifest, &j.HTMLTemplate} {
+		if len(*p) > 0 && *p != Stdin && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+}
+
+// FilterJourneys Keep the journeys named in only, in the order they were listed, every name must be one of them
+func FilterJourneys(journeys []*Journey, only []string) ([]*Journey, error) {
+	if len(only) <= 0 {
+		return journeys, nil
+	}
+
+	byName := make(map[string]*Journey)
+	var names []string
+	for _, j := range journeys {
+		byName[j.Name] = j
+		names = append(names, j.Name)
+	}
+
+	var kept []*Journey
+	for _, name := range only {
+		j, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("The workspace does not have a journey named %v, it has %v", name, strings.Join(names, ", "))
+		}
+		kept = append(kept, j)
+	}
+
+	return kept, nil
+}
+
+// Budget Shared by the journeys of a workspace published together, so between them they upload no more files
+// at the same time and no faster than the workspace allows
+type Budget struct {
+	slots   chan struct{}
+	limiter *rateLimiter
+}
+
+// NewBudget Create a budget of concurrent uploads and bytes per second, no bandwidth limit when it is 0
+func NewBudget(concurrency int, bandwidth int64) *Budget {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	b := &Budget{slots: make(chan struct{}, concurrency)}
+	if bandwidth > 0 {
+		b.limiter = newRateLimiter(bandwidth)
+	}
+
+	return b
+}
+
+// acquire Wait for a free upload, uploads without a budget never wait
+func (b *Budget) acquire(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release Free the upload for another journey
+func (b *Budget) release() {
+	if b == nil {
+		return
+	}
+
+	<-b.slots
+}
+
+// ValidateWorkspaceDependencies Check the journeys of a workspace do not depend on each other in a cycle,
+// since each one is published only once the journeys it depends on are
+func ValidateWorkspaceDependencies(journeys []*Journey) error {
+	byName := make(map[string]*Journey)
+	for _, j := range journeys {
+		byName[j.Name] = j
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(j *Journey, path []string) error
+	visit = func(j *Journey, path []string) error {
+		path = append(path, j.Name)
+		switch state[j.Name] {
+		case visiting:
+			return fmt.Errorf("The workspace journeys depend on each other in a cycle: %v", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[j.Name] = visiting
+		for _, d := range j.Dependencies {
+			if dep, ok := byName[d.Name]; ok {
+				if err := visit(dep, path); err != nil {
+					return err
+				}
+			}
+		}
+		state[j.Name] = visited
+
+		return nil
+	}
+
+	for _, j := range journeys {
+		if err := visit(j, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
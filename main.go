@@ -1,76 +1,674 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
+	"fmt"
 	"io/ioutil"
-	"log"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/jasonmichels/journey-cli/journey"
 
 	"gopkg.in/go-playground/validator.v9"
 )
 
 var j journey.Journey
-var assets map[string]string
 
-const publish = "publish"
+const (
+	publish       = "publish"
+	compare       = "compare"
+	annotate      = "annotate"
+	setLatest     = "set-latest"
+	setChannel    = "set-channel"
+	channels      = "list-channels"
+	canary        = "canary"
+	promoteCanary = "promote-canary"
+	history       = "history"
+	show          = "show"
+	cleanup       = "cleanup-multipart"
+	download      = "download"
+	serve         = "serve"
+	rollback      = "rollback"
+	list          = "list"
+	unpublish     = "unpublish"
+	prune         = "prune"
+	promote       = "promote"
+	diff          = "diff"
+	verify        = "verify"
+	sync          = "sync"
+	initCmd       = "init"
+	versionCmd    = "version"
+	selfUpdateCmd = "self-update"
+	completion    = "completion"
+	schema        = "schema"
+	signUrls      = "sign-urls"
+	archive       = "archive"
+	restore       = "restore"
+	doctor        = "doctor"
+)
+
+// metaFlags Collects repeated -meta key=value flags
+type metaFlags map[string]string
+
+func (m metaFlags) String() string {
+	return fmt.Sprint(map[string]string(m))
+}
+
+func (m metaFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || len(parts[0]) <= 0 {
+		return fmt.Errorf("metadata must look like key=value, got %v", value)
+	}
+	m[parts[0]] = parts[1]
+
+	return nil
+}
+
+// listFlags Collects a flag that can be repeated
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlags) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// isTerminal Check if the file is an interactive terminal rather than a pipe or log file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// loadConfig Read the config at path, or json from stdin when the path is -, and return it as json.
+// The format is picked from the file extension, yaml and toml are converted to json and anything unknown is read as json
+func loadConfig(path string, v interface{}) ([]byte, error) {
+	if path == journey.Stdin {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the config from stdin: %v", err)
+		}
+		return content, journey.DecodeConfig("stdin", content, v)
+	}
 
-func loadConfig(path string, v interface{}) error {
 	abs, err := filepath.Abs(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	content, err := ioutil.ReadFile(abs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return json.Unmarshal(content, v)
+	return journey.DecodeConfigFormat(path, journey.ConfigFormat(abs), content, v)
 }
 
 func main() {
+	err := run()
+	if err != nil {
+		journey.Log.Errorf("%v", err)
+	}
+
+	os.Exit(exitCode(err))
+}
 
-	journeyPath := flag.String("journey", "journey.json", "Location of the journey.json file")
-	cmd := flag.String("cmd", publish, "Command to invoke, eg: publish")
-	bucket := flag.String("bucket", "", "AWS S3 bucket")
-	cdnDomain := flag.String("cdn", "", "AWS Cloudfront domain")
-	region := flag.String("region", "us-east-1", "AWS region where bucket located")
-	flag.Parse()
+// run Run the command in the arguments
+func run() (err error) {
+	journey.CLIVersion = version
+
+	o, c, err := parseArgs(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		return nil
+	}
+	if err != nil {
+		return configError(err)
+	}
 
-	if err := loadConfig(*journeyPath, &j); err != nil {
-		log.Panic(err)
+	// CI can set these instead of passing the flags
+	for _, v := range []struct {
+		flag *string
+		env  string
+	}{{&o.bucket, "JOURNEY_BUCKET"}, {&o.cdnDomain, "JOURNEY_CDN"}, {&o.region, "JOURNEY_REGION"}} {
+		if len(*v.flag) <= 0 {
+			*v.flag = os.Getenv(v.env)
+		}
 	}
-	log.Println("Successfully loaded journey.json configuration")
 
-	j.Bucket = *bucket
-	j.JourneyPath = *journeyPath
-	j.CDNDomain = *cdnDomain
+	switch {
+	case o.quiet:
+		journey.Log.Level = journey.LevelWarn
+	case o.verbose:
+		journey.Log.Level = journey.LevelDebug
+	}
 
-	if err := j.Validate(validator.New()); err != nil {
-		log.Panic(err)
+	switch o.logFormat {
+	case "", "text":
+	case "json":
+		journey.Log.JSON = true
+	default:
+		return configError(fmt.Errorf("Do not recognize log format: %v", o.logFormat))
 	}
 
-	// lets create a new aws config
-	awsConfig := aws.Config{Region: aws.String(*region)}
+	if c.name == completion {
+		bashCompletion(os.Stdout)
+		return nil
+	}
+
+	if c.name == versionCmd {
+		printVersion(os.Stdout)
+		return nil
+	}
+
+	if c.name == selfUpdateCmd {
+		return selfUpdate(context.Background(), o.check, os.Stdout)
+	}
+
+	if c.name == schema {
+		if o.manifestSchema {
+			fmt.Print(journey.ManifestSchema)
+		} else {
+			fmt.Print(journey.JourneySchema)
+		}
+		return nil
+	}
+
+	if c.name == initCmd {
+		c := journey.DefaultInitConfig()
+		for _, v := range []struct{ to, from *string }{
+			{&c.Name, &o.name}, {&c.Version, &o.version}, {&c.RootID, &o.rootID}, {&c.Build, &o.build},
+			{&c.Manifest, &o.manifest}, {&c.Bucket, &o.bucket}, {&c.CDN, &o.cdnDomain},
+		} {
+			if len(*v.from) > 0 {
+				*v.to = *v.from
+			}
+		}
+
+		if isTerminal(os.Stdin) {
+			if err := c.Prompt(os.Stdin, os.Stdout, o.skipSemver); err != nil {
+				return configError(err)
+			}
+		} else if err := c.Validate(o.skipSemver); err != nil {
+			return configError(err)
+		}
 
-	switch *cmd {
+		if err := c.Write(o.journeyPath, o.force); err != nil {
+			return configError(err)
+		}
+		journey.Log.Infof("Wrote %v", o.journeyPath)
+		return nil
+	}
+
+	if o.journeyPath == journey.Stdin && o.manifest == journey.Stdin {
+		return configError(fmt.Errorf("Only one of -journey and -manifest can be read from stdin"))
+	}
+
+	// stop in a known state on Ctrl-C or when CI kills the job, or once the command has run out of time
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), o.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		journey.Log.Warnf("Received %v, stopping...", sig)
+		cancel()
+	}()
+
+	// -workspace is only a flag of publish
+	if len(o.workspace) > 0 {
+		return publishWorkspace(ctx, o)
+	}
+
+	if o.cmd == doctor {
+		return runDoctor(ctx, o, &j, os.Stdout)
+	}
+
+	if err := configure(o, o.journeyPath, &j); err != nil {
+		return err
+	}
+
+	// asked before the lock is taken, so nobody waits on the answer
+	if err := confirm(o, &j, os.Stdin, os.Stderr); err != nil {
+		return err
+	}
+
+	if len(o.artifact) > 0 {
+		cleanup, err := j.UseArtifact(o.artifact)
+		if err != nil {
+			return configError(err)
+		}
+		defer cleanup()
+	}
+
+	sess, err := j.NewSession(j.Region)
+	if err != nil {
+		return err
+	}
+
+	store, err := journey.NewStorage(j.Storage, j.Bucket, sess, j.StorageOptions())
+	if err != nil {
+		return configError(err)
+	}
+
+	locker := j.NewLocker(store, sess)
+	if o.forceUnlock {
+		if err := j.ForceUnlock(ctx, locker); err != nil {
+			return err
+		}
+	}
+
+	// only one job at a time may change a journey
+	switch o.cmd {
+	case publish, sync, annotate, setLatest, setChannel, rollback, canary, promoteCanary, cleanup, unpublish, prune, promote, signUrls, archive, restore:
+		var lock *journey.Lock
+		if lock, err = j.AcquireLock(ctx, locker, o.cmd); err != nil {
+			return err
+		}
+		defer j.ReleaseLock(locker, lock)
+
+		// renewed while the command runs, a command that lost the lock fails even when it got to the end
+		var stop func() error
+		ctx, stop = j.KeepLock(ctx, locker, lock)
+		defer func() {
+			if lost := stop(); lost != nil {
+				err = lost
+			}
+		}()
+	}
+
+	switch o.cmd {
 	case publish:
-		if err := loadConfig(j.Manifest, &assets); err != nil {
-			log.Panic(err)
+		if err := publishJourney(ctx, &j, sess, store); err != nil {
+			return err
+		}
+	case sync:
+		assets, err := j.LoadAssets()
+		if err != nil {
+			return configError(err)
+		}
+		journey.Log.Infof("Successfully loaded Asset Manifest configuration")
+
+		if _, err := j.Sync(ctx, assets, store); err != nil {
+			return withCode(exitUpload, err)
+		}
+		journey.Log.Infof("Finished syncing all assets to S3")
+	case diff:
+		if len(o.against) <= 0 {
+			return configError(fmt.Errorf("diff needs the published version to compare with, set -against"))
+		}
+
+		assets, err := j.LoadAssets()
+		if err != nil {
+			return configError(err)
 		}
-		log.Println("Successfully loaded Asset Manifest configuration")
 
-		if err := j.Publish(assets, &awsConfig); err != nil {
-			log.Panic(err)
+		d, err := j.DiffAgainst(ctx, assets, o.against, store)
+		if err != nil {
+			return err
+		}
+
+		if d.Empty() {
+			journey.Log.Infof("The local build is identical to %v/%v", j.Name, o.against)
+		} else {
+			fmt.Println(d)
+			return withCode(exitVerify, fmt.Errorf("The local build differs from %v/%v", j.Name, o.against))
+		}
+	case verify:
+		if err := j.Verify(ctx, store); err != nil {
+			return withCode(exitVerify, err)
+		}
+	case compare:
+		if len(o.to) <= 0 {
+			o.to = j.Version
+		}
+
+		c, err := j.Compare(ctx, o.from, o.to, store)
+		if err != nil {
+			return err
+		}
+		fmt.Print(c)
+	case annotate:
+		if err := j.Annotate(ctx, o.meta, store); err != nil {
+			return err
+		}
+	case setLatest:
+		if err := j.SetLatest(ctx, store); err != nil {
+			return withCode(exitLatest, err)
+		}
+		j.Notify(ctx, journey.EventSetLatest, j.Version)
+
+		if o.invalidate {
+			if err := j.InvalidateLatest(ctx, sess); err != nil {
+				return withCode(exitLatest, err)
+			}
+		}
+
+		if err := j.RunHook(ctx, journey.HookPostSetLatest); err != nil {
+			return fmt.Errorf("Latest points at %v/%v but %v", j.Name, j.Version, err)
+		}
+	case setChannel:
+		if len(o.channel) <= 0 {
+			return configError(fmt.Errorf("set-channel needs the channel to point at the version, set -channel"))
+		}
+
+		if err := j.SetChannel(ctx, o.channel, store); err != nil {
+			return withCode(exitLatest, err)
+		}
+
+		if o.invalidate {
+			if err := j.InvalidateChannel(ctx, o.channel, sess); err != nil {
+				return withCode(exitLatest, err)
+			}
+		}
+	case canary:
+		if err := j.StartCanary(ctx, o.weight, store); err != nil {
+			return withCode(exitLatest, err)
+		}
+		j.Notify(ctx, journey.EventCanary, j.Version)
+
+		if o.invalidate {
+			if err := j.InvalidateLatest(ctx, sess); err != nil {
+				return withCode(exitLatest, err)
+			}
+		}
+	case promoteCanary:
+		version, err := j.PromoteCanary(ctx, store)
+		if err != nil {
+			return withCode(exitLatest, err)
+		}
+		j.Notify(ctx, journey.EventPromoteCanary, version)
+
+		if o.invalidate {
+			if err := j.InvalidateLatest(ctx, sess); err != nil {
+				return withCode(exitLatest, err)
+			}
+		}
+	case channels:
+		list, err := j.ListChannels(ctx, store)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANNEL\tVERSION\tUPDATED")
+		for _, c := range list {
+			version := c.Version
+			if len(version) <= 0 {
+				version = "unknown"
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\n", c.Channel, version, c.Updated.Format(time.RFC3339))
+		}
+		w.Flush()
+	case rollback:
+		if err := j.Rollback(ctx, o.to, store); err != nil {
+			return withCode(exitLatest, err)
+		}
+		j.Notify(ctx, journey.EventRollback, o.to)
+
+		if o.invalidate {
+			if err := j.InvalidateLatest(ctx, sess); err != nil {
+				return withCode(exitLatest, err)
+			}
+		}
+	case list:
+		versions, err := j.ListVersions(ctx, store)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tPUBLISHED\tLATEST\tARCHIVED")
+		for _, v := range versions {
+			latest := ""
+			if v.Latest {
+				latest = "*"
+			}
+			archived := ""
+			if v.Archived {
+				archived = "*"
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", v.Version, v.Published.Format(time.RFC3339), latest, archived)
+		}
+		w.Flush()
+	case download:
+		if len(o.out) <= 0 {
+			o.out = filepath.Join(".", j.Name+"-"+j.Version)
+		}
+
+		if err := j.Download(ctx, store, j.Version, o.out); err != nil {
+			return err
+		}
+	case serve:
+		if len(o.dir) > 0 {
+			j.Build = o.dir
+			j.Manifest = filepath.Join(o.dir, journey.ManifestFile)
+		}
+
+		assets, err := j.LoadAssets()
+		if err != nil {
+			return configError(err)
+		}
+
+		if err := j.Serve(ctx, o.addr, assets); err != nil {
+			return err
+		}
+	case history:
+		entries, err := j.GetHistory(ctx, store)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTION\tVERSION\tCHANNEL\tUSER\tCOMMIT\tCLI\tJOB")
+		for _, e := range entries {
+			channel := e.Channel
+			if len(channel) <= 0 && e.Action != journey.ActionPublish {
+				channel = journey.Latest
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", e.Time.Format(time.RFC3339), e.Action, e.Version, channel, e.User, e.Commit, e.CLI, e.JobURL)
+		}
+		w.Flush()
+	case show:
+		if err := showVersion(ctx, o, &j, store, os.Stdout); err != nil {
+			return err
+		}
+	case unpublish:
+		if err := j.Unpublish(ctx, store, o.force); err != nil {
+			return err
+		}
+	case prune:
+		age, err := journey.ParseAge(o.olderThan)
+		if err != nil {
+			return configError(err)
+		}
+
+		if _, err := j.Prune(ctx, store, o.keep, age, o.storageClass, o.force); err != nil {
+			return err
+		}
+	case archive:
+		age, err := journey.ParseAge(o.olderThan)
+		if err != nil {
+			return configError(err)
+		}
+		if len(o.storageClass) <= 0 {
+			o.storageClass = journey.StorageClassGlacier
+		}
+
+		if o.keep > 0 || age > 0 {
+			if _, err := j.ArchiveVersions(ctx, store, o.keep, age, o.storageClass); err != nil {
+				return err
+			}
+		} else if len(o.version) <= 0 {
+			return configError(fmt.Errorf("archive needs the versions to archive, set -version, -older-than or -keep"))
+		} else if err := j.Archive(ctx, store, j.Version, o.storageClass); err != nil {
+			return err
+		}
+	case restore:
+		if _, err := j.Restore(ctx, store, j.Version); err != nil {
+			return err
+		}
+	case signUrls:
+		if err := j.SignUrls(ctx, store); err != nil {
+			return err
+		}
+	case cleanup:
+		age, err := journey.ParseAge(o.olderThan)
+		if err != nil {
+			return configError(err)
+		}
+
+		if _, err := j.CleanupMultipart(ctx, store, age); err != nil {
+			return err
+		}
+	case promote:
+		if len(o.fromBucket) <= 0 {
+			return configError(fmt.Errorf("promote needs the bucket to copy from, set -from-bucket"))
+		}
+
+		source, err := journey.NewStorage(j.Storage, o.fromBucket, sess, j.StorageOptions())
+		if err != nil {
+			return configError(err)
+		}
+
+		if err := j.Promote(ctx, o.fromBucket, source, store); err != nil {
+			return withCode(exitUpload, err)
 		}
-		log.Println("Finished publishing all assets to S3")
 	default:
-		log.Fatalf("Do not recognize command: %v", *cmd)
+		return configError(fmt.Errorf("Do not recognize command: %v", o.cmd))
+	}
+
+	journey.Log.Infof("Continue with your Journey!")
+	return nil
+}
+
+// configure Load the journey.json at path into j, with the environment and flags applied, and validate it
+func configure(o *options, path string, j *journey.Journey) error {
+	content, err := loadConfig(path, j)
+	if err != nil {
+		return configError(err)
+	}
+	// yaml and toml are published as the journey.json they were converted to
+	if path == journey.Stdin || journey.ConfigFormat(path) != journey.FormatJSON {
+		j.JourneyContent = content
+	}
+	if err := j.ExpandEnv(); err != nil {
+		return configError(err)
+	}
+	journey.Log.Infof("Successfully loaded journey.json configuration")
+
+	if len(o.env) > 0 {
+		if err := j.UseEnvironment(o.env); err != nil {
+			return configError(err)
+		}
+		journey.Log.Infof("Using the %v environment", o.env)
+	}
+
+	// the journey.json of a workspace package points at files in its own directory
+	if len(o.workspace) > 0 {
+		j.InDir(filepath.Dir(path))
+	}
+
+	if len(o.bucket) > 0 {
+		j.Bucket = o.bucket
+	}
+	if o.cmd == promote && len(o.toBucket) > 0 {
+		j.Bucket = o.toBucket
+	}
+	if len(o.cdnDomain) > 0 {
+		j.CDNDomain = o.cdnDomain
+	}
+	if len(o.region) > 0 {
+		j.Region = o.region
+	}
+	if len(j.Region) <= 0 {
+		j.Region = journey.DefaultRegion
+	}
+	if len(o.endpoint) > 0 {
+		j.Endpoint = o.endpoint
+	}
+	if o.pathStyle {
+		j.PathStyle = true
+	}
+	j.JourneyPath = path
+	// show resolves latest itself, the version in journey.json is left alone so it still validates
+	if len(o.version) > 0 && !(o.cmd == show && o.version == journey.Latest) {
+		j.Version = o.version
+	}
+	if len(o.manifest) > 0 {
+		j.Manifest = o.manifest
+	}
+	if len(o.manifestFormat) > 0 {
+		j.ManifestFormat = o.manifestFormat
+	}
+	if len(o.backend) > 0 {
+		j.Storage = o.backend
+	}
+	if len(o.acl) > 0 {
+		j.ACL = o.acl
+	}
+	if len(o.storageClass) > 0 && o.cmd != prune {
+		j.StorageClass = o.storageClass
+	}
+	if len(o.encryption) > 0 {
+		j.Encryption = o.encryption
+	}
+	if len(o.kmsKeyID) > 0 {
+		j.KMSKeyID = o.kmsKeyID
+	}
+	if o.includeAll {
+		j.IncludeAll = true
+	}
+	if o.checkDistribution {
+		j.CheckDistribution = true
+	}
+	j.VerifyExisting = o.verifyExisting
+	j.Metadata = o.meta
+	j.DryRun = o.dryRun
+	j.Concurrency = o.concurrency
+	j.SkipSemver = o.skipSemver
+	j.Retries = o.retries
+	j.UploadTimeout = o.uploadTimeout
+	if j.MaxBandwidth, err = journey.ParseBandwidth(o.maxBandwidth); err != nil {
+		return configError(err)
+	}
+	j.Report = o.report
+	j.SkipRegistry = o.skipRegistry
+	j.SkipSmokeTests = o.skipSmokeTests
+	j.SkipHooks = o.skipHooks
+	j.Force = o.force
+	j.Profile = o.profile
+	j.RoleARN = o.roleARN
+	j.ExternalID = o.externalID
+	j.RoleSessionName = o.roleSessionName
+	j.Chats = append(j.Chats, o.notify...)
+	if o.cmd == publish || o.cmd == sync {
+		j.Git = &journey.Git{Commit: o.gitSHA, Branch: o.gitBranch, Tag: o.gitTag}
+		j.UseGit()
+	}
+	j.Progress = o.progress && !journey.Log.JSON && journey.Log.Level == journey.LevelInfo && isTerminal(os.Stderr)
+
+	if err := j.Validate(validator.New()); err != nil {
+		return configError(err)
 	}
 
-	log.Println("Continue with your Journey!")
+	return nil
 }
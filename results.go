@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// printResults Print what happened to every file of a publish that failed, so it is clear which files made it
+func printResults(out io.Writer, r *journey.Report) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTATUS\tSIZE\tDURATION\tERROR")
+	for _, f := range r.Files {
+		status := "uploaded"
+		switch {
+		case len(f.Error) > 0:
+			status = "FAILED"
+		case f.Skipped:
+			status = "skipped"
+		}
+		// storage errors span lines, which would break the table
+		fmt.Fprintf(w, "%v\t%v\t%v\t%.2fs\t%v\n", f.Key, status, journey.FormatSize(f.Size), f.Duration, strings.Join(strings.Fields(f.Error), " "))
+	}
+	w.Flush()
+}
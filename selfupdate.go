@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// releasesURL The latest GitHub release of the cli
+const releasesURL = "https://api.github.com/repos/jasonmichels/journey-cli/releases/latest"
+
+// checksumsFile The release asset holding the sha256 of every binary, in the format sha256sum writes
+const checksumsFile = "checksums.txt"
+
+// release The parts of a GitHub release self-update reads
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// binaryName The release asset built for this OS and architecture, eg: journey-cli_linux_amd64
+func binaryName() string {
+	name := fmt.Sprintf("journey-cli_%v_%v", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	return name
+}
+
+// assetURL Get the download url of a release asset
+func (r *release) assetURL(name string) (string, error) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, nil
+		}
+	}
+
+	return "", fmt.Errorf("Release %v does not have %v", r.TagName, name)
+}
+
+// fetch GET the url, failing on anything but a 200
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "journey-cli/"+version)
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %v: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to get %v: %v", url, res.Status)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %v: %v", url, err)
+	}
+
+	return body, nil
+}
+
+// latestRelease Get the latest release of the cli
+func latestRelease(ctx context.Context) (*release, error) {
+	body, err := fetch(ctx, releasesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var r release
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("Unable to parse the latest release: %v", err)
+	}
+
+	return &r, nil
+}
+
+// isNewer Check if the release is newer than the running cli, builds that are not a released version are always updated
+func isNewer(tag string) bool {
+	latest, err := journey.ParseSemver(strings.TrimPrefix(tag, "v"))
+	if err != nil {
+		return true
+	}
+	current, err := journey.ParseSemver(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return true
+	}
+
+	return latest.Compare(current) > 0
+}
+
+// findChecksum Get the sha256 of the file from a checksums file
+func findChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+
+	return "", fmt.Errorf("%v does not list %v", checksumsFile, name)
+}
+
+// replaceExecutable Swap the running binary for the new one, written next to it first so the rename does not cross file systems
+func replaceExecutable(binary []byte) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("Unable to find the running binary: %v", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", fmt.Errorf("Unable to find the running binary: %v", err)
+	}
+
+	info, err := os.Stat(exe)
+	if err != nil {
+		return "", err
+	}
+
+	tmp := exe + ".new"
+	if err := ioutil.WriteFile(tmp, binary, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("Unable to write the new binary next to %v: %v", exe, err)
+	}
+
+	// windows does not allow replacing a running binary, but does allow renaming it out of the way
+	old := exe + ".old"
+	if runtime.GOOS == "windows" {
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("Unable to move %v out of the way: %v", exe, err)
+		}
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("Unable to replace %v: %v", exe, err)
+	}
+
+	return exe, nil
+}
+
+// selfUpdate Replace the cli with the latest release when it is newer, after checking the download against its sha256
+func selfUpdate(ctx context.Context, check bool, out io.Writer) error {
+	r, err := latestRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !isNewer(r.TagName) {
+		journey.Log.Infof("journey-cli %v is the latest release", version)
+		return nil
+	}
+	if check {
+		fmt.Fprintf(out, "journey-cli %v is available, this is %v, run journey-cli self-update to update\n", r.TagName, version)
+		return nil
+	}
+
+	name := binaryName()
+	binaryURL, err := r.assetURL(name)
+	if err != nil {
+		return err
+	}
+	checksumsURL, err := r.assetURL(checksumsFile)
+	if err != nil {
+		return err
+	}
+
+	checksums, err := fetch(ctx, checksumsURL)
+	if err != nil {
+		return err
+	}
+	expected, err := findChecksum(checksums, name)
+	if err != nil {
+		return err
+	}
+
+	journey.Log.Infof("Downloading journey-cli %v", r.TagName)
+	binary, err := fetch(ctx, binaryURL)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(binary)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("The sha256 of %v is %v but %v lists %v, not updating", name, actual, checksumsFile, expected)
+	}
+
+	exe, err := replaceExecutable(binary)
+	if err != nil {
+		return err
+	}
+	journey.Log.Infof("Updated %v from %v to %v", exe, version, r.TagName)
+
+	return nil
+}
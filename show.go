@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// showVersion Print the journey urls and journey.json of the version, or of the version latest points at,
+// as json or as a table for people
+func showVersion(ctx context.Context, o *options, j *journey.Journey, store journey.Storage, out io.Writer) error {
+	switch o.format {
+	case "json", "table":
+	default:
+		return configError(fmt.Errorf("Do not recognize format: %v, use json or table", o.format))
+	}
+
+	version := j.Version
+	if o.version == journey.Latest {
+		version = journey.Latest
+	}
+
+	p, err := j.Show(ctx, store, version)
+	if err != nil {
+		return err
+	}
+
+	if o.format == "json" {
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Unable to parse %v/%v into json: %v", p.Name, p.Version, err)
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\t%v\n", p.Name)
+	fmt.Fprintf(w, "VERSION\t%v\n", p.Version)
+	if p.Latest != nil {
+		latest := "set " + p.Latest.Time.Format(time.RFC3339)
+		if len(p.Latest.Actor) > 0 {
+			latest += " by " + p.Latest.Actor
+		}
+		if len(p.Latest.Previous) > 0 {
+			latest += ", was " + p.Latest.Previous
+		}
+		fmt.Fprintf(w, "LATEST\t%v\n", latest)
+	}
+	if p.Urls.Expires != nil {
+		fmt.Fprintf(w, "EXPIRES\t%v\n", p.Urls.Expires.Format(time.RFC3339))
+	}
+	w.Flush()
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tENTRY\tURL")
+	for _, c := range p.Urls.CSS {
+		fmt.Fprintf(w, "css\t%v\t%v\n", entry(c.Entry), c.URL)
+	}
+	for _, s := range p.Urls.JS {
+		fmt.Fprintf(w, "js\t%v\t%v\n", entry(s.Entry), s.URL)
+	}
+	for _, a := range p.Urls.Assets {
+		fmt.Fprintf(w, "%v\t\t%v\n", a.Type, a.URL)
+	}
+	for _, d := range p.Urls.Dependencies {
+		fmt.Fprintf(w, "dependency\t\t%v@%v\n", d.Name, d.Version)
+	}
+	w.Flush()
+
+	var config bytes.Buffer
+	if err := json.Indent(&config, p.Journey, "", "  "); err != nil {
+		return fmt.Errorf("Unable to print the journey.json of %v/%v: %v", p.Name, p.Version, err)
+	}
+	fmt.Fprintf(out, "\n%v:\n%v\n", journey.JourneyFile, config.String())
+
+	return nil
+}
+
+// entry Mark the entry points in a table
+func entry(e bool) string {
+	if e {
+		return "*"
+	}
+
+	return ""
+}
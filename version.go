@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// Build info, set when releasing with:
+// go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// printVersion Print the version of the cli and what it was built from
+func printVersion(out io.Writer) {
+	fmt.Fprintf(out, "journey-cli %v\n", version)
+	if len(commit) > 0 {
+		fmt.Fprintf(out, "commit:  %v\n", commit)
+	}
+	if len(buildDate) > 0 {
+		fmt.Fprintf(out, "built:   %v\n", buildDate)
+	}
+	fmt.Fprintf(out, "go:      %v %v/%v\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/jasonmichels/journey-cli/journey"
+)
+
+// publishJourney Publish the local build of the journey, copy it to the replicas and run the smoke tests, then notify,
+// with the hooks before and after
+func publishJourney(ctx context.Context, j *journey.Journey, sess *session.Session, store journey.Storage) error {
+	if err := j.RunHook(ctx, journey.HookPrePublish); err != nil {
+		return err
+	}
+	j.WarnDistribution(ctx, sess)
+
+	assets, err := j.LoadAssets()
+	if err != nil {
+		return configError(err)
+	}
+	journey.Log.Infof("Successfully loaded Asset Manifest configuration")
+
+	start := time.Now()
+	report, err := j.Publish(ctx, assets, store)
+	j.EmitMetrics(ctx, journey.NewPublishMetrics(report, err, time.Since(start)))
+	if err != nil {
+		if report != nil {
+			printResults(os.Stderr, report)
+		}
+		return withCode(exitUpload, err)
+	}
+	if err := j.PublishReplicas(ctx, store); err != nil {
+		return withCode(exitUpload, err)
+	}
+	if !j.SkipSmokeTests {
+		if err := j.SmokeTest(ctx); err != nil {
+			return withCode(exitVerify, err)
+		}
+	}
+	j.Notify(ctx, journey.EventPublish, j.Version)
+	journey.Log.Infof("Finished publishing all assets to S3")
+
+	if err := j.RunHook(ctx, journey.HookPostPublish); err != nil {
+		return fmt.Errorf("%v/%v is published but %v", j.Name, j.Version, err)
+	}
+
+	return nil
+}
+
+// loadWorkspace Load the journey.json of every package in the workspace, or the ones in -only
+func loadWorkspace(o *options) ([]*journey.Journey, error) {
+	for _, v := range []struct{ flag, value string }{{"artifact", o.artifact}, {"manifest", o.manifest}, {"report", o.report}} {
+		if len(v.value) > 0 {
+			return nil, configError(fmt.Errorf("-%v is set for a single journey and can not be used with -workspace", v.flag))
+		}
+	}
+
+	var w journey.Workspace
+	if _, err := loadConfig(o.workspace, &w); err != nil {
+		return nil, configError(err)
+	}
+	paths, err := w.Paths(o.workspace)
+	if err != nil {
+		return nil, configError(err)
+	}
+
+	names := make(map[string]string)
+	var journeys []*journey.Journey
+	for _, path := range paths {
+		j := &journey.Journey{}
+		if err := configure(o, path, j); err != nil {
+			return nil, withCode(exitCode(err), fmt.Errorf("%v: %v", path, err))
+		}
+		if other, ok := names[j.Name]; ok {
+			return nil, configError(fmt.Errorf("%v and %v are both named %v", other, path, j.Name))
+		}
+		names[j.Name] = path
+
+		// a progress bar for each journey would draw over the others
+		j.Progress = false
+		journeys = append(journeys, j)
+	}
+
+	var only []string
+	if len(o.only) > 0 {
+		only = strings.Split(o.only, ",")
+	}
+	if journeys, err = journey.FilterJourneys(journeys, only); err != nil {
+		return nil, configError(err)
+	}
+	if err := journey.ValidateWorkspaceDependencies(journeys); err != nil {
+		return nil, configError(err)
+	}
+
+	return journeys, nil
+}
+
+// publishPackage Publish one journey of the workspace under its own lock
+func publishPackage(ctx context.Context, o *options, j *journey.Journey, sess *session.Session) error {
+	store, err := journey.NewStorage(j.Storage, j.Bucket, sess, j.StorageOptions())
+	if err != nil {
+		return configError(err)
+	}
+
+	locker := j.NewLocker(store, sess)
+	if o.forceUnlock {
+		if err := j.ForceUnlock(ctx, locker); err != nil {
+			return err
+		}
+	}
+
+	lock, err := j.AcquireLock(ctx, locker, o.cmd)
+	if err != nil {
+		return err
+	}
+	defer j.ReleaseLock(locker, lock)
+
+	return publishJourney(ctx, j, sess, store)
+}
+
+// publishWorkspace Publish the journeys of a monorepo at the same time, sharing AWS sessions and keeping
+// their uploads together within -concurrency and -max-bandwidth
+func publishWorkspace(ctx context.Context, o *options) error {
+	journeys, err := loadWorkspace(o)
+	if err != nil {
+		return err
+	}
+
+	// every journey was given the same -max-bandwidth
+	budget := journey.NewBudget(o.concurrency, journeys[0].MaxBandwidth)
+	sessions := make(map[string]*session.Session)
+	for _, j := range journeys {
+		j.Budget = budget
+		if _, ok := sessions[j.Region]; ok {
+			continue
+		}
+		if sessions[j.Region], err = j.NewSession(j.Region); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, len(journeys))
+	for i, j := range journeys {
+		names[i] = j.Name
+	}
+	journey.Log.Infof("Publishing %v", strings.Join(names, ", "))
+
+	// the budget limits how many files upload at once, so every journey starts straight away
+	// unless it waits on journeys of the workspace it depends on
+	errs := make([]error, len(journeys))
+	done := make(map[string]chan struct{})
+	index := make(map[string]int)
+	for i, j := range journeys {
+		done[j.Name] = make(chan struct{})
+		index[j.Name] = i
+	}
+	for i, j := range journeys {
+		go func(i int, j *journey.Journey) {
+			defer close(done[j.Name])
+			for _, d := range j.Dependencies {
+				if _, ok := done[d.Name]; !ok {
+					continue
+				}
+				<-done[d.Name]
+				if errs[index[d.Name]] != nil {
+					errs[i] = fmt.Errorf("Not published, it depends on %v which failed to publish", d.Name)
+					return
+				}
+			}
+			errs[i] = publishPackage(ctx, o, j, sessions[j.Region])
+		}(i, j)
+	}
+	for _, j := range journeys {
+		<-done[j.Name]
+	}
+
+	var failed error
+	count := 0
+	for i, err := range errs {
+		if err == nil {
+			journey.Log.Infof("Published %v/%v", journeys[i].Name, journeys[i].Version)
+			continue
+		}
+		journey.Log.Errorf("Unable to publish %v: %v", journeys[i].Name, err)
+		if failed == nil {
+			failed = err
+		}
+		count++
+	}
+	if failed != nil {
+		return withCode(exitCode(failed), fmt.Errorf("%v of %v journeys failed to publish", count, len(journeys)))
+	}
+
+	return nil
+}